@@ -0,0 +1,143 @@
+package hago
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CurrentUser is the user the client is authenticated as.
+type CurrentUser struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	IsOwner     bool             `json:"is_owner"`
+	IsAdmin     bool             `json:"is_admin"`
+	Credentials []UserCredential `json:"credentials"`
+	MFAModules  []MFAModule      `json:"mfa_modules"`
+}
+
+// UserCredential is a way a user can log in, such as a username and
+// password with the homeassistant auth provider.
+type UserCredential struct {
+	AuthProviderType string  `json:"auth_provider_type"`
+	AuthProviderID   *string `json:"auth_provider_id"`
+}
+
+// MFAModule is a multi-factor authentication module of a user.
+type MFAModule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Refresh token types.
+const (
+	// RefreshTokenTypeNormal is issued when a client logs in, e.g. a
+	// browser or the companion app.
+	RefreshTokenTypeNormal = "normal"
+	// RefreshTokenTypeLongLived backs a Long-Lived Access Token.
+	RefreshTokenTypeLongLived = "long_lived_access_token"
+	// RefreshTokenTypeSystem is used by system users such as add-ons.
+	RefreshTokenTypeSystem = "system"
+)
+
+// RefreshToken is a refresh token of the current user. Long-Lived Access
+// Tokens are refresh tokens of type RefreshTokenTypeLongLived; deleting
+// one revokes the access token.
+type RefreshToken struct {
+	ID               string     `json:"id"`
+	ClientID         *string    `json:"client_id"`
+	ClientName       *string    `json:"client_name"`
+	ClientIcon       *string    `json:"client_icon"`
+	Type             string     `json:"type"`
+	CreatedAt        time.Time  `json:"created_at"`
+	IsCurrent        bool       `json:"is_current"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       *string    `json:"last_used_ip"`
+	AuthProviderType *string    `json:"auth_provider_type"`
+}
+
+// Name returns the client name for long-lived tokens, or the client ID for
+// tokens issued to a client.
+func (t *RefreshToken) Name() string {
+	switch {
+	case t.ClientName != nil && *t.ClientName != "":
+		return *t.ClientName
+	case t.ClientID != nil:
+		return *t.ClientID
+	}
+	return ""
+}
+
+// LastUsed returns when the token was last used, or when it was created if
+// it has never been used.
+func (t *RefreshToken) LastUsed() time.Time {
+	if t.LastUsedAt != nil {
+		return *t.LastUsedAt
+	}
+	return t.CreatedAt
+}
+
+// longLivedTokenCmd is the auth/long_lived_access_token command.
+type longLivedTokenCmd struct {
+	Type       string `json:"type"`
+	ClientName string `json:"client_name"`
+	Lifespan   int    `json:"lifespan,omitempty"`
+}
+
+// deleteRefreshTokenCmd is the auth/delete_refresh_token command.
+type deleteRefreshTokenCmd struct {
+	Type           string `json:"type"`
+	RefreshTokenID string `json:"refresh_token_id"`
+}
+
+// CurrentUser returns the user the client is authenticated as.
+func (c *Client) CurrentUser(ctx context.Context) (*CurrentUser, error) {
+	cmd := map[string]string{"type": "auth/current_user"}
+	var user CurrentUser
+	if err := c.wsCommand(ctx, cmd, &user); err != nil {
+		return nil, fmt.Errorf("current user: %w", err)
+	}
+	return &user, nil
+}
+
+// RefreshTokens lists the refresh tokens of the current user, including
+// Long-Lived Access Tokens, with when and from where they were last used.
+func (c *Client) RefreshTokens(ctx context.Context) ([]RefreshToken, error) {
+	cmd := map[string]string{"type": "auth/refresh_tokens"}
+	var tokens []RefreshToken
+	if err := c.wsCommand(ctx, cmd, &tokens); err != nil {
+		return nil, fmt.Errorf("refresh tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// CreateLongLivedToken creates a Long-Lived Access Token for the current
+// user and returns it. The token cannot be retrieved again. Its lifespan is
+// given in days; zero uses Home Assistant's default of ten years. The name
+// must be unique among the user's long-lived tokens.
+func (c *Client) CreateLongLivedToken(ctx context.Context, name string, lifespanDays int) (string, error) {
+	cmd := longLivedTokenCmd{
+		Type:       "auth/long_lived_access_token",
+		ClientName: name,
+		Lifespan:   lifespanDays,
+	}
+	var token string
+	if err := c.wsCommand(ctx, cmd, &token); err != nil {
+		return "", fmt.Errorf("create long-lived token: %w", err)
+	}
+	return token, nil
+}
+
+// DeleteRefreshToken deletes a refresh token of the current user by ID,
+// revoking it and any access tokens issued from it.
+func (c *Client) DeleteRefreshToken(ctx context.Context, id string) error {
+	cmd := deleteRefreshTokenCmd{
+		Type:           "auth/delete_refresh_token",
+		RefreshTokenID: id,
+	}
+	if err := c.wsCommand(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("delete refresh token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockWSCommandServer authenticates clients and answers each command with
+// the result of handle, or with an error result if handle returns one.
+func mockWSCommandServer(t *testing.T, handle func(cmd map[string]any) (any, *WebSocketError)) *Client {
+	t.Helper()
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			result, wsErr := handle(cmd)
+			if wsErr != nil {
+				conn.WriteJSON(map[string]any{
+					"id":      cmd["id"],
+					"type":    "result",
+					"success": false,
+					"error":   map[string]string{"code": wsErr.Code, "message": wsErr.Message},
+				})
+				continue
+			}
+			conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true, "result": result})
+		}
+	})
+	t.Cleanup(server.Close)
+
+	client, err := New(WithBaseURL(server.URL), WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.CloseWebSocket() })
+	return client
+}
+
+func TestClient_CurrentUser(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "auth/current_user" {
+			t.Errorf("type = %v", cmd["type"])
+		}
+		return map[string]any{
+			"id":       "u1",
+			"name":     "Alice",
+			"is_owner": true,
+			"is_admin": true,
+			"credentials": []map[string]any{
+				{"auth_provider_type": "homeassistant", "auth_provider_id": nil},
+			},
+			"mfa_modules": []map[string]any{{"id": "totp", "name": "Authenticator app", "enabled": false}},
+		}, nil
+	})
+
+	user, err := client.CurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentUser() error = %v", err)
+	}
+	if user.ID != "u1" || user.Name != "Alice" || !user.IsOwner || !user.IsAdmin {
+		t.Errorf("user = %+v", user)
+	}
+	if len(user.Credentials) != 1 || user.Credentials[0].AuthProviderType != "homeassistant" {
+		t.Errorf("credentials = %+v", user.Credentials)
+	}
+	if len(user.MFAModules) != 1 || user.MFAModules[0].ID != "totp" {
+		t.Errorf("mfa_modules = %+v", user.MFAModules)
+	}
+}
+
+func TestClient_RefreshTokens(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		return []map[string]any{
+			{
+				"id":           "t1",
+				"client_id":    "https://home-assistant.io/iOS",
+				"client_name":  nil,
+				"type":         "normal",
+				"created_at":   "2025-01-01T10:00:00+00:00",
+				"is_current":   true,
+				"last_used_at": "2025-06-01T10:00:00+00:00",
+				"last_used_ip": "192.168.1.10",
+			},
+			{
+				"id":           "t2",
+				"client_id":    nil,
+				"client_name":  "backup-script",
+				"type":         "long_lived_access_token",
+				"created_at":   "2024-01-01T10:00:00+00:00",
+				"is_current":   false,
+				"last_used_at": nil,
+				"last_used_ip": nil,
+			},
+		}, nil
+	})
+
+	tokens, err := client.RefreshTokens(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshTokens() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want 2", len(tokens))
+	}
+
+	phone, script := tokens[0], tokens[1]
+	if phone.Name() != "https://home-assistant.io/iOS" || !phone.IsCurrent || *phone.LastUsedIP != "192.168.1.10" {
+		t.Errorf("phone = %+v", phone)
+	}
+	if want := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC); !phone.LastUsed().Equal(want) {
+		t.Errorf("phone.LastUsed() = %v, want %v", phone.LastUsed(), want)
+	}
+	if script.Name() != "backup-script" || script.Type != RefreshTokenTypeLongLived {
+		t.Errorf("script = %+v", script)
+	}
+	if !script.LastUsed().Equal(script.CreatedAt) {
+		t.Errorf("unused token LastUsed() = %v, want created_at", script.LastUsed())
+	}
+}
+
+func TestClient_CreateLongLivedToken(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "auth/long_lived_access_token" || cmd["client_name"] != "ci" {
+			t.Errorf("cmd = %v", cmd)
+		}
+		if cmd["lifespan"] != float64(30) {
+			t.Errorf("lifespan = %v, want 30", cmd["lifespan"])
+		}
+		return "new-token", nil
+	})
+
+	token, err := client.CreateLongLivedToken(context.Background(), "ci", 30)
+	if err != nil {
+		t.Fatalf("CreateLongLivedToken() error = %v", err)
+	}
+	if token != "new-token" {
+		t.Errorf("token = %q", token)
+	}
+}
+
+func TestClient_DeleteRefreshToken(t *testing.T) {
+	var deleted []any
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "auth/delete_refresh_token" {
+			t.Errorf("type = %v", cmd["type"])
+		}
+		if cmd["refresh_token_id"] == "missing" {
+			return nil, &WebSocketError{Code: "invalid_token_id", Message: "Received invalid token"}
+		}
+		deleted = append(deleted, cmd["refresh_token_id"])
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	if err := client.DeleteRefreshToken(ctx, "t2"); err != nil {
+		t.Fatalf("DeleteRefreshToken() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "t2" {
+		t.Errorf("deleted = %v", deleted)
+	}
+
+	err := client.DeleteRefreshToken(ctx, "missing")
+	var wsErr *WebSocketError
+	if !errors.As(err, &wsErr) || wsErr.Code != "invalid_token_id" {
+		t.Errorf("error = %v, want WebSocketError invalid_token_id", err)
+	}
+}
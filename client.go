@@ -3,6 +3,7 @@ package hago
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,19 +11,40 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Client is the Home Assistant REST API client.
 // It is safe for concurrent use by multiple goroutines.
 type Client struct {
-	baseURL    string
-	token      string
-	httpClient *http.Client
+	baseURL     string
+	tokenSource TokenSource
+	httpClient  *http.Client
 
 	// WebSocket connection (lazy initialized)
-	ws   *wsConn
-	wsMu sync.Mutex
+	wsDialer WebSocketDialer
+	ws       *wsConn
+	wsMu     sync.Mutex
+	wsStop   chan struct{} // closed by CloseWebSocket to stop reconnecting
+
+	// Active WebSocket subscriptions, replayed after a reconnect
+	subs   map[*wsSubscription]struct{}
+	subsMu sync.Mutex
+
+	reconnect         *ReconnectPolicy
+	onConnectionState func(ConnectionState)
+
+	// REST retries (nil disables)
+	retry *RetryPolicy
+
+	// Transport for service calls and events
+	transport Transport
+
+	// WebSocket heartbeat (disabled when heartbeatInterval is zero)
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+	wsLatency         atomic.Int64
 }
 
 // Option is a functional option for configuring the Client.
@@ -41,7 +63,22 @@ func WithBaseURL(baseURL string) Option {
 // WithToken sets the Long-Lived Access Token for authentication.
 func WithToken(token string) Option {
 	return func(c *Client) error {
-		c.token = token
+		if token == "" {
+			c.tokenSource = nil
+			return nil
+		}
+		c.tokenSource = StaticToken(token)
+		return nil
+	}
+}
+
+// WithTokenSource sets where the client gets its access token, for tokens
+// that expire and must be refreshed such as those from OAuthTokenSource.
+// The source is asked for a token before every REST request and WebSocket
+// authentication.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) error {
+		c.tokenSource = ts
 		return nil
 	}
 }
@@ -62,13 +99,37 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithTLSConfig sets the TLS configuration for REST and WebSocket
+// connections, e.g. to trust a private CA or present a client certificate.
+// It installs a transport with the config on a copy of the HTTP client, so
+// it cannot be combined with a custom transport set through
+// WithHTTPClient. It replaces the WebSocket dialer, so it must come before
+// WithWebSocketDialer.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) error {
+		if t := c.httpClient.Transport; t != nil && t != http.DefaultTransport {
+			return fmt.Errorf("WithTLSConfig cannot be combined with a custom HTTP transport")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+		c.wsDialer = newWebSocketDialer(config)
+		return nil
+	}
+}
+
 // New creates a new Home Assistant API client with the given options.
-// At minimum, WithBaseURL and WithToken must be provided.
+// At minimum, WithBaseURL and WithToken (or WithTokenSource) must be
+// provided.
 func New(opts ...Option) (*Client, error) {
 	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		wsDialer: DefaultWebSocketDialer(),
+		subs:     make(map[*wsSubscription]struct{}),
 	}
 
 	for _, opt := range opts {
@@ -89,7 +150,7 @@ func (c *Client) validate() error {
 	if c.baseURL == "" {
 		return ErrNoBaseURL
 	}
-	if c.token == "" {
+	if c.tokenSource == nil {
 		return ErrNoToken
 	}
 	return nil
@@ -100,32 +161,62 @@ func (c *Client) BaseURL() string {
 	return c.baseURL
 }
 
-// doRequest performs an HTTP request with authentication.
+// doRequest performs an HTTP request with authentication and checks the
+// response status. Failed attempts are retried according to the retry policy.
+// On success the caller must close the response body.
 func (c *Client) doRequest(ctx context.Context, method, path string, body any) (*http.Response, error) {
-	var bodyReader io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, &RequestError{Op: "marshal request body", Err: err}
 		}
-		bodyReader = bytes.NewReader(jsonBody)
 	}
 
-	reqURL := c.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
-	if err != nil {
-		return nil, &RequestError{Op: "create request", Err: err}
-	}
+	for attempt := 1; ; attempt++ {
+		var bodyReader io.Reader
+		if jsonBody != nil {
+			bodyReader = bytes.NewReader(jsonBody)
+		}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
+		reqURL := c.baseURL + path
+		req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
+		if err != nil {
+			return nil, &RequestError{Op: "create request", Err: err}
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, &RequestError{Op: "execute request", Err: err}
-	}
+		token, err := c.tokenSource.Token(ctx)
+		if err != nil {
+			return nil, &RequestError{Op: "get token", Err: err}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
 
-	return resp, nil
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			resp = nil
+			err = &RequestError{Op: "execute request", Err: err}
+		} else if err = c.checkResponse(resp); err == nil {
+			return resp, nil
+		}
+
+		delay, retry := c.retryDelay(ctx, method, attempt, resp, err)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if !retry {
+			return nil, withAttempts(err, attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, withAttempts(err, attempt)
+		case <-timer.C:
+		}
+	}
 }
 
 // doJSON performs an HTTP request and decodes the JSON response.
@@ -136,10 +227,6 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body any, resu
 	}
 	defer resp.Body.Close()
 
-	if err := c.checkResponse(resp); err != nil {
-		return err
-	}
-
 	if result != nil {
 		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
 			return &RequestError{Op: "decode response", Err: err}
@@ -157,10 +244,6 @@ func (c *Client) doText(ctx context.Context, method, path string, body any) (str
 	}
 	defer resp.Body.Close()
 
-	if err := c.checkResponse(resp); err != nil {
-		return "", err
-	}
-
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", &RequestError{Op: "read response body", Err: err}
@@ -177,10 +260,6 @@ func (c *Client) doBytes(ctx context.Context, method, path string, body any) ([]
 	}
 	defer resp.Body.Close()
 
-	if err := c.checkResponse(resp); err != nil {
-		return nil, err
-	}
-
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, &RequestError{Op: "read response body", Err: err}
@@ -210,21 +289,24 @@ func (c *Client) checkResponse(resp *http.Response) error {
 		apiErr.Message = errResp.Message
 	}
 
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return ErrUnauthorized
-	case http.StatusNotFound:
-		return ErrNotFound
-	case http.StatusBadRequest:
-		if apiErr.Message != "" {
-			return apiErr
+	apiErr.Err = statusError(resp.StatusCode)
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Path = resp.Request.URL.Path
+		apiErr.EntityID = entityFromPath(apiErr.Path)
+	}
+	return apiErr
+}
+
+// entityFromPath extracts the entity ID from entity-scoped REST paths such as
+// /api/states/{entity_id}.
+func entityFromPath(path string) string {
+	for _, prefix := range []string{"/api/states/", "/api/camera_proxy/", "/api/calendars/"} {
+		if id, ok := strings.CutPrefix(path, prefix); ok && strings.Contains(id, ".") {
+			return id
 		}
-		return ErrBadRequest
-	case http.StatusMethodNotAllowed:
-		return ErrMethodNotAllowed
-	default:
-		return apiErr
 	}
+	return ""
 }
 
 // buildQueryString builds a query string from parameters.
@@ -303,6 +385,10 @@ func (c *Client) Events(ctx context.Context) ([]Event, error) {
 
 // FireEvent fires an event with optional data.
 func (c *Client) FireEvent(ctx context.Context, eventType string, data EventData) error {
+	if c.transport == TransportWebSocket {
+		_, err := c.fireEventWebSocket(ctx, eventType, data)
+		return err
+	}
 	path := fmt.Sprintf("/api/events/%s", eventType)
 	return c.doJSON(ctx, http.MethodPost, path, data, nil)
 }
@@ -318,7 +404,12 @@ func (c *Client) Services(ctx context.Context) ([]Service, error) {
 
 // CallService calls a service in a specific domain.
 // The request can include an entity_id and additional service-specific data.
+// With TransportWebSocket the call succeeds without returning states.
 func (c *Client) CallService(ctx context.Context, domain, service string, request *ServiceCallRequest) ([]State, error) {
+	if c.transport == TransportWebSocket {
+		_, err := c.callServiceWebSocket(ctx, domain, service, request, false)
+		return nil, err
+	}
 	path := fmt.Sprintf("/api/services/%s/%s", domain, service)
 	var result []State
 	if err := c.doJSON(ctx, http.MethodPost, path, request, &result); err != nil {
@@ -2,9 +2,13 @@ package hago
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -389,6 +393,11 @@ func TestClient_ErrorResponses(t *testing.T) {
 			statusCode: http.StatusUnauthorized,
 			wantErr:    ErrUnauthorized,
 		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			wantErr:    ErrForbidden,
+		},
 		{
 			name:       "not found",
 			statusCode: http.StatusNotFound,
@@ -404,6 +413,16 @@ func TestClient_ErrorResponses(t *testing.T) {
 			statusCode: http.StatusMethodNotAllowed,
 			wantErr:    ErrMethodNotAllowed,
 		},
+		{
+			name:       "server error",
+			statusCode: http.StatusInternalServerError,
+			wantErr:    ErrServerError,
+		},
+		{
+			name:       "gateway timeout",
+			statusCode: http.StatusGatewayTimeout,
+			wantErr:    ErrTimeout,
+		},
 	}
 
 	for _, tt := range tests {
@@ -422,13 +441,61 @@ func TestClient_ErrorResponses(t *testing.T) {
 			}
 
 			_, err = client.Status(context.Background())
-			if err != tt.wantErr {
+			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("Status() error = %v, want %v", err, tt.wantErr)
 			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.statusCode {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.statusCode)
+			}
+			if apiErr.Method != http.MethodGet || apiErr.Path != "/api/" {
+				t.Errorf("request = %s %s, want GET /api/", apiErr.Method, apiErr.Path)
+			}
 		})
 	}
 }
 
+func TestClient_ErrorEntityID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Entity not found."}`))
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+
+	_, err := client.State(context.Background(), "light.missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.EntityID != "light.missing" {
+		t.Errorf("EntityID = %q, want light.missing", apiErr.EntityID)
+	}
+	want := "GET /api/states/light.missing: API error 404 (404 Not Found): Entity not found."
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestClient_TimeoutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithTimeout(20*time.Millisecond))
+
+	_, err := client.Status(context.Background())
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
 func TestClient_WithTimeout(t *testing.T) {
 	client, err := New(
 		WithBaseURL("http://localhost:8123"),
@@ -474,6 +541,64 @@ func TestServiceCallRequest_MarshalJSON(t *testing.T) {
 	}
 }
 
+func TestServiceCallRequest_MarshalJSONTarget(t *testing.T) {
+	req := (&ServiceCallRequest{
+		EntityID: "light.porch",
+		Data:     map[string]any{"transition": 2},
+	}).WithTarget(NewTarget().Entities("light.hall").Areas("kitchen").Labels("critical"))
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	wantEntities := []any{"light.porch", "light.hall"}
+	if !reflect.DeepEqual(result["entity_id"], wantEntities) {
+		t.Errorf("entity_id = %v, want %v", result["entity_id"], wantEntities)
+	}
+	if !reflect.DeepEqual(result["area_id"], []any{"kitchen"}) {
+		t.Errorf("area_id = %v, want [kitchen]", result["area_id"])
+	}
+	if !reflect.DeepEqual(result["label_id"], []any{"critical"}) {
+		t.Errorf("label_id = %v, want [critical]", result["label_id"])
+	}
+	if _, ok := result["device_id"]; ok {
+		t.Errorf("device_id present for empty list: %v", result["device_id"])
+	}
+	if result["transition"] != float64(2) {
+		t.Errorf("transition = %v, want 2", result["transition"])
+	}
+}
+
+func TestNewServiceCallRequest(t *testing.T) {
+	type params struct {
+		Brightness *int   `json:"brightness,omitempty"`
+		Effect     string `json:"effect,omitempty"`
+		Flash      *bool  `json:"flash,omitempty"`
+	}
+
+	req, err := NewServiceCallRequest(NewTarget().Areas("kitchen"), params{Brightness: Ptr(0), Flash: Ptr(false)})
+	if err != nil {
+		t.Fatalf("NewServiceCallRequest() error = %v", err)
+	}
+	want := map[string]any{"brightness": float64(0), "flash": false}
+	if !reflect.DeepEqual(req.Data, want) {
+		t.Errorf("Data = %v, want %v", req.Data, want)
+	}
+	if !reflect.DeepEqual(req.Target.AreaID, []string{"kitchen"}) {
+		t.Errorf("Target = %+v", req.Target)
+	}
+
+	if _, err := NewServiceCallRequest(nil, []string{"not", "an", "object"}); err == nil {
+		t.Error("expected error for non-object data")
+	}
+}
+
 func TestAPIError_Error(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1086,6 +1211,63 @@ func TestClient_WithHTTPClient(t *testing.T) {
 	}
 }
 
+func TestClient_WithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(StatusResponse{Message: "API running."})
+	}))
+	defer server.Close()
+
+	untrusted, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	if _, err := untrusted.Status(context.Background()); err == nil {
+		t.Error("expected certificate error without TLS config")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client, err := New(
+		WithBaseURL(server.URL),
+		WithToken("test-token"),
+		WithTLSConfig(&tls.Config{RootCAs: roots}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := client.Status(context.Background()); err != nil {
+		t.Errorf("Status() error = %v", err)
+	}
+}
+
+func TestClient_WithTLSConfigKeepsCallerClient(t *testing.T) {
+	caller := &http.Client{Timeout: 5 * time.Second}
+	client, err := New(
+		WithBaseURL("https://ha.local:8123"),
+		WithToken("test-token"),
+		WithHTTPClient(caller),
+		WithTLSConfig(&tls.Config{}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if caller.Transport != nil {
+		t.Error("WithTLSConfig() modified the caller's HTTP client")
+	}
+	if client.httpClient == caller || client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("httpClient = %+v, want a copy of the caller's client", client.httpClient)
+	}
+
+	custom := &http.Client{Transport: &http.Transport{}}
+	_, err = New(
+		WithBaseURL("https://ha.local:8123"),
+		WithToken("test-token"),
+		WithHTTPClient(custom),
+		WithTLSConfig(&tls.Config{}),
+	)
+	if err == nil {
+		t.Error("expected error combining WithTLSConfig with a custom transport")
+	}
+}
+
 func TestClient_APIErrorWithMessage(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage stored access tokens",
+	Long: `Store, remove and check the access token for the current context, or for
+the top-level configuration if no context is selected.
+
+Instead of keeping a plain-text token in the config file, a context can read
+it from elsewhere:
+
+  token_command: pass show home-assistant     # output of a command
+  token_file: ~/.config/hago/ha.token         # a file only you can read
+  token_keyfile: ~/.config/hago/tokens/x.enc  # encrypted with a passphrase
+  token_env: HA_TOKEN                         # an environment variable
+  token_oauth: ~/.config/hago/tokens/x.oauth  # OAuth login, refreshed as needed
+
+hago auth login stores a token and points the config at it.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store an access token",
+	Long: `Store a Long-Lived Access Token for the current context.
+
+The token is read from --token, or from stdin (prompting if stdin is a
+terminal), and verified against Home Assistant before it is stored. By
+default it is encrypted with a passphrase, typed on the terminal or taken
+from HAGO_KEYFILE_PASSPHRASE, that hago asks for whenever it needs the
+token. Use --store file for a plain file readable only by you, or --store
+config to write it to the config file in plain text.
+
+With --oauth, hago instead opens the Home Assistant login page in a browser
+and receives the result on a local loopback address. This yields a refresh
+token tied to your user, shown under "Refresh Tokens" in your Home Assistant
+profile, which can be revoked there or with hago auth logout. The short-lived
+access token is refreshed automatically.
+
+Examples:
+  hago auth login
+  pass show home-assistant | hago --context lab auth login
+  hago auth login --store file
+  hago auth login --oauth`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		store, _ := cmd.Flags().GetString("store")
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		useOAuth, _ := cmd.Flags().GetBool("oauth")
+		if store != "keyfile" && store != "file" && store != "config" {
+			return fmt.Errorf("invalid --store: %s (use keyfile, file or config)", store)
+		}
+		if useOAuth && store != "keyfile" {
+			return fmt.Errorf("--oauth credentials are always stored in a keyfile")
+		}
+
+		conn, err := resolveConnection(cmd)
+		if err != nil {
+			return err
+		}
+		if useOAuth {
+			return loginOAuth(cmd, conn)
+		}
+
+		token := viper.GetString("token")
+		if !explicitlySet(cmd, "token") {
+			if token, err = readToken(os.Stdin); err != nil {
+				return err
+			}
+		}
+		if token == "" {
+			return fmt.Errorf("no token given")
+		}
+
+		if !noVerify {
+			if conn.url == "" {
+				return fmt.Errorf("home Assistant URL is required to verify the token (use --url, a context, or --no-verify)")
+			}
+			c, err := newClient(conn, hago.StaticToken(token))
+			if err != nil {
+				return err
+			}
+			if _, err := c.Status(ctx); err != nil {
+				return fmt.Errorf("verify token: %w", err)
+			}
+		}
+
+		var t tokenConfig
+		switch store {
+		case "keyfile":
+			path, err := storedTokenPath(conn.context, ".enc")
+			if err != nil {
+				return err
+			}
+			if err := writeKeyfile(path, token); err != nil {
+				return err
+			}
+			t.TokenKeyfile = path
+		case "file":
+			path, err := storedTokenPath(conn.context, ".token")
+			if err != nil {
+				return err
+			}
+			if err := writePrivateFile(path, []byte(token+"\n")); err != nil {
+				return err
+			}
+			t.TokenFile = path
+		case "config":
+			t.Token = token
+		}
+
+		if err := saveTokenConfig(cmd, conn.context, t); err != nil {
+			return err
+		}
+		printSuccess("Token for %s stored (%s)", contextLabel(conn.context), t.describe())
+		return nil
+	},
+}
+
+// oauthLoginTimeout is how long hago auth login --oauth waits for the user
+// to log in.
+const oauthLoginTimeout = 5 * time.Minute
+
+// loginOAuth runs the OAuth authorization code flow with a loopback
+// redirect and stores the resulting credentials.
+func loginOAuth(cmd *cobra.Command, conn *connection) error {
+	if conn.url == "" {
+		return fmt.Errorf("home Assistant URL is required (use --url or a context)")
+	}
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen for OAuth redirect: %w", err)
+	}
+	// Home Assistant accepts a loopback client ID and redirect URL on the
+	// same host without fetching the client ID page.
+	clientID := fmt.Sprintf("http://%s/", listener.Addr())
+	config := &hago.OAuthConfig{
+		BaseURL:     conn.url,
+		ClientID:    clientID,
+		RedirectURL: clientID + "callback",
+		HTTPClient:  conn.httpClient(),
+	}
+
+	state := rand.Text()
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Invalid state. Run hago auth login --oauth again.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			http.Error(w, "Login failed: "+q.Get("error"), http.StatusBadRequest)
+			select {
+			case errs <- fmt.Errorf("login failed: %s", q.Get("error")):
+			default:
+			}
+			return
+		case q.Get("code") == "":
+			http.Error(w, "No authorization code received.", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Logged in to Home Assistant. You can close this window.")
+		select {
+		case codes <- q.Get("code"):
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := config.AuthCodeURL(state)
+	fmt.Fprintf(os.Stderr, "Open this URL to log in to Home Assistant:\n\n  %s\n\n", authURL)
+	if !noBrowser {
+		if err := openBrowser(authURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not open a browser: %v\n", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), oauthLoginTimeout)
+	defer cancel()
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for login")
+	}
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return fmt.Errorf("exchange authorization code: %w", err)
+	}
+	path, err := storedTokenPath(conn.context, ".oauth")
+	if err != nil {
+		return err
+	}
+	creds := &oauthCredentials{URL: conn.url, ClientID: clientID, OAuthToken: *token}
+	if err := saveOAuthCredentials(path, creds); err != nil {
+		return err
+	}
+
+	t := tokenConfig{TokenOAuth: path}
+	if err := saveTokenConfig(cmd, conn.context, t); err != nil {
+		return err
+	}
+	printSuccess("Logged in to %s (%s)", contextLabel(conn.context), t.describe())
+	return nil
+}
+
+// saveTokenConfig points a context, or the top level, at a new token
+// source, removing the token files it previously used.
+func saveTokenConfig(cmd *cobra.Command, name string, t tokenConfig) error {
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+	old, err := f.tokenConfig(name)
+	if err != nil {
+		return err
+	}
+	if err := removeStoredTokens(cmd, old, t.TokenFile+t.TokenKeyfile+t.TokenOAuth); err != nil {
+		return err
+	}
+	if err := f.setTokenConfig(name, t); err != nil {
+		return err
+	}
+	return f.save()
+}
+
+// openBrowser opens a URL in the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored access token",
+	Long: `Remove the token settings of the current context from the config file, and
+delete the token file if hago auth login created it.`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := selectedContext()
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		t, err := f.tokenConfig(name)
+		if err != nil {
+			return err
+		}
+		if t.isEmpty() {
+			return fmt.Errorf("no token configured for %s", contextLabel(name))
+		}
+
+		if err := removeStoredTokens(cmd, t, ""); err != nil {
+			return err
+		}
+		if err := f.setTokenConfig(name, tokenConfig{}); err != nil {
+			return err
+		}
+		if err := f.save(); err != nil {
+			return err
+		}
+		printSuccess("Token for %s removed", contextLabel(name))
+		return nil
+	},
+}
+
+// authStatus is the result of hago auth status.
+type authStatus struct {
+	Context     string `json:"context,omitempty"`
+	URL         string `json:"url"`
+	TokenSource string `json:"token_source"`
+	Valid       bool   `json:"valid"`
+	Version     string `json:"version,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check the access token",
+	Long: `Show where the token for the current context comes from and check that
+Home Assistant accepts it. Exits with an error if it does not.`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conn, err := resolveConnection(cmd)
+		if err != nil {
+			return err
+		}
+
+		status := authStatus{Context: conn.context, URL: conn.url}
+		if conn.token != nil {
+			status.TokenSource = conn.token.String()
+		}
+
+		switch tokenSource, err := clientTokenSource(ctx, conn.token); {
+		case err != nil:
+			status.Error = err.Error()
+		case conn.url == "":
+			status.Error = "no URL configured"
+		default:
+			c, err := newClient(conn, tokenSource)
+			if err != nil {
+				return err
+			}
+			config, err := c.Config(ctx)
+			if err != nil {
+				status.Error = err.Error()
+				break
+			}
+			status.Valid = true
+			status.Version = config.Version
+		}
+
+		if err := printResult(status); err != nil {
+			return err
+		}
+		if !status.Valid {
+			return fmt.Errorf("not authenticated to %s", contextLabel(conn.context))
+		}
+		return nil
+	},
+}
+
+var authWhoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the authenticated user",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		user, err := getClient().CurrentUser(ctx)
+		if err != nil {
+			return err
+		}
+		return printResult(user)
+	},
+}
+
+// readToken reads a token from r. If r is a terminal, it prompts on stderr
+// and does not echo the token.
+func readToken(r *os.File) (string, error) {
+	if isTerminal(r) {
+		token, err := promptSecret(r, "Paste your Long-Lived Access Token: ")
+		if err != nil {
+			return "", fmt.Errorf("read token: %w", err)
+		}
+		return strings.TrimSpace(token), nil
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// selectedContext returns the context selected by --context, HAGO_CONTEXT
+// or current_context.
+func selectedContext() string {
+	if name := viper.GetString("context"); name != "" {
+		return name
+	}
+	return viper.GetString("current_context")
+}
+
+// contextLabel names a context in messages.
+func contextLabel(name string) string {
+	if name == "" {
+		return "the default configuration"
+	}
+	return fmt.Sprintf("context %q", name)
+}
+
+// tokensDir returns the directory hago auth login stores tokens in.
+func tokensDir() (string, error) {
+	dir, err := secretsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tokens"), nil
+}
+
+// storedTokenPath returns the file hago auth login stores a context's token
+// in.
+func storedTokenPath(name, ext string) (string, error) {
+	dir, err := tokensDir()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = "default"
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("context name %q cannot be used as a file name", name)
+	}
+	return filepath.Join(dir, name+ext), nil
+}
+
+// removeStoredTokens deletes the token files of t that hago auth login
+// created, except keep. OAuth refresh tokens are revoked first.
+func removeStoredTokens(cmd *cobra.Command, t tokenConfig, keep string) error {
+	for _, path := range []string{t.TokenFile, t.TokenKeyfile, t.TokenOAuth} {
+		path = expandHome(path)
+		if path == "" || path == keep {
+			continue
+		}
+		stored, err := isStoredToken(path)
+		if err != nil {
+			return err
+		}
+		if !stored {
+			continue
+		}
+		if path == expandHome(t.TokenOAuth) {
+			revokeOAuth(cmd, path)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove token: %w", err)
+		}
+	}
+	return nil
+}
+
+// revokeOAuth revokes the refresh token in an OAuth credentials file. It
+// only warns on failure, so that an unreachable server does not prevent
+// removing the credentials.
+func revokeOAuth(cmd *cobra.Command, path string) {
+	creds, err := loadOAuthCredentials(path)
+	if err == nil && creds.RefreshToken != "" {
+		conn := &connection{timeout: viper.GetDuration("timeout")}
+		if resolved, resolveErr := resolveConnection(cmd); resolveErr == nil {
+			conn = resolved
+		}
+		config := &hago.OAuthConfig{BaseURL: creds.URL, ClientID: creds.ClientID, HTTPClient: conn.httpClient()}
+		err = config.Revoke(cmd.Context(), creds.RefreshToken)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not revoke OAuth refresh token: %v\n", err)
+	}
+}
+
+// isStoredToken reports whether path was created by hago auth login, so
+// that logout never deletes a file the user manages.
+func isStoredToken(path string) (bool, error) {
+	dir, err := tokensDir()
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false, nil
+	}
+	return filepath.IsLocal(rel), nil
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authWhoamiCmd)
+
+	authLoginCmd.Flags().String("store", "keyfile", "Where to store the token (keyfile, file, config)")
+	authLoginCmd.Flags().Bool("no-verify", false, "Store the token without checking it against Home Assistant")
+	authLoginCmd.Flags().Bool("oauth", false, "Log in through the Home Assistant login page instead of pasting a token")
+	authLoginCmd.Flags().Bool("no-browser", false, "With --oauth, print the login URL instead of opening a browser")
+}
@@ -1,14 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/rmrfslashbin/hago"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var automationCmd = &cobra.Command{
@@ -169,21 +167,17 @@ WARNING: This uses an undocumented REST API endpoint that may change without not
 Examples:
   hago automation get my_automation
   hago automation get my_automation -o json
-  hago automation get my_automation --yaml > automation.yaml`,
+  hago automation get my_automation -o yaml > automation.yaml`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
+		applyYAMLFlag(cmd)
 		config, err := getClient().AutomationGet(ctx, args[0])
 		if err != nil {
 			return err
 		}
 
-		asYAML, _ := cmd.Flags().GetBool("yaml")
-		if asYAML {
-			return yaml.NewEncoder(os.Stdout).Encode(config)
-		}
-
 		return printResult(config)
 	},
 }
@@ -228,13 +222,9 @@ Examples:
 			return fmt.Errorf("no configuration provided (use --file or pipe to stdin)")
 		}
 
-		// Parse as JSON or YAML
 		var config hago.AutomationConfig
-		if err := json.Unmarshal(data, &config); err != nil {
-			// Try YAML
-			if err := yaml.Unmarshal(data, &config); err != nil {
-				return fmt.Errorf("parse config (tried JSON and YAML): %w", err)
-			}
+		if err := decodeConfig(data, &config); err != nil {
+			return err
 		}
 
 		// Ensure ID matches argument
@@ -290,6 +280,7 @@ func init() {
 
 	// Get flags
 	automationGetCmd.Flags().Bool("yaml", false, "Output as YAML")
+	automationGetCmd.Flags().MarkDeprecated("yaml", "use -o yaml")
 
 	// Save flags
 	automationSaveCmd.Flags().StringP("file", "f", "", "File containing automation configuration (JSON or YAML)")
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// column is a table column for -o table, csv and tsv.
+type column struct {
+	header string
+	value  func(item any) string
+}
+
+// col builds a column for items of type T.
+func col[T any](header string, value func(T) string) column {
+	return column{header: header, value: func(item any) string {
+		v, ok := item.(T)
+		if !ok {
+			return ""
+		}
+		return value(v)
+	}}
+}
+
+// columnsFor returns the default columns for a result item, or nil if the
+// type has none.
+func columnsFor(item any) []column {
+	switch item.(type) {
+	case hago.State:
+		return []column{
+			col("ENTITY_ID", func(s hago.State) string { return s.EntityID }),
+			col("STATE", func(s hago.State) string { return s.State }),
+			col("NAME", func(s hago.State) string { return attrString(s.Attributes, "friendly_name") }),
+			col("LAST_CHANGED", func(s hago.State) string { return formatTime(s.LastChanged) }),
+		}
+	case hago.HistoryEntry:
+		return []column{
+			col("ENTITY_ID", func(e hago.HistoryEntry) string { return e.EntityID }),
+			col("STATE", func(e hago.HistoryEntry) string { return e.State }),
+			col("LAST_CHANGED", func(e hago.HistoryEntry) string { return formatTime(e.LastChanged) }),
+		}
+	case hago.LogbookEntry:
+		return []column{
+			col("WHEN", func(e hago.LogbookEntry) string { return formatTime(e.When) }),
+			col("ENTITY_ID", func(e hago.LogbookEntry) string { return e.EntityID }),
+			col("NAME", func(e hago.LogbookEntry) string { return e.Name }),
+			col("MESSAGE", func(e hago.LogbookEntry) string { return e.Message }),
+			col("STATE", func(e hago.LogbookEntry) string { return e.State }),
+		}
+	case hago.StateChangedData:
+		return []column{
+			col("ENTITY_ID", func(d hago.StateChangedData) string { return d.EntityID }),
+			col("OLD_STATE", func(d hago.StateChangedData) string { return stateValue(d.OldState) }),
+			col("NEW_STATE", func(d hago.StateChangedData) string { return stateValue(d.NewState) }),
+			col("LAST_CHANGED", func(d hago.StateChangedData) string {
+				if d.NewState == nil {
+					return ""
+				}
+				return formatTime(d.NewState.LastChanged)
+			}),
+		}
+	case hago.EventMessage:
+		return []column{
+			col("TIME_FIRED", func(e hago.EventMessage) string { return formatTime(e.TimeFired) }),
+			col("EVENT_TYPE", func(e hago.EventMessage) string { return e.EventType }),
+			col("ORIGIN", func(e hago.EventMessage) string { return e.Origin }),
+			col("CONTEXT_ID", func(e hago.EventMessage) string { return e.Context.ID }),
+		}
+	case hago.Event:
+		return []column{
+			col("EVENT", func(e hago.Event) string { return e.Event }),
+			col("LISTENERS", func(e hago.Event) string { return strconv.Itoa(e.ListenerCount) }),
+		}
+	case hago.Service:
+		return []column{
+			col("DOMAIN", func(s hago.Service) string { return s.Domain }),
+			col("SERVICES", func(s hago.Service) string {
+				return strings.Join(slices.Sorted(maps.Keys(s.Services)), ",")
+			}),
+		}
+	case hago.EntityRegistryEntry:
+		return []column{
+			col("ENTITY_ID", func(e hago.EntityRegistryEntry) string { return e.EntityID }),
+			col("NAME", func(e hago.EntityRegistryEntry) string {
+				if e.Name != nil {
+					return *e.Name
+				}
+				return stringValue(e.OriginalName)
+			}),
+			col("PLATFORM", func(e hago.EntityRegistryEntry) string { return e.Platform }),
+			col("AREA", func(e hago.EntityRegistryEntry) string { return stringValue(e.AreaID) }),
+			col("DEVICE_ID", func(e hago.EntityRegistryEntry) string { return stringValue(e.DeviceID) }),
+			col("LABELS", func(e hago.EntityRegistryEntry) string { return strings.Join(e.Labels, ",") }),
+			col("DISABLED_BY", func(e hago.EntityRegistryEntry) string { return stringValue(e.DisabledBy) }),
+		}
+	case hago.DeviceRegistryEntry:
+		return []column{
+			col("ID", func(d hago.DeviceRegistryEntry) string { return d.ID }),
+			col("NAME", func(d hago.DeviceRegistryEntry) string {
+				if d.NameByUser != nil {
+					return *d.NameByUser
+				}
+				return d.Name
+			}),
+			col("MANUFACTURER", func(d hago.DeviceRegistryEntry) string { return stringValue(d.Manufacturer) }),
+			col("MODEL", func(d hago.DeviceRegistryEntry) string { return stringValue(d.Model) }),
+			col("AREA", func(d hago.DeviceRegistryEntry) string { return stringValue(d.AreaID) }),
+			col("LABELS", func(d hago.DeviceRegistryEntry) string { return strings.Join(d.Labels, ",") }),
+		}
+	case hago.AreaRegistryEntry:
+		return []column{
+			col("AREA_ID", func(a hago.AreaRegistryEntry) string { return a.AreaID }),
+			col("NAME", func(a hago.AreaRegistryEntry) string { return a.Name }),
+			col("FLOOR", func(a hago.AreaRegistryEntry) string { return stringValue(a.FloorID) }),
+			col("LABELS", func(a hago.AreaRegistryEntry) string { return strings.Join(a.Labels, ",") }),
+		}
+	case hago.FloorRegistryEntry:
+		return []column{
+			col("FLOOR_ID", func(f hago.FloorRegistryEntry) string { return f.FloorID }),
+			col("NAME", func(f hago.FloorRegistryEntry) string { return f.Name }),
+			col("LEVEL", func(f hago.FloorRegistryEntry) string {
+				if f.Level == nil {
+					return ""
+				}
+				return strconv.Itoa(*f.Level)
+			}),
+		}
+	case hago.LabelRegistryEntry:
+		return []column{
+			col("LABEL_ID", func(l hago.LabelRegistryEntry) string { return l.LabelID }),
+			col("NAME", func(l hago.LabelRegistryEntry) string { return l.Name }),
+			col("COLOR", func(l hago.LabelRegistryEntry) string { return stringValue(l.Color) }),
+			col("DESCRIPTION", func(l hago.LabelRegistryEntry) string { return stringValue(l.Description) }),
+		}
+	case hago.Dashboard:
+		return []column{
+			col("URL_PATH", func(d hago.Dashboard) string { return d.URLPath }),
+			col("TITLE", func(d hago.Dashboard) string { return d.Title }),
+			col("MODE", func(d hago.Dashboard) string { return d.Mode }),
+			col("SIDEBAR", func(d hago.Dashboard) string { return strconv.FormatBool(d.ShowInSidebar) }),
+			col("REQUIRE_ADMIN", func(d hago.Dashboard) string { return strconv.FormatBool(d.RequireAdmin) }),
+		}
+	case hago.Resource:
+		return []column{
+			col("ID", func(r hago.Resource) string { return r.ID }),
+			col("TYPE", func(r hago.Resource) string { return r.Type }),
+			col("URL", func(r hago.Resource) string { return r.URL }),
+		}
+	case hago.Calendar:
+		return []column{
+			col("ENTITY_ID", func(c hago.Calendar) string { return c.EntityID }),
+			col("NAME", func(c hago.Calendar) string { return c.Name }),
+		}
+	case hago.CalendarEvent:
+		return []column{
+			col("START", func(e hago.CalendarEvent) string { return e.Start }),
+			col("END", func(e hago.CalendarEvent) string { return e.End }),
+			col("SUMMARY", func(e hago.CalendarEvent) string { return e.Summary }),
+			col("LOCATION", func(e hago.CalendarEvent) string { return e.Location }),
+		}
+	case hago.AutomationConfig:
+		return []column{
+			col("ID", func(a hago.AutomationConfig) string { return a.ID }),
+			col("ALIAS", func(a hago.AutomationConfig) string { return a.Alias }),
+			col("MODE", func(a hago.AutomationConfig) string { return a.Mode }),
+			col("TRIGGERS", func(a hago.AutomationConfig) string { return strconv.Itoa(len(a.Trigger)) }),
+			col("ACTIONS", func(a hago.AutomationConfig) string { return strconv.Itoa(len(a.Action)) }),
+		}
+	case hago.ScriptConfig:
+		return []column{
+			col("ID", func(s hago.ScriptConfig) string { return s.ID }),
+			col("ALIAS", func(s hago.ScriptConfig) string { return s.Alias }),
+			col("MODE", func(s hago.ScriptConfig) string { return s.Mode }),
+			col("STEPS", func(s hago.ScriptConfig) string { return strconv.Itoa(len(s.Sequence)) }),
+		}
+	case contextInfo:
+		return []column{
+			col("CURRENT", func(c contextInfo) string {
+				if c.Current {
+					return "*"
+				}
+				return ""
+			}),
+			col("NAME", func(c contextInfo) string { return c.Name }),
+			col("URL", func(c contextInfo) string { return c.URL }),
+			col("TOKEN", func(c contextInfo) string { return c.TokenSource }),
+			col("TIMEOUT", func(c contextInfo) string { return c.Timeout }),
+		}
+	case hago.CurrentUser:
+		return []column{
+			col("ID", func(u hago.CurrentUser) string { return u.ID }),
+			col("NAME", func(u hago.CurrentUser) string { return u.Name }),
+			col("OWNER", func(u hago.CurrentUser) string { return strconv.FormatBool(u.IsOwner) }),
+			col("ADMIN", func(u hago.CurrentUser) string { return strconv.FormatBool(u.IsAdmin) }),
+		}
+	case hago.RefreshToken:
+		return []column{
+			col("ID", func(t hago.RefreshToken) string { return t.ID }),
+			col("TYPE", func(t hago.RefreshToken) string { return t.Type }),
+			col("NAME", func(t hago.RefreshToken) string { return t.Name() }),
+			col("CREATED", func(t hago.RefreshToken) string { return formatTime(t.CreatedAt) }),
+			col("LAST_USED", func(t hago.RefreshToken) string {
+				if t.LastUsedAt == nil {
+					return ""
+				}
+				return formatTime(*t.LastUsedAt)
+			}),
+			col("LAST_USED_IP", func(t hago.RefreshToken) string { return stringValue(t.LastUsedIP) }),
+			col("CURRENT", func(t hago.RefreshToken) string {
+				if t.IsCurrent {
+					return "*"
+				}
+				return ""
+			}),
+		}
+	case hago.User:
+		return []column{
+			col("ID", func(u hago.User) string { return u.ID }),
+			col("USERNAME", func(u hago.User) string { return stringValue(u.Username) }),
+			col("NAME", func(u hago.User) string { return u.Name }),
+			col("GROUPS", func(u hago.User) string { return strings.Join(u.GroupIDs, ",") }),
+			col("ACTIVE", func(u hago.User) string { return strconv.FormatBool(u.IsActive) }),
+			col("OWNER", func(u hago.User) string { return strconv.FormatBool(u.IsOwner) }),
+			col("LOCAL_ONLY", func(u hago.User) string { return strconv.FormatBool(u.LocalOnly) }),
+		}
+	case hago.Person:
+		return []column{
+			col("ID", func(p hago.Person) string { return p.ID }),
+			col("NAME", func(p hago.Person) string { return p.Name }),
+			col("USER_ID", func(p hago.Person) string { return stringValue(p.UserID) }),
+			col("DEVICE_TRACKERS", func(p hago.Person) string { return strings.Join(p.DeviceTrackers, ",") }),
+			col("SOURCE", func(p hago.Person) string {
+				if p.YAML {
+					return "yaml"
+				}
+				return "storage"
+			}),
+		}
+	case authStatus:
+		return []column{
+			col("CONTEXT", func(a authStatus) string { return a.Context }),
+			col("URL", func(a authStatus) string { return a.URL }),
+			col("TOKEN", func(a authStatus) string { return a.TokenSource }),
+			col("VALID", func(a authStatus) string { return strconv.FormatBool(a.Valid) }),
+			col("VERSION", func(a authStatus) string { return a.Version }),
+			col("ERROR", func(a authStatus) string { return a.Error }),
+		}
+	}
+	return nil
+}
+
+// formatTime formats a timestamp in local time, or "" if it is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// stringValue returns *p, or "" if p is nil.
+func stringValue(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// stateValue returns the state of s, or "" if s is nil.
+func stateValue(s *hago.State) string {
+	if s == nil {
+		return ""
+	}
+	return s.State
+}
+
+// attrString returns an attribute formatted as a string.
+func attrString(attrs map[string]any, key string) string {
+	v, ok := attrs[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -1,13 +1,23 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 )
 
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Get Home Assistant configuration",
-	Long:  `Retrieve the current Home Assistant configuration including version, location, and units.`,
+	Long: `Retrieve the current Home Assistant configuration including version, location, and units.
+
+The subcommands manage named contexts in the hago config file, one per Home
+Assistant instance:
+
+  hago config set-context lab --url https://lab.local:8123 --token-env LAB_TOKEN
+  hago config use-context lab
+  hago config get-contexts
+  hago --context prod state list`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 		config, err := getClient().Config(ctx)
@@ -32,7 +42,180 @@ var checkConfigCmd = &cobra.Command{
 	},
 }
 
+var getContextsCmd = &cobra.Command{
+	Use:               "get-contexts",
+	Short:             "List contexts",
+	Long:              `List the contexts in the config file. The current context is marked.`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		infos, err := f.contextInfos()
+		if err != nil {
+			return err
+		}
+		return printResult(infos)
+	},
+}
+
+var currentContextCmd = &cobra.Command{
+	Use:               "current-context",
+	Short:             "Print the current context",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		current := f.currentContext()
+		if current == "" {
+			return fmt.Errorf("current context is not set")
+		}
+		fmt.Println(current)
+		return nil
+	},
+}
+
+var useContextCmd = &cobra.Command{
+	Use:               "use-context <name>",
+	Short:             "Set the current context",
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		if _, err := f.context(args[0]); err != nil {
+			return err
+		}
+		f.setCurrentContext(args[0])
+		if err := f.save(); err != nil {
+			return err
+		}
+		printSuccess("Switched to context %q", args[0])
+		return nil
+	},
+}
+
+var setContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create or update a context",
+	Long: `Create a context, or update the given settings of an existing one.
+
+Examples:
+  hago config set-context home --url http://homeassistant.local:8123 --token-env HAGO_HOME_TOKEN
+  hago config set-context work --url https://ha.work.example --token-command "pass show ha/work"
+  hago config set-context lab --url https://lab.local:8123 --ca-file ~/lab-ca.pem --timeout 10s
+  hago config set-context ci --url http://ha-ci:8123 --insecure-skip-tls-verify`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		contexts, err := f.contexts()
+		if err != nil {
+			return err
+		}
+		c, exists := contexts[args[0]]
+
+		flags := cmd.Flags()
+		if flags.Changed("url") {
+			c.URL, _ = flags.GetString("url")
+		}
+		// Token flags are mutually exclusive and replace any existing source.
+		switch {
+		case flags.Changed("token"):
+			token, _ := flags.GetString("token")
+			c.tokenConfig = tokenConfig{Token: token}
+		case flags.Changed("token-env"):
+			env, _ := flags.GetString("token-env")
+			c.tokenConfig = tokenConfig{TokenEnv: env}
+		case flags.Changed("token-command"):
+			command, _ := flags.GetString("token-command")
+			c.tokenConfig = tokenConfig{TokenCommand: command}
+		case flags.Changed("token-file"):
+			file, _ := flags.GetString("token-file")
+			c.tokenConfig = tokenConfig{TokenFile: file}
+		}
+		if flags.Changed("timeout") {
+			timeout, _ := flags.GetDuration("timeout")
+			c.Timeout = timeout.String()
+		}
+		if flags.Changed("ca-file") {
+			c.CAFile, _ = flags.GetString("ca-file")
+		}
+		if flags.Changed("insecure-skip-tls-verify") {
+			c.InsecureSkipTLSVerify, _ = flags.GetBool("insecure-skip-tls-verify")
+		}
+
+		if err := f.setContext(args[0], c); err != nil {
+			return err
+		}
+		if err := f.save(); err != nil {
+			return err
+		}
+		if exists {
+			printSuccess("Context %q updated", args[0])
+		} else {
+			printSuccess("Context %q created", args[0])
+		}
+		return nil
+	},
+}
+
+var deleteContextCmd = &cobra.Command{
+	Use:               "delete-context <name>",
+	Short:             "Delete a context",
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: skipClient,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		if !f.deleteContext(args[0]) {
+			return fmt.Errorf("context %q not found in %s", args[0], f.path)
+		}
+		if f.currentContext() == args[0] {
+			f.setCurrentContext("")
+		}
+		if err := f.save(); err != nil {
+			return err
+		}
+		printSuccess("Context %q deleted", args[0])
+		return nil
+	},
+}
+
+// skipClient is a PersistentPreRunE for commands that do not talk to Home
+// Assistant, such as those editing the config file.
+func skipClient(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(checkConfigCmd)
+	configCmd.AddCommand(getContextsCmd)
+	configCmd.AddCommand(currentContextCmd)
+	configCmd.AddCommand(useContextCmd)
+	configCmd.AddCommand(setContextCmd)
+	configCmd.AddCommand(deleteContextCmd)
+
+	setContextCmd.Flags().String("url", "", "Home Assistant URL")
+	setContextCmd.Flags().String("token", "", "Long-Lived Access Token, stored in plain text")
+	setContextCmd.Flags().String("token-env", "", "Environment variable holding the token")
+	setContextCmd.Flags().String("token-command", "", "Command that prints the token, e.g. a password manager CLI")
+	setContextCmd.Flags().String("token-file", "", "File holding the token, readable only by you")
+	setContextCmd.Flags().Duration("timeout", 0, "Request timeout")
+	setContextCmd.Flags().String("ca-file", "", "PEM file with additional CA certificates to trust")
+	setContextCmd.Flags().Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification (insecure)")
+	setContextCmd.MarkFlagsMutuallyExclusive("token", "token-env", "token-command", "token-file")
 }
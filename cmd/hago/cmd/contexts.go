@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// contextConfig is a named connection profile in the config file:
+//
+//	current_context: home
+//	contexts:
+//	  home:
+//	    url: https://ha.example.com
+//	    token_command: pass show home-assistant
+//	  lab:
+//	    url: https://lab.local:8123
+//	    token_keyfile: ~/.config/hago/tokens/lab.enc
+//	    timeout: 10s
+//	    ca_file: ~/lab-ca.pem
+type contextConfig struct {
+	URL                   string `yaml:"url,omitempty" json:"url,omitempty"`
+	tokenConfig           `yaml:",inline"`
+	Timeout               string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	CAFile                string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure_skip_tls_verify,omitempty" json:"insecure_skip_tls_verify,omitempty"`
+}
+
+// contextInfo is a context as listed by hago config get-contexts.
+type contextInfo struct {
+	Current     bool   `json:"current"`
+	Name        string `json:"name"`
+	TokenSource string `json:"token_source,omitempty"`
+	contextConfig
+}
+
+// configFile is the hago config file. It is edited as a YAML node tree so
+// that comments and unrelated settings are preserved when it is saved.
+type configFile struct {
+	path string
+	root *yaml.Node
+}
+
+// configFilePath returns the config file to read and write: the --config
+// flag, the file viper found, or ~/.hago.yaml.
+func configFilePath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("find home directory: %w", err)
+	}
+	return filepath.Join(home, ".hago.yaml"), nil
+}
+
+// loadConfigFile reads the config file. A missing file is treated as empty.
+func loadConfigFile() (*configFile, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f := &configFile{path: path, root: &yaml.Node{Kind: yaml.MappingNode}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if len(doc.Content) > 0 {
+		if doc.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("parse config %s: not a mapping", path)
+		}
+		f.root = doc.Content[0]
+	}
+	return f, nil
+}
+
+// save writes the config file, readable only by the owner since it may
+// contain tokens.
+func (f *configFile) save() error {
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{f.root}}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	if err := writePrivateFile(f.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+// currentContext returns the current_context setting.
+func (f *configFile) currentContext() string {
+	if n := mappingValue(f.root, "current_context"); n != nil {
+		return n.Value
+	}
+	return ""
+}
+
+// setCurrentContext sets or, if name is empty, removes current_context.
+func (f *configFile) setCurrentContext(name string) {
+	if name == "" {
+		deleteMappingKey(f.root, "current_context")
+		return
+	}
+	setMappingValue(f.root, "current_context", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name})
+}
+
+// contexts returns all contexts by name.
+func (f *configFile) contexts() (map[string]contextConfig, error) {
+	contexts := make(map[string]contextConfig)
+	if n := mappingValue(f.root, "contexts"); n != nil {
+		if err := n.Decode(&contexts); err != nil {
+			return nil, fmt.Errorf("parse contexts in %s: %w", f.path, err)
+		}
+	}
+	return contexts, nil
+}
+
+// context returns a context by name.
+func (f *configFile) context(name string) (contextConfig, error) {
+	contexts, err := f.contexts()
+	if err != nil {
+		return contextConfig{}, err
+	}
+	c, ok := contexts[name]
+	if !ok {
+		return contextConfig{}, fmt.Errorf("context %q not found in %s", name, f.path)
+	}
+	return c, nil
+}
+
+// setContext creates or replaces a context.
+func (f *configFile) setContext(name string, c contextConfig) error {
+	var value yaml.Node
+	if err := value.Encode(c); err != nil {
+		return fmt.Errorf("encode context: %w", err)
+	}
+	contexts := mappingValue(f.root, "contexts")
+	if contexts == nil || contexts.Kind != yaml.MappingNode {
+		contexts = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(f.root, "contexts", contexts)
+	}
+	setMappingValue(contexts, name, &value)
+	return nil
+}
+
+// deleteContext removes a context and reports whether it existed.
+func (f *configFile) deleteContext(name string) bool {
+	contexts := mappingValue(f.root, "contexts")
+	if contexts == nil {
+		return false
+	}
+	return deleteMappingKey(contexts, name)
+}
+
+// contextInfos lists the contexts sorted by name.
+func (f *configFile) contextInfos() ([]contextInfo, error) {
+	contexts, err := f.contexts()
+	if err != nil {
+		return nil, err
+	}
+	current := f.currentContext()
+	infos := make([]contextInfo, 0, len(contexts))
+	for name, c := range contexts {
+		infos = append(infos, contextInfo{
+			Current:       name == current,
+			Name:          name,
+			TokenSource:   c.describe(),
+			contextConfig: c,
+		})
+	}
+	slices.SortFunc(infos, func(a, b contextInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in a mapping node, keeping its position if it
+// already exists.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// deleteMappingKey removes key from a mapping node and reports whether it
+// was present.
+func deleteMappingKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = slices.Delete(m.Content, i, i+2)
+			return true
+		}
+	}
+	return false
+}
+
+// connection holds the settings used to connect to Home Assistant.
+type connection struct {
+	context string
+	url     string
+	token   secretSource
+	timeout time.Duration
+	tls     *tls.Config
+}
+
+// resolveConnection works out the connection settings. Flags and HAGO_*
+// environment variables take precedence, then the selected context (from
+// --context, HAGO_CONTEXT or current_context), then top-level settings in
+// the config file. The token is not read until it is needed, so commands
+// that fix a broken token source can still resolve the connection.
+func resolveConnection(cmd *cobra.Command) (*connection, error) {
+	var ctxConfig contextConfig
+	name := selectedContext()
+	if name != "" {
+		f, err := loadConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		if ctxConfig, err = f.context(name); err != nil {
+			return nil, err
+		}
+	}
+
+	conn := &connection{
+		context: name,
+		url:     setting(cmd, "url", ctxConfig.URL),
+		timeout: viper.GetDuration("timeout"),
+	}
+
+	var err error
+	switch {
+	case explicitlySet(cmd, "token"):
+		conn.token = staticSecret{token: viper.GetString("token"), origin: "flag or HAGO_TOKEN"}
+	case !ctxConfig.tokenConfig.isEmpty():
+		if conn.token, err = ctxConfig.source(); err != nil {
+			return nil, fmt.Errorf("context %q: %w", name, err)
+		}
+	default:
+		if conn.token, err = topLevelTokenConfig().source(); err != nil {
+			return nil, err
+		}
+	}
+
+	if ctxConfig.Timeout != "" && !explicitlySet(cmd, "timeout") {
+		timeout, err := time.ParseDuration(ctxConfig.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: invalid timeout: %w", name, err)
+		}
+		conn.timeout = timeout
+	}
+
+	caFile := setting(cmd, "ca_file", ctxConfig.CAFile)
+	insecure := viper.GetBool("insecure_skip_tls_verify")
+	if !explicitlySet(cmd, "insecure_skip_tls_verify") && ctxConfig.InsecureSkipTLSVerify {
+		insecure = true
+	}
+	if caFile != "" || insecure {
+		tlsConfig, err := newTLSConfig(caFile, insecure)
+		if err != nil {
+			return nil, err
+		}
+		conn.tls = tlsConfig
+	}
+
+	if o, ok := conn.token.(*oauthSecret); ok {
+		o.httpClient = conn.httpClient()
+	}
+
+	return conn, nil
+}
+
+// httpClient returns an HTTP client with the connection's timeout and TLS
+// settings, for requests made outside hago.Client.
+func (c *connection) httpClient() *http.Client {
+	httpClient := &http.Client{Timeout: c.timeout}
+	if c.tls != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.tls
+		httpClient.Transport = transport
+	}
+	return httpClient
+}
+
+// setting returns a string setting: the flag or environment variable if
+// set, otherwise the context value, otherwise the top-level config value.
+func setting(cmd *cobra.Command, key, contextValue string) string {
+	if contextValue != "" && !explicitlySet(cmd, key) {
+		return contextValue
+	}
+	return viper.GetString(key)
+}
+
+// explicitlySet reports whether a setting was given as a flag or HAGO_*
+// environment variable, which override the context.
+func explicitlySet(cmd *cobra.Command, key string) bool {
+	if flag := cmd.Flags().Lookup(strings.ReplaceAll(key, "_", "-")); flag != nil && flag.Changed {
+		return true
+	}
+	_, ok := os.LookupEnv("HAGO_" + strings.ToUpper(key))
+	return ok
+}
+
+// newTLSConfig builds a TLS configuration that trusts the system roots plus
+// the certificates in caFile.
+func newTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(expandHome(caFile))
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	config.RootCAs = roots
+	return config, nil
+}
+
+// expandHome expands a leading ~/ to the home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
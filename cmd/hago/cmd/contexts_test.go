@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigFile_SaveMakesFilePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no permission bits on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "hago.yaml")
+	if err := os.WriteFile(path, []byte("url: http://ha.local:8123\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+
+	f, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	if err := f.setTokenConfig("", tokenConfig{Token: "secret"}); err != nil {
+		t.Fatalf("setTokenConfig() error = %v", err)
+	}
+	if err := f.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %04o, want 0600", perm)
+	}
+}
@@ -35,7 +35,8 @@ var eventFireCmd = &cobra.Command{
 
 Examples:
   hago event fire my_custom_event
-  hago event fire my_custom_event --data '{"key": "value"}'`,
+  hago event fire my_custom_event --data '{"key": "value"}'
+  hago event fire my_custom_event --track`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
@@ -51,6 +52,14 @@ Examples:
 			data = parsed
 		}
 
+		if track, _ := cmd.Flags().GetBool("track"); track {
+			eventCtx, err := getClient().FireEventTracked(ctx, eventType, data)
+			if err != nil {
+				return err
+			}
+			return printResult(eventCtx)
+		}
+
 		if err := getClient().FireEvent(ctx, eventType, data); err != nil {
 			return err
 		}
@@ -65,4 +74,5 @@ func init() {
 	eventCmd.AddCommand(eventFireCmd)
 
 	eventFireCmd.Flags().StringP("data", "d", "", "Event data as JSON")
+	eventFireCmd.Flags().Bool("track", false, "Fire over WebSocket and print the context of the event")
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/rmrfslashbin/hago/servicegen"
+	"github.com/spf13/cobra"
+)
+
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate code",
+	Long:  `Generate Go code from a Home Assistant instance.`,
+}
+
+var genServicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "Generate typed service wrappers",
+	Long: `Generate a Go package per domain with a typed parameter struct and function
+for every service, using field selectors for field types and service
+descriptions for doc comments.
+
+The service catalog is read from Home Assistant, or from a JSON file saved
+with "hago service list".
+
+Examples:
+  hago gen services --dir ./haservices
+  hago gen services --dir ./haservices --domain light --domain climate
+  hago service list > services.json && hago gen services --from services.json`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A saved catalog needs no connection to Home Assistant.
+		if from, _ := cmd.Flags().GetString("from"); from != "" {
+			return nil
+		}
+		return initializeClient(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		from, _ := cmd.Flags().GetString("from")
+		outDir, _ := cmd.Flags().GetString("dir")
+		domains, _ := cmd.Flags().GetStringSlice("domain")
+
+		var services []hago.Service
+		if from != "" {
+			data, err := os.ReadFile(from)
+			if err != nil {
+				return fmt.Errorf("read service catalog: %w", err)
+			}
+			if err := json.Unmarshal(data, &services); err != nil {
+				return fmt.Errorf("parse service catalog: %w", err)
+			}
+		} else {
+			var err error
+			services, err = getClient().Services(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		files, err := servicegen.Generate(services, servicegen.Options{Domains: domains})
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			filename := filepath.Join(outDir, filepath.FromSlash(f.Path))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				return fmt.Errorf("create output directory: %w", err)
+			}
+			if err := os.WriteFile(filename, f.Content, 0644); err != nil {
+				return fmt.Errorf("write %s: %w", filename, err)
+			}
+			printSuccess("Generated: %s", filename)
+		}
+
+		printSuccess("\nGenerated %d package(s)", len(files))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genCmd)
+	genCmd.AddCommand(genServicesCmd)
+
+	genServicesCmd.Flags().String("dir", "haservices", "Output directory")
+	genServicesCmd.Flags().String("from", "", "Read the service catalog from a JSON file instead of Home Assistant")
+	genServicesCmd.Flags().StringSlice("domain", nil, "Only generate these domains")
+}
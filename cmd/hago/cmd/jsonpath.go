@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a JSONPath expression.
+type pathStep struct {
+	// key selects an object member; index selects an array element.
+	key   string
+	index int
+	// kind is one of "key", "index" or "wildcard".
+	kind string
+	// recursive makes the step match at any depth (..key).
+	recursive bool
+}
+
+// parseJSONPath parses the subset of JSONPath supported by -o jsonpath:
+// member access (.name or ['name']), array indexes ([0], negative from the
+// end), wildcards (.* or [*]) and recursive descent (..name). The leading
+// "$" and kubectl-style surrounding braces are optional, e.g.
+// {.items[*].entity_id} or $[*].attributes.friendly_name.
+func parseJSONPath(expr string) ([]pathStep, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "{") && strings.HasSuffix(expr, "}") {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	expr = strings.TrimPrefix(expr, "$")
+	if expr == "" {
+		return nil, nil
+	}
+
+	var steps []pathStep
+	for expr != "" {
+		var step pathStep
+		switch {
+		case strings.HasPrefix(expr, ".."):
+			step.recursive = true
+			expr = expr[2:]
+		case expr[0] == '.':
+			expr = expr[1:]
+		case expr[0] == '[':
+		default:
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("unexpected %q", expr)
+			}
+		}
+
+		if strings.HasPrefix(expr, "[") {
+			end := strings.IndexByte(expr, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in %q", expr)
+			}
+			inner := strings.TrimSpace(expr[1:end])
+			expr = expr[end+1:]
+			switch {
+			case inner == "*":
+				step.kind = "wildcard"
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				step.kind, step.key = "key", inner[1:len(inner)-1]
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				step.kind, step.index = "index", i
+			}
+			steps = append(steps, step)
+			continue
+		}
+
+		end := strings.IndexAny(expr, ".[")
+		if end < 0 {
+			end = len(expr)
+		}
+		name := expr[:end]
+		expr = expr[end:]
+		switch name {
+		case "":
+			return nil, fmt.Errorf("empty member name")
+		case "*":
+			step.kind = "wildcard"
+		default:
+			step.kind, step.key = "key", name
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// evalJSONPath returns the values selected by steps from a JSON value.
+func evalJSONPath(steps []pathStep, root any) []any {
+	current := []any{root}
+	for _, step := range steps {
+		var next []any
+		for _, v := range current {
+			if step.recursive {
+				for _, d := range descendants(v) {
+					next = append(next, applyStep(step, d)...)
+				}
+				continue
+			}
+			next = append(next, applyStep(step, v)...)
+		}
+		current = next
+	}
+	return current
+}
+
+// applyStep applies a single non-recursive step to v.
+func applyStep(step pathStep, v any) []any {
+	switch step.kind {
+	case "key":
+		if obj, ok := v.(map[string]any); ok {
+			if child, ok := obj[step.key]; ok {
+				return []any{child}
+			}
+		}
+	case "index":
+		if arr, ok := v.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				return []any{arr[i]}
+			}
+		}
+	case "wildcard":
+		switch v := v.(type) {
+		case []any:
+			return v
+		case map[string]any:
+			var children []any
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				children = append(children, v[k])
+			}
+			return children
+		}
+	}
+	return nil
+}
+
+// descendants returns v and all values nested in it, depth first.
+func descendants(v any) []any {
+	values := []any{v}
+	switch v := v.(type) {
+	case []any:
+		for _, child := range v {
+			values = append(values, descendants(child)...)
+		}
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, descendants(v[k])...)
+		}
+	}
+	return values
+}
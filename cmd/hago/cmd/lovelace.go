@@ -59,22 +59,13 @@ Examples:
 		}
 
 		force, _ := cmd.Flags().GetBool("force")
-		asYAML, _ := cmd.Flags().GetBool("yaml")
+		applyYAMLFlag(cmd)
 
 		config, err := getClient().LovelaceGetConfig(ctx, urlPath, force)
 		if err != nil {
 			return err
 		}
 
-		if asYAML {
-			// Convert JSON to YAML
-			var data any
-			if err := json.Unmarshal(config, &data); err != nil {
-				return err
-			}
-			return yaml.NewEncoder(os.Stdout).Encode(data)
-		}
-
 		return printResult(json.RawMessage(config))
 	},
 }
@@ -128,13 +119,9 @@ Examples:
 			return fmt.Errorf("no configuration provided (use --file or pipe to stdin)")
 		}
 
-		// Parse as JSON or YAML
 		var config any
-		if err := json.Unmarshal(data, &config); err != nil {
-			// Try YAML
-			if err := yaml.Unmarshal(data, &config); err != nil {
-				return fmt.Errorf("parse config (tried JSON and YAML): %w", err)
-			}
+		if err := decodeConfig(data, &config); err != nil {
+			return err
 		}
 
 		if err := getClient().LovelaceSaveConfig(ctx, urlPath, config); err != nil {
@@ -359,6 +346,7 @@ func init() {
 	lovelaceGetCmd.Flags().StringP("dashboard", "d", "", "Dashboard URL path")
 	lovelaceGetCmd.Flags().Bool("force", false, "Bypass cache")
 	lovelaceGetCmd.Flags().Bool("yaml", false, "Output as YAML")
+	lovelaceGetCmd.Flags().MarkDeprecated("yaml", "use -o yaml")
 
 	// Save flags
 	lovelaceSaveCmd.Flags().StringP("dashboard", "d", "", "Dashboard URL path")
@@ -1,28 +1,125 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
 )
 
 // outputFormat controls how results are displayed.
 var outputFormat string
 
+// headerPrinted records that a CSV or TSV header has been written, so
+// commands that print a stream of results (watch) emit it only once.
+var headerPrinted bool
+
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json", "Output format (json, pretty)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json",
+		"Output format (json, pretty, yaml, table, csv, tsv, name, template=<go template>, jsonpath=<expr>)")
+}
+
+// outputSpec is a parsed --output flag.
+type outputSpec struct {
+	format   string
+	template *template.Template
+	jsonPath []pathStep
+}
+
+// parseOutputFormat parses an --output flag value.
+func parseOutputFormat(s string) (*outputSpec, error) {
+	format, arg, hasArg := strings.Cut(s, "=")
+	spec := &outputSpec{format: format}
+	switch format {
+	case "json", "pretty", "yaml", "table", "csv", "tsv", "name":
+		if hasArg {
+			return nil, fmt.Errorf("output format %s takes no argument", format)
+		}
+	case "template", "go-template":
+		spec.format = "template"
+		tmpl, err := template.New("output").Funcs(templateFuncs).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		spec.template = tmpl
+	case "jsonpath":
+		steps, err := parseJSONPath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonpath: %w", err)
+		}
+		spec.jsonPath = steps
+	default:
+		return nil, fmt.Errorf("unknown output format %q (use json, pretty, yaml, table, csv, tsv, name, template=... or jsonpath=...)", s)
+	}
+	return spec, nil
+}
+
+// templateFuncs are the functions available to -o template.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v []any) string {
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = cellString(e)
+		}
+		return strings.Join(parts, sep)
+	},
 }
 
 // printResult outputs the result in the configured format.
 func printResult(v any) error {
-	switch outputFormat {
+	spec, err := parseOutputFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	switch spec.format {
 	case "pretty":
 		return printPrettyJSON(v)
+	case "yaml":
+		return printYAML(v)
+	case "table":
+		return printTable(os.Stdout, v)
+	case "csv":
+		return printDelimited(os.Stdout, v, ',')
+	case "tsv":
+		return printDelimited(os.Stdout, v, '\t')
+	case "name":
+		return printNames(os.Stdout, v)
+	case "template":
+		return printTemplate(os.Stdout, spec.template, v)
+	case "jsonpath":
+		return printJSONPath(os.Stdout, spec.jsonPath, v)
 	default:
 		return printJSON(v)
 	}
 }
 
+// checkStreamOutput rejects -o table for commands that print a stream of
+// results, since aligning the columns needs every row up front.
+func checkStreamOutput() error {
+	spec, err := parseOutputFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	if spec.format == "table" {
+		return fmt.Errorf("-o table cannot align a stream of results; use tsv, csv or --compact")
+	}
+	return nil
+}
+
 // printJSON outputs compact JSON.
 func printJSON(v any) error {
 	enc := json.NewEncoder(os.Stdout)
@@ -36,6 +133,231 @@ func printPrettyJSON(v any) error {
 	return enc.Encode(v)
 }
 
+// printYAML outputs YAML with the same keys as the JSON output.
+func printYAML(v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// printTemplate executes a Go template against the JSON form of v, so
+// fields are addressed by their JSON names, e.g. {{.entity_id}}.
+func printTemplate(w io.Writer, tmpl *template.Template, v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, generic); err != nil {
+		return fmt.Errorf("execute output template: %w", err)
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// printJSONPath prints the values selected by a JSONPath expression, one
+// per line. Strings are printed as is, other values as JSON.
+func printJSONPath(w io.Writer, steps []pathStep, v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	for _, value := range evalJSONPath(steps, generic) {
+		if _, err := fmt.Fprintln(w, cellString(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printTable outputs an aligned table.
+func printTable(w io.Writer, v any) error {
+	headers, rows, err := tableRows(v)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printNames prints the first table column of each row, one per line, such
+// as entity IDs for states or the names in a plain list.
+func printNames(w io.Writer, v any) error {
+	_, rows, err := tableRows(v)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, row[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printDelimited outputs CSV or TSV with a header row.
+func printDelimited(w io.Writer, v any, comma rune) error {
+	headers, rows, err := tableRows(v)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if !headerPrinted {
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+		headerPrinted = true
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// tableRows converts a result into rows. Slices produce one row per element
+// (nested slices, such as history, are flattened); anything else produces a
+// single row. Known types use their default columns; other values are shown
+// with one column per top-level JSON field.
+func tableRows(v any) (headers []string, rows [][]string, err error) {
+	items := flatten(reflect.ValueOf(v))
+	if len(items) == 0 {
+		return nil, nil, nil
+	}
+
+	if cols := columnsFor(items[0]); cols != nil {
+		for _, c := range cols {
+			headers = append(headers, c.header)
+		}
+		for _, item := range items {
+			row := make([]string, len(cols))
+			for i, c := range cols {
+				row[i] = c.value(item)
+			}
+			rows = append(rows, row)
+		}
+		return headers, rows, nil
+	}
+
+	return genericRows(items)
+}
+
+// flatten returns the elements of v, descending into nested slices and
+// dereferencing pointers.
+func flatten(v reflect.Value) []any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []any{v.Interface()}
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{v.Interface()}
+	}
+	var items []any
+	for i := range v.Len() {
+		items = append(items, flatten(v.Index(i))...)
+	}
+	return items
+}
+
+// genericRows builds rows from the JSON form of items. Objects get one
+// column per key, in sorted order; other values get a single VALUE column.
+func genericRows(items []any) (headers []string, rows [][]string, err error) {
+	values := make([]any, len(items))
+	keys := make(map[string]bool)
+	objects := true
+	for i, item := range items {
+		if values[i], err = toGeneric(item); err != nil {
+			return nil, nil, err
+		}
+		obj, ok := values[i].(map[string]any)
+		if !ok {
+			objects = false
+			continue
+		}
+		for k := range obj {
+			keys[k] = true
+		}
+	}
+
+	if !objects {
+		for _, value := range values {
+			rows = append(rows, []string{cellString(value)})
+		}
+		return []string{"VALUE"}, rows, nil
+	}
+
+	names := slices.Sorted(maps.Keys(keys))
+	for _, k := range names {
+		headers = append(headers, strings.ToUpper(k))
+	}
+	for _, value := range values {
+		obj := value.(map[string]any)
+		row := make([]string, len(names))
+		for i, k := range names {
+			row[i] = cellString(obj[k])
+		}
+		rows = append(rows, row)
+	}
+	return headers, rows, nil
+}
+
+// toGeneric converts v to its JSON form: maps, slices, strings, float64s,
+// bools and nils.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode output: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("decode output: %w", err)
+	}
+	return generic, nil
+}
+
+// cellString formats a JSON value for a table cell or a line of output.
+func cellString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
 // printSuccess outputs a success message.
 func printSuccess(format string, args ...any) {
 	fmt.Printf(format+"\n", args...)
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+func TestPrintNames(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"plain list", []string{"api", "light"}, "api\nlight\n"},
+		{"states", []hago.State{{EntityID: "light.kitchen", State: "on"}, {EntityID: "sun.sun", State: "above_horizon"}}, "light.kitchen\nsun.sun\n"},
+		{"empty", []string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printNames(&buf, tt.v); err != nil {
+				t.Fatalf("printNames() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
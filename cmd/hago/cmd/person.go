@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+)
+
+var personCmd = &cobra.Command{
+	Use:   "person",
+	Short: "Manage persons",
+	Long: `List, create, update and delete persons. A person tracks whether someone is
+home through their device trackers and can be linked to a user. Requires an
+admin token. Persons are named by ID or name; users by ID, username or name.
+
+Persons configured in configuration.yaml are listed but cannot be changed.`,
+}
+
+var personListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List persons",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		persons, err := getClient().Persons(ctx)
+		if err != nil {
+			return err
+		}
+		return printResult(persons)
+	},
+}
+
+var personCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a person",
+	Long: `Create a person.
+
+Examples:
+  hago person create "Dog Walker" --device-tracker device_tracker.walker_phone
+  hago person create Bob --user bob --device-tracker device_tracker.bob_phone,device_tracker.bob_watch`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+
+		req := &hago.CreatePersonRequest{Name: args[0]}
+		req.DeviceTrackers, _ = cmd.Flags().GetStringSlice("device-tracker")
+		req.Picture, _ = cmd.Flags().GetString("picture")
+		if user, _ := cmd.Flags().GetString("user"); user != "" {
+			userID, err := resolveUserID(ctx, client, user)
+			if err != nil {
+				return err
+			}
+			req.UserID = userID
+		}
+
+		person, err := client.CreatePerson(ctx, req)
+		if err != nil {
+			return err
+		}
+		return printResult(person)
+	},
+}
+
+var personUpdateCmd = &cobra.Command{
+	Use:   "update <person>",
+	Short: "Update a person",
+	Long: `Change a person's name, linked user, device trackers or picture.
+--device-tracker replaces the device trackers; --add-device-tracker and
+--remove-device-tracker change them one by one. --user "" and --picture ""
+clear the linked user and picture.
+
+Examples:
+  hago person update bob --add-device-tracker device_tracker.bob_new_phone
+  hago person update "Dog Walker" --user ""`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+		persons, err := client.Persons(ctx)
+		if err != nil {
+			return err
+		}
+		person, err := findPerson(persons, args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		req := &hago.UpdatePersonRequest{}
+		if flags.Changed("name") {
+			name, _ := flags.GetString("name")
+			req.Name = &name
+		}
+		if flags.Changed("user") {
+			user, _ := flags.GetString("user")
+			if user != "" {
+				if user, err = resolveUserID(ctx, client, user); err != nil {
+					return err
+				}
+			}
+			req.UserID = &user
+		}
+		if flags.Changed("picture") {
+			picture, _ := flags.GetString("picture")
+			req.Picture = &picture
+		}
+
+		trackers := slices.Clone(person.DeviceTrackers)
+		if flags.Changed("device-tracker") {
+			trackers, _ = flags.GetStringSlice("device-tracker")
+		}
+		add, _ := flags.GetStringSlice("add-device-tracker")
+		for _, t := range add {
+			if !slices.Contains(trackers, t) {
+				trackers = append(trackers, t)
+			}
+		}
+		remove, _ := flags.GetStringSlice("remove-device-tracker")
+		trackers = slices.DeleteFunc(trackers, func(t string) bool { return slices.Contains(remove, t) })
+		if trackers == nil {
+			trackers = []string{}
+		}
+		if !slices.Equal(trackers, person.DeviceTrackers) {
+			req.DeviceTrackers = trackers
+		}
+
+		if req.Name == nil && req.UserID == nil && req.DeviceTrackers == nil && req.Picture == nil {
+			return fmt.Errorf("nothing to update")
+		}
+
+		updated, err := client.UpdatePerson(ctx, person.ID, req)
+		if err != nil {
+			return err
+		}
+		return printResult(updated)
+	},
+}
+
+var personDeleteCmd = &cobra.Command{
+	Use:     "delete <person>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a person",
+	Long:    `Delete a person. A linked user is kept.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+		persons, err := client.Persons(ctx)
+		if err != nil {
+			return err
+		}
+		person, err := findPerson(persons, args[0])
+		if err != nil {
+			return err
+		}
+		if err := client.DeletePerson(ctx, person.ID); err != nil {
+			return err
+		}
+		printSuccess("Person '%s' (%s) deleted", person.Name, person.ID)
+		return nil
+	},
+}
+
+// findPerson finds a person by ID or name. Persons from configuration.yaml
+// are refused since they cannot be changed.
+func findPerson(persons []hago.Person, arg string) (*hago.Person, error) {
+	i := slices.IndexFunc(persons, func(p hago.Person) bool { return p.ID == arg })
+	if i < 0 {
+		var found []int
+		for j, p := range persons {
+			if strings.EqualFold(p.Name, arg) {
+				found = append(found, j)
+			}
+		}
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("no person with ID or name %q", arg)
+		case 1:
+			i = found[0]
+		default:
+			return nil, fmt.Errorf("%d persons are named %q, use the person ID", len(found), arg)
+		}
+	}
+	if persons[i].YAML {
+		return nil, fmt.Errorf("person %q is configured in configuration.yaml and cannot be changed", persons[i].ID)
+	}
+	return &persons[i], nil
+}
+
+// resolveUserID returns the ID of a user given by ID, username or name.
+func resolveUserID(ctx context.Context, client *hago.Client, arg string) (string, error) {
+	users, err := client.Users(ctx)
+	if err != nil {
+		return "", err
+	}
+	user, err := findUser(users, arg)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func init() {
+	rootCmd.AddCommand(personCmd)
+	personCmd.AddCommand(personListCmd)
+	personCmd.AddCommand(personCreateCmd)
+	personCmd.AddCommand(personUpdateCmd)
+	personCmd.AddCommand(personDeleteCmd)
+
+	personCreateCmd.Flags().String("user", "", "User to link to the person")
+	personCreateCmd.Flags().StringSlice("device-tracker", nil, "Device trackers of the person")
+	personCreateCmd.Flags().String("picture", "", "Picture URL")
+
+	personUpdateCmd.Flags().String("name", "", "New name")
+	personUpdateCmd.Flags().String("user", "", `User to link to the person ("" to unlink)`)
+	personUpdateCmd.Flags().StringSlice("device-tracker", nil, "Replace the device trackers")
+	personUpdateCmd.Flags().StringSlice("add-device-tracker", nil, "Add device trackers")
+	personUpdateCmd.Flags().StringSlice("remove-device-tracker", nil, "Remove device trackers")
+	personUpdateCmd.Flags().String("picture", "", `Picture URL ("" to clear)`)
+}
@@ -43,6 +43,8 @@ including querying states, calling services, and more.
 Configuration can be provided via:
   - Command-line flags
   - Environment variables (HAGO_URL, HAGO_TOKEN, etc.)
+  - Named contexts in the config file (see hago config get-contexts)
+  - Stored tokens (see hago auth login)
   - Config file (~/.hago.yaml or ./.hago.yaml)
   - .env file in current directory`,
 	PersistentPreRunE: initializeClient,
@@ -61,16 +63,26 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.hago.yaml)")
+	rootCmd.PersistentFlags().String("context", "", "Named context from the config file to use")
 	rootCmd.PersistentFlags().String("url", "", "Home Assistant URL")
 	rootCmd.PersistentFlags().String("token", "", "Long-Lived Access Token")
 	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "Request timeout")
+	rootCmd.PersistentFlags().String("ca-file", "", "PEM file with additional CA certificates to trust")
+	rootCmd.PersistentFlags().Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification (insecure)")
+	rootCmd.PersistentFlags().Int("retries", 0, "Retry transient failures up to this many times (0 = no retries)")
+	rootCmd.PersistentFlags().String("transport", "rest", "Transport for service calls and events (rest, websocket)")
 	rootCmd.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().String("log-format", "text", "Log format (text, json)")
 
 	// Bind flags to viper
+	viper.BindPFlag("context", rootCmd.PersistentFlags().Lookup("context"))
 	viper.BindPFlag("url", rootCmd.PersistentFlags().Lookup("url"))
 	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
 	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("ca_file", rootCmd.PersistentFlags().Lookup("ca-file"))
+	viper.BindPFlag("insecure_skip_tls_verify", rootCmd.PersistentFlags().Lookup("insecure-skip-tls-verify"))
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	viper.BindPFlag("transport", rootCmd.PersistentFlags().Lookup("transport"))
 	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format"))
 
@@ -150,6 +162,11 @@ func initializeClient(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Validate the output format before doing any work
+	if _, err := parseOutputFormat(outputFormat); err != nil {
+		return err
+	}
+
 	// Setup logger
 	var err error
 	logger, err = setupLogger(viper.GetString("log_level"), viper.GetString("log_format"))
@@ -159,35 +176,69 @@ func initializeClient(cmd *cobra.Command, args []string) error {
 	slog.SetDefault(logger)
 
 	// Get configuration
-	url := viper.GetString("url")
-	token := viper.GetString("token")
-	timeout := viper.GetDuration("timeout")
-
-	if url == "" {
-		return fmt.Errorf("home Assistant URL is required (use --url, HAGO_URL, or config file)")
+	conn, err := resolveConnection(cmd)
+	if err != nil {
+		return err
+	}
+	if conn.url == "" {
+		return fmt.Errorf("home Assistant URL is required (use --url, HAGO_URL, a context, or config file)")
 	}
-	if token == "" {
-		return fmt.Errorf("access token is required (use --token, HAGO_TOKEN, or config file)")
+	if conn.token == nil {
+		return fmt.Errorf("access token is required (use --token, HAGO_TOKEN, hago auth login, a context, or config file)")
+	}
+	tokenSource, err := clientTokenSource(cmd.Context(), conn.token)
+	if err != nil {
+		return fmt.Errorf("read token from %s: %w", conn.token, err)
 	}
 
 	// Create client
-	client, err = hago.New(
-		hago.WithBaseURL(url),
-		hago.WithToken(token),
-		hago.WithTimeout(timeout),
-	)
+	client, err = newClient(conn, tokenSource)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
 
 	logger.Debug("client initialized",
-		"url", url,
-		"timeout", timeout,
+		"context", conn.context,
+		"url", conn.url,
+		"token_source", conn.token.String(),
+		"timeout", conn.timeout,
+		"retries", viper.GetInt("retries"),
+		"transport", viper.GetString("transport"),
 	)
 
 	return nil
 }
 
+// newClient creates a client for a resolved connection and token source.
+func newClient(conn *connection, tokenSource hago.TokenSource) (*hago.Client, error) {
+	opts := []hago.Option{
+		hago.WithBaseURL(conn.url),
+		hago.WithTokenSource(tokenSource),
+		hago.WithTimeout(conn.timeout),
+	}
+	if conn.tls != nil {
+		opts = append(opts, hago.WithTLSConfig(conn.tls))
+	}
+	if retries := viper.GetInt("retries"); retries > 0 {
+		policy := hago.DefaultRetryPolicy()
+		policy.MaxAttempts = retries + 1
+		opts = append(opts, hago.WithRetry(policy))
+	}
+	switch transport := viper.GetString("transport"); strings.ToLower(transport) {
+	case "", "rest":
+	case "websocket", "ws":
+		opts = append(opts, hago.WithTransport(hago.TransportWebSocket))
+	default:
+		return nil, fmt.Errorf("invalid transport: %s (use rest or websocket)", transport)
+	}
+
+	c, err := hago.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return c, nil
+}
+
 func setupLogger(level, format string) (*slog.Logger, error) {
 	var logLevel slog.Level
 	switch strings.ToLower(level) {
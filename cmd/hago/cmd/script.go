@@ -8,7 +8,6 @@ import (
 
 	"github.com/rmrfslashbin/hago"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var scriptCmd = &cobra.Command{
@@ -176,21 +175,17 @@ WARNING: This uses an undocumented REST API endpoint that may change without not
 Examples:
   hago script get my_script
   hago script get my_script -o json
-  hago script get my_script --yaml > script.yaml`,
+  hago script get my_script -o yaml > script.yaml`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
+		applyYAMLFlag(cmd)
 		config, err := getClient().ScriptGet(ctx, args[0])
 		if err != nil {
 			return err
 		}
 
-		asYAML, _ := cmd.Flags().GetBool("yaml")
-		if asYAML {
-			return yaml.NewEncoder(os.Stdout).Encode(config)
-		}
-
 		return printResult(config)
 	},
 }
@@ -235,13 +230,9 @@ Examples:
 			return fmt.Errorf("no configuration provided (use --file or pipe to stdin)")
 		}
 
-		// Parse as JSON or YAML
 		var config hago.ScriptConfig
-		if err := json.Unmarshal(data, &config); err != nil {
-			// Try YAML
-			if err := yaml.Unmarshal(data, &config); err != nil {
-				return fmt.Errorf("parse config (tried JSON and YAML): %w", err)
-			}
+		if err := decodeConfig(data, &config); err != nil {
+			return err
 		}
 
 		// Ensure ID matches argument
@@ -300,6 +291,7 @@ func init() {
 
 	// Get flags
 	scriptGetCmd.Flags().Bool("yaml", false, "Output as YAML")
+	scriptGetCmd.Flags().MarkDeprecated("yaml", "use -o yaml")
 
 	// Save flags
 	scriptSaveCmd.Flags().StringP("file", "f", "", "File containing script configuration (JSON or YAML)")
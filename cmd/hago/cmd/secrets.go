@@ -0,0 +1,568 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// tokenConfig holds the ways an access token can be configured. At most one
+// of them may be set:
+//
+//	token:          the token itself, in plain text
+//	token_env:      an environment variable holding the token
+//	token_command:  a command printing the token, e.g. a password manager CLI
+//	token_file:     a file holding the token, readable only by its owner
+//	token_keyfile:  a file holding the token encrypted with a passphrase
+//	token_oauth:    a file holding OAuth credentials from hago auth login --oauth,
+//	                encrypted with a passphrase
+type tokenConfig struct {
+	Token        string `yaml:"token,omitempty" json:"-"`
+	TokenEnv     string `yaml:"token_env,omitempty" json:"token_env,omitempty"`
+	TokenCommand string `yaml:"token_command,omitempty" json:"token_command,omitempty"`
+	TokenFile    string `yaml:"token_file,omitempty" json:"token_file,omitempty"`
+	TokenKeyfile string `yaml:"token_keyfile,omitempty" json:"token_keyfile,omitempty"`
+	TokenOAuth   string `yaml:"token_oauth,omitempty" json:"token_oauth,omitempty"`
+}
+
+// tokenKeys are the config keys of tokenConfig.
+var tokenKeys = []string{"token", "token_env", "token_command", "token_file", "token_keyfile", "token_oauth"}
+
+// topLevelTokenConfig returns the token settings outside any context.
+func topLevelTokenConfig() tokenConfig {
+	return tokenConfig{
+		Token:        viper.GetString("token"),
+		TokenEnv:     viper.GetString("token_env"),
+		TokenCommand: viper.GetString("token_command"),
+		TokenFile:    viper.GetString("token_file"),
+		TokenKeyfile: viper.GetString("token_keyfile"),
+		TokenOAuth:   viper.GetString("token_oauth"),
+	}
+}
+
+// isEmpty reports whether no token source is configured.
+func (t tokenConfig) isEmpty() bool {
+	return t == tokenConfig{}
+}
+
+// source returns the configured secret source, or nil if none is.
+func (t tokenConfig) source() (secretSource, error) {
+	var sources []secretSource
+	if t.Token != "" {
+		sources = append(sources, staticSecret{token: t.Token, origin: "config"})
+	}
+	if t.TokenEnv != "" {
+		sources = append(sources, envSecret(t.TokenEnv))
+	}
+	if t.TokenCommand != "" {
+		sources = append(sources, commandSecret(t.TokenCommand))
+	}
+	if t.TokenFile != "" {
+		sources = append(sources, fileSecret(expandHome(t.TokenFile)))
+	}
+	if t.TokenKeyfile != "" {
+		sources = append(sources, keyfileSecret(expandHome(t.TokenKeyfile)))
+	}
+	if t.TokenOAuth != "" {
+		sources = append(sources, &oauthSecret{path: expandHome(t.TokenOAuth)})
+	}
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0], nil
+	default:
+		return nil, fmt.Errorf("more than one token source configured (use only one of %s)", strings.Join(tokenKeys, ", "))
+	}
+}
+
+// describe describes the token source without revealing the token.
+func (t tokenConfig) describe() string {
+	source, err := t.source()
+	if err != nil {
+		return "invalid"
+	}
+	if source == nil {
+		return ""
+	}
+	return source.String()
+}
+
+// tokenConfig returns the token settings of a context, or the top-level
+// ones if name is empty.
+func (f *configFile) tokenConfig(name string) (tokenConfig, error) {
+	if name != "" {
+		c, err := f.context(name)
+		return c.tokenConfig, err
+	}
+	var t tokenConfig
+	if err := f.root.Decode(&t); err != nil {
+		return tokenConfig{}, fmt.Errorf("parse config %s: %w", f.path, err)
+	}
+	return t, nil
+}
+
+// setTokenConfig replaces the token settings of a context, or the top-level
+// ones if name is empty.
+func (f *configFile) setTokenConfig(name string, t tokenConfig) error {
+	if name != "" {
+		c, err := f.context(name)
+		if err != nil {
+			return err
+		}
+		c.tokenConfig = t
+		return f.setContext(name, c)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(t); err != nil {
+		return fmt.Errorf("encode token settings: %w", err)
+	}
+	for _, key := range tokenKeys {
+		deleteMappingKey(f.root, key)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		setMappingValue(f.root, value.Content[i].Value, value.Content[i+1])
+	}
+	return nil
+}
+
+// secretSource provides the access token.
+type secretSource interface {
+	// Token returns the token.
+	Token(ctx context.Context) (string, error)
+	// String describes the source without revealing the token.
+	String() string
+}
+
+// staticSecret is a token given directly.
+type staticSecret struct {
+	token  string
+	origin string
+}
+
+func (s staticSecret) Token(ctx context.Context) (string, error) { return s.token, nil }
+func (s staticSecret) String() string                            { return s.origin }
+
+// envSecret reads the token from an environment variable.
+type envSecret string
+
+func (s envSecret) Token(ctx context.Context) (string, error) {
+	token := strings.TrimSpace(os.Getenv(string(s)))
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", string(s))
+	}
+	return token, nil
+}
+
+func (s envSecret) String() string { return "env:" + string(s) }
+
+// commandSecret runs a command through the system shell and uses the first
+// line of its output as the token. The command's stderr is passed through
+// so password managers can prompt.
+type commandSecret string
+
+func (s commandSecret) Token(ctx context.Context) (string, error) {
+	name, args := "sh", []string{"-c", string(s)}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", string(s)}
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command: %w", err)
+	}
+	token, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if token = strings.TrimSpace(token); token == "" {
+		return "", fmt.Errorf("token command printed nothing")
+	}
+	return token, nil
+}
+
+func (s commandSecret) String() string { return "command:" + string(s) }
+
+// fileSecret reads the token from a file that only its owner can access.
+type fileSecret string
+
+func (s fileSecret) Token(ctx context.Context) (string, error) {
+	data, err := readPrivateFile(string(s))
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", string(s))
+	}
+	return token, nil
+}
+
+func (s fileSecret) String() string { return "file:" + string(s) }
+
+// keyfileSecret reads a token encrypted with the keyfile passphrase.
+type keyfileSecret string
+
+func (s keyfileSecret) Token(ctx context.Context) (string, error) {
+	return readKeyfile(string(s))
+}
+
+func (s keyfileSecret) String() string { return "keyfile:" + string(s) }
+
+// oauthCredentials is what hago auth login --oauth stores. The refresh
+// token only works with the client ID it was issued to, so that is kept
+// too.
+type oauthCredentials struct {
+	URL      string `json:"url"`
+	ClientID string `json:"client_id"`
+	hago.OAuthToken
+}
+
+// oauthSecret reads OAuth credentials from a keyfile and refreshes the
+// access token when it expires, saving the new one. Unlike the other
+// sources it is handed to the client as its hago.TokenSource, so long
+// running commands keep working past the access token's lifetime.
+type oauthSecret struct {
+	path string
+	// httpClient is used to refresh the token; set by resolveConnection.
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	source *hago.OAuthTokenSource
+}
+
+func (s *oauthSecret) Token(ctx context.Context) (string, error) {
+	source, err := s.tokenSource()
+	if err != nil {
+		return "", err
+	}
+	return source.Token(ctx)
+}
+
+func (s *oauthSecret) String() string { return "oauth:" + s.path }
+
+// tokenSource loads the credentials on first use.
+func (s *oauthSecret) tokenSource() (*hago.OAuthTokenSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.source != nil {
+		return s.source, nil
+	}
+
+	creds, err := loadOAuthCredentials(s.path)
+	if err != nil {
+		return nil, err
+	}
+	config := &hago.OAuthConfig{BaseURL: creds.URL, ClientID: creds.ClientID, HTTPClient: s.httpClient}
+	s.source = config.TokenSource(&creds.OAuthToken, func(token *hago.OAuthToken) {
+		creds.OAuthToken = *token
+		if err := saveOAuthCredentials(s.path, creds); err != nil {
+			slog.Warn("could not save refreshed OAuth token", "path", s.path, "error", err)
+		}
+	})
+	return s.source, nil
+}
+
+// loadOAuthCredentials reads credentials saved by saveOAuthCredentials.
+func loadOAuthCredentials(path string) (*oauthCredentials, error) {
+	data, err := readKeyfile(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds oauthCredentials
+	if err := json.Unmarshal([]byte(data), &creds); err != nil {
+		return nil, fmt.Errorf("parse OAuth credentials %s: %w", path, err)
+	}
+	return &creds, nil
+}
+
+// saveOAuthCredentials encrypts credentials to a keyfile.
+func saveOAuthCredentials(path string, creds *oauthCredentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("encode OAuth credentials: %w", err)
+	}
+	return writeKeyfile(path, string(data))
+}
+
+// clientTokenSource returns the token source to give the client. Most
+// sources are read once; OAuth sources are passed through so the client
+// can refresh them.
+func clientTokenSource(ctx context.Context, s secretSource) (hago.TokenSource, error) {
+	if s == nil {
+		return nil, fmt.Errorf("no token configured")
+	}
+	token, err := s.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("token is empty")
+	}
+	if o, ok := s.(*oauthSecret); ok {
+		return o, nil
+	}
+	return hago.StaticToken(token), nil
+}
+
+// readKeyfile reads and decrypts a file written by writeKeyfile.
+func readKeyfile(path string) (string, error) {
+	data, err := readPrivateFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(data, []byte(legacyKeyfileHeader)) {
+		return "", fmt.Errorf("%s was encrypted with a key stored next to it, which is no longer supported; run hago auth login again", path)
+	}
+	passphrase, err := keyfilePassphrase(path, false)
+	if err != nil {
+		return "", err
+	}
+	secret, err := decryptToken(passphrase, data)
+	if err != nil {
+		forgetPassphrase()
+		return "", fmt.Errorf("decrypt %s: %w", path, err)
+	}
+	return secret, nil
+}
+
+// writeKeyfile encrypts a secret with the keyfile passphrase and writes it
+// to path.
+func writeKeyfile(path, secret string) error {
+	passphrase, err := keyfilePassphrase(path, true)
+	if err != nil {
+		return err
+	}
+	data, err := encryptToken(passphrase, secret)
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(path, data)
+}
+
+// readPrivateFile reads a file, refusing files that other users can access.
+func readPrivateFile(path string) ([]byte, error) {
+	if err := checkPrivate(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// checkPrivate returns an error if path is readable or writable by users
+// other than its owner. Windows has no permission bits, so it is skipped
+// there.
+func checkPrivate(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by other users (mode %04o); run: chmod 600 %s", path, perm, path)
+	}
+	return nil
+}
+
+// secretsDir returns the directory for hago's stored tokens.
+func secretsDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("find config directory: %w", err)
+	}
+	return filepath.Join(dir, "hago"), nil
+}
+
+// keyfilePassphraseEnv names the environment variable holding the keyfile
+// passphrase, for use without a terminal.
+const keyfilePassphraseEnv = "HAGO_KEYFILE_PASSPHRASE"
+
+// passphrase caches the keyfile passphrase so it is asked for at most once
+// per run, e.g. when an OAuth token is read and later refreshed.
+var passphrase struct {
+	sync.Mutex
+	value string
+}
+
+// keyfilePassphrase returns the passphrase protecting keyfiles, from
+// HAGO_KEYFILE_PASSPHRASE or typed on the terminal. A new passphrase
+// (confirm) has to be typed twice.
+func keyfilePassphrase(path string, confirm bool) (string, error) {
+	passphrase.Lock()
+	defer passphrase.Unlock()
+	if passphrase.value != "" {
+		return passphrase.value, nil
+	}
+	if p := os.Getenv(keyfilePassphraseEnv); p != "" {
+		passphrase.value = p
+		return p, nil
+	}
+
+	p, err := promptTerminal(fmt.Sprintf("Passphrase for %s: ", path))
+	if errors.Is(err, errNoTerminal) {
+		return "", fmt.Errorf("%s is encrypted with a passphrase: set %s or run in a terminal", path, keyfilePassphraseEnv)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	if p == "" {
+		return "", fmt.Errorf("empty passphrase")
+	}
+	if confirm {
+		again, err := promptTerminal("Repeat passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("read passphrase: %w", err)
+		}
+		if again != p {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	passphrase.value = p
+	return p, nil
+}
+
+// forgetPassphrase clears a cached passphrase that failed to decrypt.
+func forgetPassphrase() {
+	passphrase.Lock()
+	defer passphrase.Unlock()
+	passphrase.value = ""
+}
+
+const (
+	// keyfileHeader identifies the format of encrypted token files.
+	keyfileHeader = "hago-keyfile-v2\n"
+	// legacyKeyfileHeader marks files encrypted with a key stored in the
+	// config directory.
+	legacyKeyfileHeader = "hago-keyfile-v1\n"
+	// keyfileIterations is the PBKDF2-HMAC-SHA256 work factor recommended
+	// by OWASP.
+	keyfileIterations = 600_000
+	keyfileSaltSize   = 16
+)
+
+// encryptToken encrypts a token with AES-256-GCM under a key derived from
+// the passphrase and a random salt.
+func encryptToken(passphrase, token string) ([]byte, error) {
+	salt := make([]byte, keyfileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	gcm, err := passphraseGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(append(salt, nonce...), nonce, []byte(token), []byte(keyfileHeader))
+	return []byte(keyfileHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// decryptToken decrypts a token encrypted by encryptToken.
+func decryptToken(passphrase string, data []byte) (string, error) {
+	payload, ok := bytes.CutPrefix(data, []byte(keyfileHeader))
+	if !ok {
+		return "", fmt.Errorf("not a hago keyfile")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(payload)))
+	if err != nil {
+		return "", fmt.Errorf("decode keyfile: %w", err)
+	}
+	if len(sealed) < keyfileSaltSize {
+		return "", fmt.Errorf("keyfile is truncated")
+	}
+	salt, sealed := sealed[:keyfileSaltSize], sealed[keyfileSaltSize:]
+	gcm, err := passphraseGCM(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("keyfile is truncated")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	token, err := gcm.Open(nil, nonce, ciphertext, []byte(keyfileHeader))
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase or corrupt keyfile")
+	}
+	return string(token), nil
+}
+
+// passphraseGCM returns an AES-GCM cipher keyed from a passphrase and salt.
+func passphraseGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, keyfileIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	return newGCM(key)
+}
+
+// newGCM returns an AES-GCM cipher for key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// writePrivateFile writes a file readable only by its owner, creating its
+// directory if needed. The data goes to a new 0600 temp file that replaces
+// the target, so it is never written into a file others can read. A
+// symlinked target is followed so the link is kept.
+func writePrivateFile(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	// CreateTemp creates the file with mode 0600
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
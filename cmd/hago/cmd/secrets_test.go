@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// loadTestConfig reads a config file into viper, as initConfig does, and
+// resets viper when the test ends.
+func loadTestConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hago.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+}
+
+func TestResolveConnection_TopLevelTokenEnv(t *testing.T) {
+	loadTestConfig(t, "url: http://ha.local:8123\ntoken_env: MYTOK\n")
+	t.Setenv("MYTOK", "zzz")
+
+	conn, err := resolveConnection(&cobra.Command{})
+	if err != nil {
+		t.Fatalf("resolveConnection() error = %v", err)
+	}
+	if conn.token == nil {
+		t.Fatal("token_env at the top level was ignored")
+	}
+	token, err := conn.token.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token != "zzz" {
+		t.Errorf("token = %q, want zzz", token)
+	}
+}
+
+func TestKeyfile_Passphrase(t *testing.T) {
+	t.Setenv(keyfilePassphraseEnv, "correct horse")
+	t.Cleanup(forgetPassphrase)
+	path := filepath.Join(t.TempDir(), "tokens", "home.enc")
+
+	if err := writeKeyfile(path, "secret-token"); err != nil {
+		t.Fatalf("writeKeyfile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("keyfile contains the token in plain text")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("files next to the keyfile: %v, want only the keyfile", entries)
+	}
+
+	token, err := readKeyfile(path)
+	if err != nil {
+		t.Fatalf("readKeyfile() error = %v", err)
+	}
+	if token != "secret-token" {
+		t.Errorf("token = %q", token)
+	}
+
+	if _, err := decryptToken("wrong", data); err == nil {
+		t.Error("expected error decrypting with the wrong passphrase")
+	}
+}
+
+func TestKeyfile_LegacyFormat(t *testing.T) {
+	t.Setenv(keyfilePassphraseEnv, "correct horse")
+	t.Cleanup(forgetPassphrase)
+	path := filepath.Join(t.TempDir(), "old.enc")
+	if err := os.WriteFile(path, []byte(legacyKeyfileHeader+"AAAA\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readKeyfile(path); err == nil || !strings.Contains(err.Error(), "hago auth login") {
+		t.Errorf("readKeyfile() error = %v, want a hint to log in again", err)
+	}
+}
+
+func TestWritePrivateFile_ReplacesReadableFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no permission bits on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writePrivateFile(link, []byte("secret\n")); err != nil {
+		t.Fatalf("writePrivateFile() error = %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink was replaced (err = %v)", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %04o, want 0600", perm)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "secret\n" {
+		t.Errorf("content = %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
@@ -36,6 +36,12 @@ var serviceCallCmd = &cobra.Command{
 Examples:
   hago service call light turn_on light.living_room
   hago service call light turn_on light.living_room --data '{"brightness": 255}'
+  hago service call light turn_off --area kitchen
+  hago service call light turn_on --entity light.hall --entity light.porch
+  hago service call light turn_off --select 'domain:light label:holiday'
+  hago service call weather get_forecasts weather.home -d '{"type": "daily"}' --response
+  hago service call light turn_on light.hall --track
+  hago service call light turn_on light.hall -d '{"brightness_pct": 80}' --validate
   hago service call homeassistant restart`,
 	Args: cobra.RangeArgs(2, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -48,6 +54,20 @@ Examples:
 		if len(args) > 2 {
 			req.EntityID = args[2]
 		}
+		target := targetFromFlags(cmd)
+		if query, _ := cmd.Flags().GetString("select"); query != "" {
+			ids, err := getClient().SelectEntityIDs(ctx, query)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return fmt.Errorf("no entities match %q", query)
+			}
+			target.Entities(ids...)
+		}
+		if !target.IsEmpty() {
+			req.Target = target
+		}
 
 		dataJSON, _ := cmd.Flags().GetString("data")
 		if dataJSON != "" {
@@ -58,6 +78,28 @@ Examples:
 			req.Data = data
 		}
 
+		if validate, _ := cmd.Flags().GetBool("validate"); validate {
+			if err := getClient().ValidateServiceCall(ctx, domain, service, req); err != nil {
+				return err
+			}
+		}
+
+		if response, _ := cmd.Flags().GetBool("response"); response {
+			result, err := getClient().CallServiceWithResponse(ctx, domain, service, req)
+			if err != nil {
+				return err
+			}
+			return printResult(result)
+		}
+
+		if track, _ := cmd.Flags().GetBool("track"); track {
+			result, err := getClient().CallServiceTracked(ctx, domain, service, req)
+			if err != nil {
+				return err
+			}
+			return printResult(result)
+		}
+
 		states, err := getClient().CallService(ctx, domain, service, req)
 		if err != nil {
 			return err
@@ -72,4 +114,30 @@ func init() {
 	serviceCmd.AddCommand(serviceCallCmd)
 
 	serviceCallCmd.Flags().StringP("data", "d", "", "Service data as JSON")
+	serviceCallCmd.Flags().Bool("validate", false, "Check the call against the service's schema before sending it")
+	serviceCallCmd.Flags().Bool("response", false, "Request and print the service response (return_response)")
+	serviceCallCmd.Flags().Bool("track", false, "Call over WebSocket and print the context of the call")
+	serviceCallCmd.MarkFlagsMutuallyExclusive("response", "track")
+	serviceCallCmd.Flags().StringArray("entity", nil, "Target entity ID (repeatable)")
+	serviceCallCmd.Flags().StringSlice("device", nil, "Target device IDs")
+	serviceCallCmd.Flags().StringSlice("area", nil, "Target area IDs")
+	serviceCallCmd.Flags().StringSlice("floor", nil, "Target floor IDs")
+	serviceCallCmd.Flags().StringSlice("label", nil, "Target label IDs")
+	serviceCallCmd.Flags().String("select", "", "Target the entities matching a selection query (see hago state list --help)")
+}
+
+// targetFromFlags builds a service call target from the --entity, --device,
+// --area, --floor and --label flags.
+func targetFromFlags(cmd *cobra.Command) *hago.Target {
+	entities, _ := cmd.Flags().GetStringArray("entity")
+	devices, _ := cmd.Flags().GetStringSlice("device")
+	areas, _ := cmd.Flags().GetStringSlice("area")
+	floors, _ := cmd.Flags().GetStringSlice("floor")
+	labels, _ := cmd.Flags().GetStringSlice("label")
+	return hago.NewTarget().
+		Entities(entities...).
+		Devices(devices...).
+		Areas(areas...).
+		Floors(floors...).
+		Labels(labels...)
 }
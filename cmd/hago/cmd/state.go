@@ -17,9 +17,36 @@ var stateListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List all entity states",
-	Long:    `List all entity states currently tracked by Home Assistant.`,
+	Long: `List all entity states currently tracked by Home Assistant.
+
+Use --select to list only entities matching a selection query. Terms are
+ANDed; values are globs and may list alternatives separated by commas:
+
+  light.*                    entity ID glob
+  domain:sensor              entity domain
+  state:unavailable          entity state
+  device_class:temperature   device_class attribute
+  area:kitchen               area ID or name (entity or device area)
+  floor:upstairs             floor ID or name
+  label:critical             label ID or name (entity or device labels)
+  device:"Hall Sensor"       device ID or name
+  integration:hue            integration of the entity
+
+Prefix a term with ! to negate it.
+
+Examples:
+  hago state list --select 'light.*'
+  hago state list --select 'domain:sensor device_class:temperature area:kitchen'
+  hago state list --select 'label:critical state:unavailable,unknown'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
+		if query, _ := cmd.Flags().GetString("select"); query != "" {
+			states, err := getClient().Select(ctx, query)
+			if err != nil {
+				return err
+			}
+			return printResult(states)
+		}
 		states, err := getClient().States(ctx)
 		if err != nil {
 			return err
@@ -99,5 +126,6 @@ func init() {
 	stateCmd.AddCommand(stateSetCmd)
 	stateCmd.AddCommand(stateDeleteCmd)
 
+	stateListCmd.Flags().String("select", "", "Only list entities matching a selection query")
 	stateSetCmd.Flags().String("attr", "", "Attributes as JSON")
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errNoTerminal is returned when a secret must be typed but there is no
+// terminal to type it on.
+var errNoTerminal = errors.New("no terminal")
+
+// promptSecret prompts on stderr and reads a line from the terminal f
+// without echoing it.
+func promptSecret(f *os.File, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	restore, err := disableEcho(f)
+	if err != nil {
+		return "", err
+	}
+	line, err := readLine(f)
+	restore()
+	// The newline typed by the user was not echoed either
+	fmt.Fprintln(os.Stderr)
+	return line, err
+}
+
+// promptTerminal opens the controlling terminal and reads a secret from it,
+// for when stdin is taken by piped input.
+func promptTerminal(prompt string) (string, error) {
+	tty, err := os.OpenFile(ttyPath, os.O_RDWR, 0)
+	if err != nil {
+		return "", errNoTerminal
+	}
+	defer tty.Close()
+	return promptSecret(tty, prompt)
+}
+
+// readLine reads a line from r a byte at a time, so no input past the line
+// is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
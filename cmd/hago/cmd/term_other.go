@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// ttyPath is the controlling terminal.
+const ttyPath = "/dev/tty"
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// disableEcho is not supported on this platform, so secrets cannot be
+// typed; they have to be piped in.
+func disableEcho(f *os.File) (func(), error) {
+	return nil, fmt.Errorf("hiding typed input is not supported on %s: pipe it in instead", runtime.GOOS)
+}
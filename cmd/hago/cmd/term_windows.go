@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// ttyPath is the console input.
+const ttyPath = "CONIN$"
+
+// isTerminal reports whether f is a console.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(f.Fd()), &mode) == nil
+}
+
+// disableEcho turns off echo on the console f and returns a function that
+// restores it.
+func disableEcho(f *os.File) (func(), error) {
+	handle := windows.Handle(f.Fd())
+	var old uint32
+	if err := windows.GetConsoleMode(handle, &old); err != nil {
+		return nil, fmt.Errorf("get console mode: %w", err)
+	}
+	quiet := old&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(handle, quiet); err != nil {
+		return nil, fmt.Errorf("disable echo: %w", err)
+	}
+	return func() { windows.SetConsoleMode(handle, old) }, nil
+}
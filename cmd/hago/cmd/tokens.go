@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+)
+
+var authTokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Manage refresh and Long-Lived Access Tokens",
+	Long: `List, create and revoke the refresh tokens of the authenticated user.
+
+Long-Lived Access Tokens are refresh tokens of type long_lived_access_token.
+Rotating the token of a service account:
+
+  NEW=$(hago auth tokens create "backup $(date +%F)" --lifespan-days 90)
+  # ... deploy $NEW ...
+  hago auth tokens revoke "backup 2025-01-01"
+
+Revoking tokens that have not been used in 90 days:
+
+  hago auth tokens revoke --unused-days 90 --dry-run`,
+}
+
+var authTokensListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List refresh tokens",
+	Long: `List the refresh tokens of the authenticated user with when they were
+created and last used.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		tokens, err := getClient().RefreshTokens(ctx)
+		if err != nil {
+			return err
+		}
+		tokenType, _ := cmd.Flags().GetString("type")
+		unusedDays, _ := cmd.Flags().GetInt("unused-days")
+		tokens = slices.DeleteFunc(tokens, func(t hago.RefreshToken) bool {
+			return (tokenType != "" && t.Type != tokenType) || !unusedFor(t, unusedDays)
+		})
+		slices.SortFunc(tokens, func(a, b hago.RefreshToken) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		return printResult(tokens)
+	},
+}
+
+var authTokensCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a Long-Lived Access Token",
+	Long: `Create a Long-Lived Access Token for the authenticated user and print it.
+The token cannot be shown again. The name must be unique among the user's
+long-lived tokens.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		days, _ := cmd.Flags().GetInt("lifespan-days")
+		if days < 0 {
+			return fmt.Errorf("--lifespan-days must not be negative")
+		}
+		token, err := getClient().CreateLongLivedToken(ctx, args[0], days)
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+		return nil
+	},
+}
+
+var authTokensRevokeCmd = &cobra.Command{
+	Use:   "revoke [id-or-name...]",
+	Short: "Revoke refresh tokens",
+	Long: `Revoke refresh tokens by ID, or Long-Lived Access Tokens by name.
+
+With --unused-days, revoke every Long-Lived Access Token not used in that
+many days. The token hago is using is never revoked.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		unusedDays, _ := cmd.Flags().GetInt("unused-days")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if len(args) == 0 && unusedDays <= 0 {
+			return fmt.Errorf("give token IDs or names, or --unused-days")
+		}
+
+		tokens, err := getClient().RefreshTokens(ctx)
+		if err != nil {
+			return err
+		}
+
+		var revoke []hago.RefreshToken
+		for _, arg := range args {
+			i := slices.IndexFunc(tokens, func(t hago.RefreshToken) bool {
+				return t.ID == arg || (t.Type == hago.RefreshTokenTypeLongLived && t.Name() == arg)
+			})
+			if i < 0 {
+				return fmt.Errorf("no refresh token with ID or name %q", arg)
+			}
+			if tokens[i].IsCurrent {
+				return fmt.Errorf("refusing to revoke %q: it is the token hago is using", arg)
+			}
+			revoke = append(revoke, tokens[i])
+		}
+		if unusedDays > 0 {
+			for _, t := range tokens {
+				if t.Type == hago.RefreshTokenTypeLongLived && !t.IsCurrent && unusedFor(t, unusedDays) &&
+					!slices.ContainsFunc(revoke, func(r hago.RefreshToken) bool { return r.ID == t.ID }) {
+					revoke = append(revoke, t)
+				}
+			}
+		}
+
+		for _, t := range revoke {
+			if dryRun {
+				printSuccess("Would revoke %s (%s, %s)", t.ID, t.Name(), lastUsedLabel(t))
+				continue
+			}
+			if err := getClient().DeleteRefreshToken(ctx, t.ID); err != nil {
+				return err
+			}
+			printSuccess("Revoked %s (%s)", t.ID, t.Name())
+		}
+		if len(revoke) == 0 {
+			printSuccess("No tokens to revoke")
+		}
+		return nil
+	},
+}
+
+// lastUsedLabel describes when a token was last used.
+func lastUsedLabel(t hago.RefreshToken) string {
+	if t.LastUsedAt == nil {
+		return "never used, created " + formatTime(t.CreatedAt)
+	}
+	return "last used " + formatTime(*t.LastUsedAt)
+}
+
+// unusedFor reports whether a token has not been used in the given number
+// of days. Zero days matches every token.
+func unusedFor(t hago.RefreshToken, days int) bool {
+	if days <= 0 {
+		return true
+	}
+	return time.Since(t.LastUsed()) > time.Duration(days)*24*time.Hour
+}
+
+func init() {
+	authCmd.AddCommand(authTokensCmd)
+	authTokensCmd.AddCommand(authTokensListCmd)
+	authTokensCmd.AddCommand(authTokensCreateCmd)
+	authTokensCmd.AddCommand(authTokensRevokeCmd)
+
+	authTokensListCmd.Flags().String("type", "", "Only list tokens of this type (normal, long_lived_access_token, system)")
+	authTokensListCmd.Flags().Int("unused-days", 0, "Only list tokens not used in this many days")
+	authTokensCreateCmd.Flags().Int("lifespan-days", 0, "Days until the token expires (default: Home Assistant's 10 years)")
+	authTokensRevokeCmd.Flags().Int("unused-days", 0, "Revoke Long-Lived Access Tokens not used in this many days")
+	authTokensRevokeCmd.Flags().Bool("dry-run", false, "Show what would be revoked without revoking")
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+)
+
+// userGroups maps the short group names accepted by --group to group IDs.
+var userGroups = map[string]string{
+	"admin":     hago.GroupAdmin,
+	"user":      hago.GroupUsers,
+	"read-only": hago.GroupReadOnly,
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage Home Assistant users",
+	Long: `List, create, update and delete Home Assistant users. Requires an admin
+token. Users are named by ID, username or display name.
+
+Onboarding a household member with a login and a person:
+
+  pass show ha/bob | hago user create Bob --username bob --person \
+      --device-tracker device_tracker.bob_phone
+
+Offboarding a contractor, keeping the account for the record:
+
+  hago user update contractor --active=false
+
+or removing it with its person:
+
+  hago user delete contractor --with-person`,
+}
+
+var userListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List users",
+	Long:    `List users. System-generated users such as the Supervisor are hidden unless --all is given.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		users, err := getClient().Users(ctx)
+		if err != nil {
+			return err
+		}
+		if all, _ := cmd.Flags().GetBool("all"); !all {
+			users = slices.DeleteFunc(users, func(u hago.User) bool { return u.SystemGenerated })
+		}
+		return printResult(users)
+	},
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a user",
+	Long: `Create a user with the given display name.
+
+With --username, the user can log in with that username and a password read
+from stdin (prompting if stdin is a terminal). Without it, the user cannot log
+in until credentials are added in the Home Assistant UI.
+
+With --person, a person linked to the user is created as well, so the user
+shows up on the map and in presence automations.
+
+Examples:
+  hago user create "Guest Tablet" --username tablet --group read-only --local-only < pw.txt
+  hago user create Bob --username bob --person --device-tracker device_tracker.bob_phone`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+
+		group, _ := cmd.Flags().GetString("group")
+		groupID, err := userGroupID(group)
+		if err != nil {
+			return err
+		}
+		username, _ := cmd.Flags().GetString("username")
+		var password string
+		if username != "" {
+			passwords, err := readPasswords(os.Stdin, "Password: ")
+			if err != nil {
+				return err
+			}
+			if password = passwords[0]; password == "" {
+				return fmt.Errorf("empty password")
+			}
+			// Check up front so a taken username doesn't leave a user behind
+			users, err := client.Users(ctx)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(users, func(u hago.User) bool { return u.Username != nil && *u.Username == username }) {
+				return fmt.Errorf("username %q is taken", username)
+			}
+		}
+
+		req := &hago.CreateUserRequest{Name: args[0], GroupIDs: []string{groupID}}
+		req.LocalOnly, _ = cmd.Flags().GetBool("local-only")
+		user, err := client.CreateUser(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		if username != "" {
+			if err := client.CreateUserCredentials(ctx, user.ID, username, password); err != nil {
+				return fmt.Errorf("user %s created without a login: %w", user.ID, err)
+			}
+			user.Username = &username
+			user.Credentials = append(user.Credentials, hago.CredentialInfo{Type: "homeassistant"})
+		}
+
+		if withPerson, _ := cmd.Flags().GetBool("person"); withPerson {
+			trackers, _ := cmd.Flags().GetStringSlice("device-tracker")
+			if _, err := client.CreatePerson(ctx, &hago.CreatePersonRequest{
+				Name:           user.Name,
+				UserID:         user.ID,
+				DeviceTrackers: trackers,
+			}); err != nil {
+				return fmt.Errorf("user %s created without a person: %w", user.ID, err)
+			}
+		}
+
+		return printResult(user)
+	},
+}
+
+var userUpdateCmd = &cobra.Command{
+	Use:   "update <user>",
+	Short: "Update a user",
+	Long: `Change a user's name, group, or whether it is active or local only.
+An inactive user cannot log in, but keeps its history and settings.
+
+Examples:
+  hago user update bob --group admin
+  hago user update contractor --active=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+		users, err := client.Users(ctx)
+		if err != nil {
+			return err
+		}
+		user, err := findUser(users, args[0])
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		req := &hago.UpdateUserRequest{}
+		if flags.Changed("name") {
+			name, _ := flags.GetString("name")
+			req.Name = &name
+		}
+		if flags.Changed("group") {
+			group, _ := flags.GetString("group")
+			groupID, err := userGroupID(group)
+			if err != nil {
+				return err
+			}
+			req.GroupIDs = []string{groupID}
+		}
+		if flags.Changed("active") {
+			active, _ := flags.GetBool("active")
+			req.IsActive = &active
+		}
+		if flags.Changed("local-only") {
+			localOnly, _ := flags.GetBool("local-only")
+			req.LocalOnly = &localOnly
+		}
+		if req.Name == nil && req.GroupIDs == nil && req.IsActive == nil && req.LocalOnly == nil {
+			return fmt.Errorf("nothing to update: give --name, --group, --active or --local-only")
+		}
+
+		updated, err := client.UpdateUser(ctx, user.ID, req)
+		if err != nil {
+			return err
+		}
+		return printResult(updated)
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:     "delete <user>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a user",
+	Long: `Delete a user with its login and tokens. The owner and the user hago is
+authenticated as cannot be deleted. A person linked to the user is kept but
+unlinked, unless --with-person is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := getClient()
+		users, err := client.Users(ctx)
+		if err != nil {
+			return err
+		}
+		user, err := findUser(users, args[0])
+		if err != nil {
+			return err
+		}
+
+		var linked []hago.Person
+		if withPerson, _ := cmd.Flags().GetBool("with-person"); withPerson {
+			persons, err := client.Persons(ctx)
+			if err != nil {
+				return err
+			}
+			for _, p := range persons {
+				if p.UserID != nil && *p.UserID == user.ID && !p.YAML {
+					linked = append(linked, p)
+				}
+			}
+		}
+
+		if err := client.DeleteUser(ctx, user.ID); err != nil {
+			return err
+		}
+		printSuccess("User '%s' (%s) deleted", user.Name, user.ID)
+		for _, p := range linked {
+			if err := client.DeletePerson(ctx, p.ID); err != nil {
+				return err
+			}
+			printSuccess("Person '%s' (%s) deleted", p.Name, p.ID)
+		}
+		return nil
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change your password",
+	Long: `Change the password of the user hago is authenticated as. The current and
+new password are read from stdin, one per line, prompting if stdin is a
+terminal.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		passwords, err := readPasswords(os.Stdin, "Current password: ", "New password: ")
+		if err != nil {
+			return err
+		}
+		if passwords[1] == "" {
+			return fmt.Errorf("empty password")
+		}
+		if err := getClient().ChangePassword(ctx, passwords[0], passwords[1]); err != nil {
+			return err
+		}
+		printSuccess("Password changed")
+		return nil
+	},
+}
+
+// findUser finds a user by ID, username or display name.
+func findUser(users []hago.User, arg string) (*hago.User, error) {
+	for _, match := range []func(u hago.User) bool{
+		func(u hago.User) bool { return u.ID == arg },
+		func(u hago.User) bool { return u.Username != nil && *u.Username == arg },
+		func(u hago.User) bool { return strings.EqualFold(u.Name, arg) },
+	} {
+		var found []hago.User
+		for _, u := range users {
+			if match(u) {
+				found = append(found, u)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return &found[0], nil
+		default:
+			return nil, fmt.Errorf("%d users are named %q, use the user ID", len(found), arg)
+		}
+	}
+	return nil, fmt.Errorf("no user with ID, username or name %q", arg)
+}
+
+// userGroupID returns the group ID for a --group value.
+func userGroupID(group string) (string, error) {
+	if id, ok := userGroups[group]; ok {
+		return id, nil
+	}
+	if slices.Contains([]string{hago.GroupAdmin, hago.GroupUsers, hago.GroupReadOnly}, group) {
+		return group, nil
+	}
+	return "", fmt.Errorf("unknown group %q: use admin, user or read-only", group)
+}
+
+// readPasswords reads one password per prompt from r. On a terminal each
+// is prompted for without echo; otherwise they are read one per line.
+func readPasswords(r *os.File, prompts ...string) ([]string, error) {
+	lines := make([]string, len(prompts))
+	if isTerminal(r) {
+		for i, prompt := range prompts {
+			line, err := promptSecret(r, prompt)
+			if err != nil {
+				return nil, fmt.Errorf("read password: %w", err)
+			}
+			lines[i] = line
+		}
+		return lines, nil
+	}
+	br := bufio.NewReader(r)
+	for i := range prompts {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, fmt.Errorf("read password: expected %d lines on stdin", len(prompts))
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("read password: %w", err)
+		}
+		lines[i] = strings.TrimRight(line, "\r\n")
+	}
+	return lines, nil
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userCreateCmd)
+	userCmd.AddCommand(userUpdateCmd)
+	userCmd.AddCommand(userDeleteCmd)
+	userCmd.AddCommand(userPasswdCmd)
+
+	userListCmd.Flags().Bool("all", false, "Include system-generated users")
+
+	userCreateCmd.Flags().String("username", "", "Login username; the password is read from stdin")
+	userCreateCmd.Flags().String("group", "user", "Group: admin, user or read-only")
+	userCreateCmd.Flags().Bool("local-only", false, "Only allow logins from the local network")
+	userCreateCmd.Flags().Bool("person", false, "Also create a person linked to the user")
+	userCreateCmd.Flags().StringSlice("device-tracker", nil, "Device trackers of the person (with --person)")
+
+	userUpdateCmd.Flags().String("name", "", "New display name")
+	userUpdateCmd.Flags().String("group", "", "Group: admin, user or read-only")
+	userUpdateCmd.Flags().Bool("active", true, "Whether the user can log in")
+	userUpdateCmd.Flags().Bool("local-only", false, "Only allow logins from the local network")
+
+	userDeleteCmd.Flags().Bool("with-person", false, "Also delete the person linked to the user")
+}
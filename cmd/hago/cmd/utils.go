@@ -3,6 +3,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // parseJSON parses a JSON string into a map.
@@ -13,3 +16,32 @@ func parseJSON(s string) (map[string]any, error) {
 	}
 	return result, nil
 }
+
+// decodeConfig decodes a JSON or YAML document into v. YAML documents are
+// converted to JSON first, so they use the same keys as the JSON and YAML
+// output of the get commands.
+func decodeConfig(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err == nil {
+		return nil
+	}
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse config (tried JSON and YAML): %w", err)
+	}
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("convert YAML config: %w", err)
+	}
+	if err := json.Unmarshal(converted, v); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	return nil
+}
+
+// applyYAMLFlag honors the deprecated --yaml flag of the get commands by
+// switching the output format to YAML.
+func applyYAMLFlag(cmd *cobra.Command) {
+	if asYAML, _ := cmd.Flags().GetBool("yaml"); asYAML {
+		outputFormat = "yaml"
+	}
+}
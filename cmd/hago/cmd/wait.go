@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait [entity_id]",
+	Short: "Wait until an entity condition holds",
+	Long: `Block until an entity reaches the given condition, then print its state.
+
+All given conditions must hold at the same time. State changes are followed
+over the WebSocket API; if that is unavailable (or --poll is set) the entity
+is polled instead. Exits non-zero if --wait-timeout expires first.
+
+A template condition is rendered with Home Assistant's template engine and
+holds when it renders to true, on, yes or 1. Without an entity, the template
+is polled.
+
+Examples:
+  hago wait binary_sensor.garage_door --state off --wait-timeout 2m
+  hago wait sensor.temperature --above 20 --below 25
+  hago wait light.kitchen --state on --attr color_mode=xy
+  hago wait vacuum.robot --not-state cleaning
+  hago wait --template "{{ is_state('sun.sun', 'below_horizon') }}" --wait-timeout 1h`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cond, err := newWaitCondition(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		var timeout time.Duration
+		if s, _ := cmd.Flags().GetString("wait-timeout"); s != "" && s != "0" {
+			if timeout, err = parseDuration(s); err != nil {
+				return fmt.Errorf("invalid --wait-timeout: %w", err)
+			}
+		}
+		intervalStr, _ := cmd.Flags().GetString("interval")
+		interval, err := parseDuration(intervalStr)
+		if err != nil {
+			return fmt.Errorf("invalid --interval: %w", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("invalid --interval: must be positive")
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		poll, _ := cmd.Flags().GetBool("poll")
+
+		state, err := waitFor(ctx, cond, poll || cond.entityID == "", interval)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("timed out after %s waiting for %s", timeout, cond)
+			}
+			return err
+		}
+
+		if state != nil {
+			return printResult(state)
+		}
+		printSuccess("Condition met")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+
+	waitCmd.Flags().StringSlice("state", nil, "Wait until the state is one of these values")
+	waitCmd.Flags().StringSlice("not-state", nil, "Wait until the state is none of these values")
+	waitCmd.Flags().Float64("above", 0, "Wait until the numeric state is above this value")
+	waitCmd.Flags().Float64("below", 0, "Wait until the numeric state is below this value")
+	waitCmd.Flags().StringArray("attr", nil, "Wait until attribute key=value holds (repeatable)")
+	waitCmd.Flags().String("template", "", "Wait until this template renders true")
+	waitCmd.Flags().String("wait-timeout", "", "Give up and exit non-zero after this long (e.g., 30s, 2m, 1d; default wait forever)")
+	waitCmd.Flags().Bool("poll", false, "Poll instead of following WebSocket state changes")
+	waitCmd.Flags().String("interval", "2s", "Polling interval (e.g., 500ms, 10s)")
+}
+
+// waitCondition is the set of conditions an entity must satisfy.
+type waitCondition struct {
+	entityID string
+	states   []string
+	notState []string
+	above    *float64
+	below    *float64
+	attrs    map[string]string
+	template string
+}
+
+// newWaitCondition builds a condition from command arguments and flags.
+func newWaitCondition(cmd *cobra.Command, args []string) (*waitCondition, error) {
+	cond := &waitCondition{attrs: make(map[string]string)}
+	if len(args) > 0 {
+		cond.entityID = args[0]
+	}
+
+	cond.states, _ = cmd.Flags().GetStringSlice("state")
+	cond.notState, _ = cmd.Flags().GetStringSlice("not-state")
+	cond.template, _ = cmd.Flags().GetString("template")
+
+	if cmd.Flags().Changed("above") {
+		v, _ := cmd.Flags().GetFloat64("above")
+		cond.above = &v
+	}
+	if cmd.Flags().Changed("below") {
+		v, _ := cmd.Flags().GetFloat64("below")
+		cond.below = &v
+	}
+
+	attrs, _ := cmd.Flags().GetStringArray("attr")
+	for _, a := range attrs {
+		key, value, ok := strings.Cut(a, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --attr %q: expected key=value", a)
+		}
+		cond.attrs[key] = value
+	}
+
+	if cond.entityID == "" && cond.template == "" {
+		return nil, fmt.Errorf("an entity ID or --template is required")
+	}
+	if cond.entityID == "" && (len(cond.states) > 0 || len(cond.notState) > 0 ||
+		cond.above != nil || cond.below != nil || len(cond.attrs) > 0) {
+		return nil, fmt.Errorf("state, numeric and attribute conditions require an entity ID")
+	}
+	if cond.entityID != "" && len(cond.states) == 0 && len(cond.notState) == 0 &&
+		cond.above == nil && cond.below == nil && len(cond.attrs) == 0 && cond.template == "" {
+		return nil, fmt.Errorf("at least one condition is required (--state, --not-state, --above, --below, --attr or --template)")
+	}
+
+	return cond, nil
+}
+
+// String describes the condition for error messages.
+func (w *waitCondition) String() string {
+	var parts []string
+	if len(w.states) > 0 {
+		parts = append(parts, "state in "+strings.Join(w.states, "|"))
+	}
+	if len(w.notState) > 0 {
+		parts = append(parts, "state not in "+strings.Join(w.notState, "|"))
+	}
+	if w.above != nil {
+		parts = append(parts, fmt.Sprintf("above %g", *w.above))
+	}
+	if w.below != nil {
+		parts = append(parts, fmt.Sprintf("below %g", *w.below))
+	}
+	for k, v := range w.attrs {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
+	}
+	if w.template != "" {
+		parts = append(parts, "template")
+	}
+
+	desc := strings.Join(parts, ", ")
+	if w.entityID != "" {
+		return fmt.Sprintf("%s (%s)", w.entityID, desc)
+	}
+	return desc
+}
+
+// matchState checks the state, numeric and attribute conditions.
+func (w *waitCondition) matchState(state *hago.State) bool {
+	if state == nil {
+		return false
+	}
+	if len(w.states) > 0 && !slices.Contains(w.states, state.State) {
+		return false
+	}
+	if slices.Contains(w.notState, state.State) {
+		return false
+	}
+	if w.above != nil || w.below != nil {
+		v, err := strconv.ParseFloat(state.State, 64)
+		if err != nil {
+			return false
+		}
+		if w.above != nil && v <= *w.above {
+			return false
+		}
+		if w.below != nil && v >= *w.below {
+			return false
+		}
+	}
+	for key, want := range w.attrs {
+		got, ok := state.Attributes[key]
+		if !ok || fmt.Sprint(got) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// match evaluates the whole condition, rendering the template if one is set.
+func (w *waitCondition) match(ctx context.Context, state *hago.State) (bool, error) {
+	if w.entityID != "" && !w.matchState(state) {
+		return false, nil
+	}
+	if w.template == "" {
+		return true, nil
+	}
+
+	result, err := getClient().RenderTemplate(ctx, w.template)
+	if err != nil {
+		return false, fmt.Errorf("render template: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(result)) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// waitFor blocks until the condition holds and returns the entity's state.
+// It follows state_changed events unless poll is set or the WebSocket
+// subscription fails, in which case it polls every interval.
+func waitFor(ctx context.Context, cond *waitCondition, poll bool, interval time.Duration) (*hago.State, error) {
+	c := getClient()
+
+	// Subscribe before checking the current state so no change is missed
+	var events <-chan hago.EventMessage
+	if !poll {
+		var err error
+		events, err = c.SubscribeEvents(ctx, "state_changed")
+		if err != nil {
+			getLogger().Debug("websocket unavailable, falling back to polling", "error", err)
+			poll = true
+		}
+	}
+
+	state, err := currentState(ctx, cond.entityID)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := cond.match(ctx, state); err != nil || ok {
+		return state, err
+	}
+
+	if poll {
+		return pollFor(ctx, cond, interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				getLogger().Debug("websocket subscription ended, falling back to polling")
+				return pollFor(ctx, cond, interval)
+			}
+
+			data, err := event.StateChanged()
+			if err != nil || data.EntityID != cond.entityID {
+				continue
+			}
+			if ok, err := cond.match(ctx, data.NewState); err != nil || ok {
+				return data.NewState, err
+			}
+		}
+	}
+}
+
+// pollFor checks the condition every interval until it holds.
+func pollFor(ctx context.Context, cond *waitCondition, interval time.Duration) (*hago.State, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		state, err := currentState(ctx, cond.entityID)
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := cond.match(ctx, state); err != nil || ok {
+			return state, err
+		}
+	}
+}
+
+// currentState fetches an entity's state. A missing entity is not an error
+// since it may appear later; it returns nil instead.
+func currentState(ctx context.Context, entityID string) (*hago.State, error) {
+	if entityID == "" {
+		return nil, nil
+	}
+	state, err := getClient().State(ctx, entityID)
+	if errors.Is(err, hago.ErrNotFound) {
+		return nil, nil
+	}
+	return state, err
+}
@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream live state changes and events",
+	Long: `Follow state changes or events as they happen using the WebSocket API.
+
+Output is one JSON object per line (use --compact for a human-readable line).
+-o csv and tsv print one row per change under a single header; -o table is
+not supported since its columns cannot be aligned while streaming.
+Press Ctrl-C to stop, or use --count / --until to exit automatically.`,
+}
+
+var watchStatesCmd = &cobra.Command{
+	Use:   "states [entity_glob...]",
+	Short: "Stream entity state changes",
+	Long: `Stream state changes for entities matching the given globs and filters.
+
+Examples:
+  hago watch states
+  hago watch states 'light.*' 'switch.kitchen_*'
+  hago watch states --domain sensor --attr device_class=temperature
+  hago watch states --area kitchen --compact
+  hago watch states binary_sensor.front_door --count 1 --until 10m`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if compact, _ := cmd.Flags().GetBool("compact"); !compact {
+			if err := checkStreamOutput(); err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel, err := watchContext(cmd)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		filter, err := newStateFilter(ctx, cmd, args)
+		if err != nil {
+			return err
+		}
+
+		events, err := getClient().SubscribeEvents(ctx, "state_changed")
+		if err != nil {
+			return err
+		}
+
+		compact, _ := cmd.Flags().GetBool("compact")
+		count, _ := cmd.Flags().GetInt("count")
+
+		return consumeWatch(ctx, events, count, func(event hago.EventMessage) (bool, error) {
+			data, err := event.StateChanged()
+			if err != nil {
+				return false, nil
+			}
+			if !filter.match(data) {
+				return false, nil
+			}
+			if compact {
+				fmt.Println(formatStateChange(event.TimeFired, data))
+				return true, nil
+			}
+			return true, printResult(data)
+		})
+	},
+}
+
+var watchEventsCmd = &cobra.Command{
+	Use:   "events [event_type]",
+	Short: "Stream events from the event bus",
+	Long: `Stream events from the Home Assistant event bus.
+
+If no event type is given, all events are streamed.
+
+Examples:
+  hago watch events
+  hago watch events call_service
+  hago watch events zha_event --compact --count 5`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if compact, _ := cmd.Flags().GetBool("compact"); !compact {
+			if err := checkStreamOutput(); err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel, err := watchContext(cmd)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		var eventType string
+		if len(args) > 0 {
+			eventType = args[0]
+		}
+
+		events, err := getClient().SubscribeEvents(ctx, eventType)
+		if err != nil {
+			return err
+		}
+
+		compact, _ := cmd.Flags().GetBool("compact")
+		count, _ := cmd.Flags().GetInt("count")
+
+		return consumeWatch(ctx, events, count, func(event hago.EventMessage) (bool, error) {
+			if compact {
+				data, _ := json.Marshal(event.Data)
+				fmt.Printf("%s %s %s\n", event.TimeFired.Local().Format(time.DateTime), event.EventType, data)
+				return true, nil
+			}
+			return true, printResult(event)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.AddCommand(watchStatesCmd)
+	watchCmd.AddCommand(watchEventsCmd)
+
+	for _, c := range []*cobra.Command{watchStatesCmd, watchEventsCmd} {
+		c.Flags().Bool("compact", false, "Print one human-readable line per change instead of JSON")
+		c.Flags().Int("count", 0, "Exit after this many matching changes (0 = unlimited)")
+		c.Flags().String("until", "", "Exit after this duration (e.g., 30s, 10m, 1d)")
+	}
+
+	watchStatesCmd.Flags().StringSlice("domain", nil, "Only include entities in these domains")
+	watchStatesCmd.Flags().StringSlice("area", nil, "Only include entities in these areas (ID, name or glob)")
+	watchStatesCmd.Flags().StringArray("attr", nil, "Only include states with attribute key=value (or key to require presence)")
+}
+
+// watchContext returns a context that ends on Ctrl-C, SIGTERM, or --until.
+func watchContext(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	var until time.Duration
+	if untilStr, _ := cmd.Flags().GetString("until"); untilStr != "" {
+		var err error
+		until, err = parseDuration(untilStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	if until == 0 {
+		return ctx, stop, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, until)
+	return ctx, func() {
+		cancel()
+		stop()
+	}, nil
+}
+
+// consumeWatch reads events until ctx ends or count matching events have been
+// handled. handle reports whether an event matched.
+func consumeWatch(ctx context.Context, events <-chan hago.EventMessage, count int, handle func(hago.EventMessage) (bool, error)) error {
+	matched := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("event subscription ended unexpectedly")
+			}
+
+			ok, err := handle(event)
+			if err != nil {
+				return err
+			}
+			if ok {
+				matched++
+				if count > 0 && matched >= count {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// stateFilter selects state changes by entity glob, domain, area and attribute.
+type stateFilter struct {
+	globs   []string
+	domains map[string]bool
+	areas   map[string]bool   // entity IDs in the selected areas; nil means no area filter
+	attrs   map[string]string // attribute key to value; empty value means presence only
+}
+
+// newStateFilter builds a filter from command arguments and flags.
+// Area filters are resolved with a selection query.
+func newStateFilter(ctx context.Context, cmd *cobra.Command, globs []string) (*stateFilter, error) {
+	for _, g := range globs {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid entity glob %q: %w", g, err)
+		}
+	}
+
+	f := &stateFilter{
+		globs:   globs,
+		domains: make(map[string]bool),
+		attrs:   make(map[string]string),
+	}
+
+	domains, _ := cmd.Flags().GetStringSlice("domain")
+	for _, d := range domains {
+		f.domains[d] = true
+	}
+
+	attrs, _ := cmd.Flags().GetStringArray("attr")
+	for _, a := range attrs {
+		key, value, _ := strings.Cut(a, "=")
+		f.attrs[key] = value
+	}
+
+	areas, _ := cmd.Flags().GetStringSlice("area")
+	if len(areas) > 0 {
+		entities, err := entitiesInAreas(ctx, areas)
+		if err != nil {
+			return nil, err
+		}
+		f.areas = entities
+	}
+
+	return f, nil
+}
+
+// match reports whether a state change passes the filter.
+func (f *stateFilter) match(data *hago.StateChangedData) bool {
+	entityID := data.EntityID
+
+	if len(f.globs) > 0 {
+		matched := false
+		for _, g := range f.globs {
+			if ok, _ := path.Match(g, entityID); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.domains) > 0 {
+		domain, _, _ := strings.Cut(entityID, ".")
+		if !f.domains[domain] {
+			return false
+		}
+	}
+
+	if f.areas != nil && !f.areas[entityID] {
+		return false
+	}
+
+	if len(f.attrs) > 0 {
+		state := data.NewState
+		if state == nil {
+			state = data.OldState
+		}
+		if state == nil {
+			return false
+		}
+		for key, want := range f.attrs {
+			got, ok := state.Attributes[key]
+			if !ok {
+				return false
+			}
+			if want != "" && fmt.Sprint(got) != want {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// entitiesInAreas returns the set of entity IDs in any of the given areas,
+// using the area: term of a selection query (see hago.SelectQuery).
+func entitiesInAreas(ctx context.Context, areas []string) (map[string]bool, error) {
+	query := fmt.Sprintf(`%s:"%s"`, hago.SelectArea, strings.Join(areas, ","))
+	ids, err := getClient().SelectEntityIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no entities in areas matching %s", strings.Join(areas, ", "))
+	}
+	result := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// formatStateChange renders a state change as a single human-readable line.
+func formatStateChange(when time.Time, data *hago.StateChangedData) string {
+	from, to := "<none>", "<removed>"
+	if data.OldState != nil {
+		from = data.OldState.State
+	}
+	if data.NewState != nil {
+		to = data.NewState.State
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", when.Local().Format(time.DateTime), data.EntityID, from, to)
+}
@@ -9,6 +9,10 @@
 // Generate one from your Home Assistant profile page at:
 // http://your-ha-instance:8123/profile -> Long-Lived Access Tokens
 //
+// Alternatively, OAuthConfig runs the OAuth authorization code flow and
+// WithTokenSource uses the resulting short-lived access token, refreshing
+// it automatically before it expires.
+//
 // # Basic Usage
 //
 //	client, err := hago.New(
@@ -0,0 +1,504 @@
+package hago
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special entity states shared by every domain.
+const (
+	StateUnavailable = "unavailable"
+	StateUnknown     = "unknown"
+	StateOn          = "on"
+	StateOff         = "off"
+)
+
+// Domain returns the domain of the entity, e.g. "light" for light.kitchen.
+func (s State) Domain() string {
+	domain, _, _ := strings.Cut(s.EntityID, ".")
+	return domain
+}
+
+// IsAvailable reports whether the entity has a real state, i.e. is neither
+// unavailable nor unknown.
+func (s State) IsAvailable() bool {
+	return s.State != StateUnavailable && s.State != StateUnknown
+}
+
+// FriendlyName returns the friendly_name attribute, or the entity ID if it
+// is not set.
+func (s State) FriendlyName() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// AttributeError describes an attribute a typed view could not decode
+// because its value had an unexpected type. The attribute's field is left
+// at its zero value.
+type AttributeError struct {
+	Attribute string
+	Err       error
+}
+
+// Error implements the error interface.
+func (e AttributeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Attribute, e.Err)
+}
+
+// Unwrap returns the decoding error.
+func (e AttributeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeAttributes checks the entity domain and decodes the state's
+// attributes into v. Integrations are not always consistent about attribute
+// types, so an attribute that fails to decode is skipped and returned as an
+// AttributeError rather than failing the whole view.
+func (s State) decodeAttributes(domain string, v any) ([]AttributeError, error) {
+	if s.Domain() != domain {
+		return nil, fmt.Errorf("%s is not a %s entity", s.EntityID, domain)
+	}
+	raw, err := json.Marshal(s.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s attributes: %w", s.EntityID, err)
+	}
+	// Decode into a scratch value first: a failed decode may have set
+	// part of the field, such as allocating a pointer
+	scratch := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	if err := json.Unmarshal(raw, scratch); err == nil {
+		return nil, json.Unmarshal(raw, v)
+	}
+
+	var invalid []AttributeError
+	for _, name := range slices.Sorted(maps.Keys(s.Attributes)) {
+		raw, err := json.Marshal(map[string]any{name: s.Attributes[name]})
+		if err != nil {
+			invalid = append(invalid, AttributeError{Attribute: name, Err: err})
+			continue
+		}
+		scratch := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+		if err := json.Unmarshal(raw, scratch); err != nil {
+			invalid = append(invalid, AttributeError{Attribute: name, Err: err})
+			continue
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return nil, fmt.Errorf("decode %s attributes: %w", s.EntityID, err)
+		}
+	}
+	return invalid, nil
+}
+
+// LightFeature is a bit in the supported_features attribute of a light.
+type LightFeature int
+
+// Light features.
+const (
+	LightSupportEffect     LightFeature = 4
+	LightSupportFlash      LightFeature = 8
+	LightSupportTransition LightFeature = 32
+)
+
+// Has reports whether all bits of flag are set.
+func (f LightFeature) Has(flag LightFeature) bool {
+	return f&flag == flag
+}
+
+// Light color modes, as found in color_mode and supported_color_modes.
+const (
+	ColorModeUnknown    = "unknown"
+	ColorModeOnOff      = "onoff"
+	ColorModeBrightness = "brightness"
+	ColorModeColorTemp  = "color_temp"
+	ColorModeHS         = "hs"
+	ColorModeXY         = "xy"
+	ColorModeRGB        = "rgb"
+	ColorModeRGBW       = "rgbw"
+	ColorModeRGBWW      = "rgbww"
+	ColorModeWhite      = "white"
+)
+
+// LightState is a typed view of a light entity.
+// Color attributes are only set while the light is on.
+type LightState struct {
+	State `json:"-"`
+
+	// InvalidAttributes lists attributes that had an unexpected type
+	// and were left at their zero value.
+	InvalidAttributes []AttributeError `json:"-"`
+
+	// Brightness is from 0 to 255.
+	Brightness          *int         `json:"brightness"`
+	ColorMode           string       `json:"color_mode"`
+	SupportedColorModes []string     `json:"supported_color_modes"`
+	ColorTempKelvin     *int         `json:"color_temp_kelvin"`
+	MinColorTempKelvin  *int         `json:"min_color_temp_kelvin"`
+	MaxColorTempKelvin  *int         `json:"max_color_temp_kelvin"`
+	HSColor             []float64    `json:"hs_color"`
+	RGBColor            []int        `json:"rgb_color"`
+	RGBWColor           []int        `json:"rgbw_color"`
+	RGBWWColor          []int        `json:"rgbww_color"`
+	XYColor             []float64    `json:"xy_color"`
+	Effect              string       `json:"effect"`
+	EffectList          []string     `json:"effect_list"`
+	SupportedFeatures   LightFeature `json:"supported_features"`
+}
+
+// Light returns a typed view of a light entity.
+func (s State) Light() (*LightState, error) {
+	l := &LightState{State: s}
+	var err error
+	if l.InvalidAttributes, err = s.decodeAttributes("light", l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// IsOn reports whether the light is on.
+func (l *LightState) IsOn() bool {
+	return l.State.State == StateOn
+}
+
+// BrightnessPct returns the brightness as a percentage, rounded to the
+// nearest integer. ok is false if the light reports no brightness.
+func (l *LightState) BrightnessPct() (pct int, ok bool) {
+	if l.Brightness == nil {
+		return 0, false
+	}
+	return (*l.Brightness*100 + 127) / 255, true
+}
+
+// SupportsColorMode reports whether the light supports a color mode.
+func (l *LightState) SupportsColorMode(mode string) bool {
+	return slices.Contains(l.SupportedColorModes, mode)
+}
+
+// Supports reports whether the light supports a feature.
+func (l *LightState) Supports(flag LightFeature) bool {
+	return l.SupportedFeatures.Has(flag)
+}
+
+// ClimateFeature is a bit in the supported_features attribute of a climate
+// entity.
+type ClimateFeature int
+
+// Climate features.
+const (
+	ClimateSupportTargetTemperature      ClimateFeature = 1
+	ClimateSupportTargetTemperatureRange ClimateFeature = 2
+	ClimateSupportTargetHumidity         ClimateFeature = 4
+	ClimateSupportFanMode                ClimateFeature = 8
+	ClimateSupportPresetMode             ClimateFeature = 16
+	ClimateSupportSwingMode              ClimateFeature = 32
+	ClimateSupportAuxHeat                ClimateFeature = 64
+	ClimateSupportTurnOff                ClimateFeature = 128
+	ClimateSupportTurnOn                 ClimateFeature = 256
+	ClimateSupportSwingHorizontalMode    ClimateFeature = 512
+)
+
+// Has reports whether all bits of flag are set.
+func (f ClimateFeature) Has(flag ClimateFeature) bool {
+	return f&flag == flag
+}
+
+// HVAC modes, the state of a climate entity.
+const (
+	HVACModeOff      = "off"
+	HVACModeHeat     = "heat"
+	HVACModeCool     = "cool"
+	HVACModeHeatCool = "heat_cool"
+	HVACModeAuto     = "auto"
+	HVACModeDry      = "dry"
+	HVACModeFanOnly  = "fan_only"
+)
+
+// HVAC actions, reported in the hvac_action attribute.
+const (
+	HVACActionOff        = "off"
+	HVACActionPreheating = "preheating"
+	HVACActionHeating    = "heating"
+	HVACActionCooling    = "cooling"
+	HVACActionDrying     = "drying"
+	HVACActionFan        = "fan"
+	HVACActionIdle       = "idle"
+	HVACActionDefrosting = "defrosting"
+)
+
+// ClimateState is a typed view of a climate entity. Temperatures are in the
+// unit system of the Home Assistant instance.
+type ClimateState struct {
+	State `json:"-"`
+
+	InvalidAttributes []AttributeError `json:"-"`
+
+	HVACModes          []string       `json:"hvac_modes"`
+	HVACAction         string         `json:"hvac_action"`
+	CurrentTemperature *float64       `json:"current_temperature"`
+	Temperature        *float64       `json:"temperature"`
+	TargetTempHigh     *float64       `json:"target_temp_high"`
+	TargetTempLow      *float64       `json:"target_temp_low"`
+	TargetTempStep     *float64       `json:"target_temp_step"`
+	MinTemp            *float64       `json:"min_temp"`
+	MaxTemp            *float64       `json:"max_temp"`
+	CurrentHumidity    *float64       `json:"current_humidity"`
+	Humidity           *float64       `json:"humidity"`
+	MinHumidity        *float64       `json:"min_humidity"`
+	MaxHumidity        *float64       `json:"max_humidity"`
+	FanMode            string         `json:"fan_mode"`
+	FanModes           []string       `json:"fan_modes"`
+	PresetMode         string         `json:"preset_mode"`
+	PresetModes        []string       `json:"preset_modes"`
+	SwingMode          string         `json:"swing_mode"`
+	SwingModes         []string       `json:"swing_modes"`
+	SupportedFeatures  ClimateFeature `json:"supported_features"`
+}
+
+// Climate returns a typed view of a climate entity.
+func (s State) Climate() (*ClimateState, error) {
+	c := &ClimateState{State: s}
+	var err error
+	if c.InvalidAttributes, err = s.decodeAttributes("climate", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// HVACMode returns the current HVAC mode, which is the entity state.
+func (c *ClimateState) HVACMode() string {
+	return c.State.State
+}
+
+// Supports reports whether the climate entity supports a feature.
+func (c *ClimateState) Supports(flag ClimateFeature) bool {
+	return c.SupportedFeatures.Has(flag)
+}
+
+// CoverFeature is a bit in the supported_features attribute of a cover.
+type CoverFeature int
+
+// Cover features.
+const (
+	CoverSupportOpen            CoverFeature = 1
+	CoverSupportClose           CoverFeature = 2
+	CoverSupportSetPosition     CoverFeature = 4
+	CoverSupportStop            CoverFeature = 8
+	CoverSupportOpenTilt        CoverFeature = 16
+	CoverSupportCloseTilt       CoverFeature = 32
+	CoverSupportStopTilt        CoverFeature = 64
+	CoverSupportSetTiltPosition CoverFeature = 128
+)
+
+// Has reports whether all bits of flag are set.
+func (f CoverFeature) Has(flag CoverFeature) bool {
+	return f&flag == flag
+}
+
+// Cover states.
+const (
+	CoverOpen    = "open"
+	CoverClosed  = "closed"
+	CoverOpening = "opening"
+	CoverClosing = "closing"
+)
+
+// CoverState is a typed view of a cover entity. Positions are from 0
+// (closed) to 100 (open).
+type CoverState struct {
+	State `json:"-"`
+
+	InvalidAttributes []AttributeError `json:"-"`
+
+	CurrentPosition     *int         `json:"current_position"`
+	CurrentTiltPosition *int         `json:"current_tilt_position"`
+	DeviceClass         string       `json:"device_class"`
+	SupportedFeatures   CoverFeature `json:"supported_features"`
+}
+
+// Cover returns a typed view of a cover entity.
+func (s State) Cover() (*CoverState, error) {
+	c := &CoverState{State: s}
+	var err error
+	if c.InvalidAttributes, err = s.decodeAttributes("cover", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// IsOpen reports whether the cover is open or opening.
+func (c *CoverState) IsOpen() bool {
+	return c.State.State == CoverOpen || c.State.State == CoverOpening
+}
+
+// IsClosed reports whether the cover is closed.
+func (c *CoverState) IsClosed() bool {
+	return c.State.State == CoverClosed
+}
+
+// IsMoving reports whether the cover is opening or closing.
+func (c *CoverState) IsMoving() bool {
+	return c.State.State == CoverOpening || c.State.State == CoverClosing
+}
+
+// Supports reports whether the cover supports a feature.
+func (c *CoverState) Supports(flag CoverFeature) bool {
+	return c.SupportedFeatures.Has(flag)
+}
+
+// MediaPlayerFeature is a bit in the supported_features attribute of a
+// media player.
+type MediaPlayerFeature int
+
+// Media player features.
+const (
+	MediaPlayerSupportPause           MediaPlayerFeature = 1
+	MediaPlayerSupportSeek            MediaPlayerFeature = 2
+	MediaPlayerSupportVolumeSet       MediaPlayerFeature = 4
+	MediaPlayerSupportVolumeMute      MediaPlayerFeature = 8
+	MediaPlayerSupportPreviousTrack   MediaPlayerFeature = 16
+	MediaPlayerSupportNextTrack       MediaPlayerFeature = 32
+	MediaPlayerSupportTurnOn          MediaPlayerFeature = 128
+	MediaPlayerSupportTurnOff         MediaPlayerFeature = 256
+	MediaPlayerSupportPlayMedia       MediaPlayerFeature = 512
+	MediaPlayerSupportVolumeStep      MediaPlayerFeature = 1024
+	MediaPlayerSupportSelectSource    MediaPlayerFeature = 2048
+	MediaPlayerSupportStop            MediaPlayerFeature = 4096
+	MediaPlayerSupportClearPlaylist   MediaPlayerFeature = 8192
+	MediaPlayerSupportPlay            MediaPlayerFeature = 16384
+	MediaPlayerSupportShuffleSet      MediaPlayerFeature = 32768
+	MediaPlayerSupportSelectSoundMode MediaPlayerFeature = 65536
+	MediaPlayerSupportBrowseMedia     MediaPlayerFeature = 131072
+	MediaPlayerSupportRepeatSet       MediaPlayerFeature = 262144
+	MediaPlayerSupportGrouping        MediaPlayerFeature = 524288
+	MediaPlayerSupportMediaAnnounce   MediaPlayerFeature = 1048576
+	MediaPlayerSupportMediaEnqueue    MediaPlayerFeature = 2097152
+	MediaPlayerSupportSearchMedia     MediaPlayerFeature = 4194304
+)
+
+// Has reports whether all bits of flag are set.
+func (f MediaPlayerFeature) Has(flag MediaPlayerFeature) bool {
+	return f&flag == flag
+}
+
+// Media player states.
+const (
+	MediaPlayerOff       = "off"
+	MediaPlayerOn        = "on"
+	MediaPlayerIdle      = "idle"
+	MediaPlayerPlaying   = "playing"
+	MediaPlayerPaused    = "paused"
+	MediaPlayerStandby   = "standby"
+	MediaPlayerBuffering = "buffering"
+)
+
+// MediaPlayerState is a typed view of a media player entity.
+type MediaPlayerState struct {
+	State `json:"-"`
+
+	InvalidAttributes []AttributeError `json:"-"`
+
+	VolumeLevel            *float64           `json:"volume_level"`
+	IsVolumeMuted          *bool              `json:"is_volume_muted"`
+	MediaContentID         string             `json:"media_content_id"`
+	MediaContentType       string             `json:"media_content_type"`
+	MediaDuration          *float64           `json:"media_duration"`
+	MediaPosition          *float64           `json:"media_position"`
+	MediaPositionUpdatedAt *time.Time         `json:"media_position_updated_at"`
+	MediaTitle             string             `json:"media_title"`
+	MediaArtist            string             `json:"media_artist"`
+	MediaAlbumName         string             `json:"media_album_name"`
+	MediaSeriesTitle       string             `json:"media_series_title"`
+	AppName                string             `json:"app_name"`
+	Source                 string             `json:"source"`
+	SourceList             []string           `json:"source_list"`
+	SoundMode              string             `json:"sound_mode"`
+	SoundModeList          []string           `json:"sound_mode_list"`
+	Shuffle                *bool              `json:"shuffle"`
+	Repeat                 string             `json:"repeat"`
+	EntityPicture          string             `json:"entity_picture"`
+	DeviceClass            string             `json:"device_class"`
+	SupportedFeatures      MediaPlayerFeature `json:"supported_features"`
+}
+
+// MediaPlayer returns a typed view of a media player entity.
+func (s State) MediaPlayer() (*MediaPlayerState, error) {
+	m := &MediaPlayerState{State: s}
+	var err error
+	if m.InvalidAttributes, err = s.decodeAttributes("media_player", m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IsPlaying reports whether the media player is playing.
+func (m *MediaPlayerState) IsPlaying() bool {
+	return m.State.State == MediaPlayerPlaying
+}
+
+// Position estimates the playback position at now. Home Assistant only
+// updates media_position periodically, so while playing the time elapsed
+// since media_position_updated_at is added. ok is false if the player
+// reports no position.
+func (m *MediaPlayerState) Position(now time.Time) (position time.Duration, ok bool) {
+	if m.MediaPosition == nil {
+		return 0, false
+	}
+	position = time.Duration(*m.MediaPosition * float64(time.Second))
+	if m.IsPlaying() && m.MediaPositionUpdatedAt != nil {
+		position += now.Sub(*m.MediaPositionUpdatedAt)
+	}
+	if m.MediaDuration != nil {
+		position = min(position, time.Duration(*m.MediaDuration*float64(time.Second)))
+	}
+	return position, true
+}
+
+// Supports reports whether the media player supports a feature.
+func (m *MediaPlayerState) Supports(flag MediaPlayerFeature) bool {
+	return m.SupportedFeatures.Has(flag)
+}
+
+// Sensor state classes.
+const (
+	StateClassMeasurement     = "measurement"
+	StateClassTotal           = "total"
+	StateClassTotalIncreasing = "total_increasing"
+)
+
+// SensorState is a typed view of a sensor entity.
+type SensorState struct {
+	State `json:"-"`
+
+	InvalidAttributes []AttributeError `json:"-"`
+
+	// Value is the state parsed as a number, or nil if the state is not
+	// numeric (including unavailable and unknown).
+	Value             *float64 `json:"-"`
+	UnitOfMeasurement string   `json:"unit_of_measurement"`
+	DeviceClass       string   `json:"device_class"`
+	StateClass        string   `json:"state_class"`
+}
+
+// Sensor returns a typed view of a sensor entity.
+func (s State) Sensor() (*SensorState, error) {
+	sensor := &SensorState{State: s}
+	var err error
+	if sensor.InvalidAttributes, err = s.decodeAttributes("sensor", sensor); err != nil {
+		return nil, err
+	}
+	if v, err := strconv.ParseFloat(s.State, 64); err == nil {
+		sensor.Value = &v
+	}
+	return sensor, nil
+}
+
+// IsNumeric reports whether the sensor state is a number.
+func (s *SensorState) IsNumeric() bool {
+	return s.Value != nil
+}
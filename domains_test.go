@@ -0,0 +1,277 @@
+package hago
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustState(t *testing.T, raw string) State {
+	t.Helper()
+	var s State
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	return s
+}
+
+func TestState_Helpers(t *testing.T) {
+	s := State{EntityID: "light.kitchen", State: "unavailable"}
+	if s.Domain() != "light" {
+		t.Errorf("Domain() = %q, want light", s.Domain())
+	}
+	if s.IsAvailable() {
+		t.Error("expected unavailable entity")
+	}
+	if s.FriendlyName() != "light.kitchen" {
+		t.Errorf("FriendlyName() = %q, want entity ID fallback", s.FriendlyName())
+	}
+
+	s.Attributes = map[string]any{"friendly_name": "Kitchen"}
+	if s.FriendlyName() != "Kitchen" {
+		t.Errorf("FriendlyName() = %q, want Kitchen", s.FriendlyName())
+	}
+}
+
+func TestState_Light(t *testing.T) {
+	s := mustState(t, `{
+		"entity_id": "light.kitchen",
+		"state": "on",
+		"attributes": {
+			"brightness": 128,
+			"color_mode": "color_temp",
+			"supported_color_modes": ["color_temp", "hs"],
+			"color_temp_kelvin": 3000,
+			"hs_color": [30.5, 60],
+			"effect_list": ["colorloop"],
+			"supported_features": 44
+		}
+	}`)
+
+	light, err := s.Light()
+	if err != nil {
+		t.Fatalf("Light() error: %v", err)
+	}
+	if !light.IsOn() {
+		t.Error("expected light to be on")
+	}
+	if pct, ok := light.BrightnessPct(); !ok || pct != 50 {
+		t.Errorf("BrightnessPct() = %d, %v, want 50, true", pct, ok)
+	}
+	if light.ColorTempKelvin == nil || *light.ColorTempKelvin != 3000 {
+		t.Errorf("ColorTempKelvin = %v, want 3000", light.ColorTempKelvin)
+	}
+	if len(light.HSColor) != 2 || light.HSColor[0] != 30.5 {
+		t.Errorf("HSColor = %v", light.HSColor)
+	}
+	if !light.SupportsColorMode(ColorModeHS) || light.SupportsColorMode(ColorModeRGB) {
+		t.Errorf("unexpected color modes %v", light.SupportedColorModes)
+	}
+	if !light.Supports(LightSupportEffect) || !light.Supports(LightSupportFlash|LightSupportTransition) {
+		t.Errorf("expected effect, flash and transition support in %d", light.SupportedFeatures)
+	}
+	if light.EntityID != "light.kitchen" {
+		t.Errorf("EntityID = %q", light.EntityID)
+	}
+
+	if _, err := s.Climate(); err == nil {
+		t.Error("expected error viewing a light as a climate entity")
+	}
+}
+
+func TestState_LightOff(t *testing.T) {
+	light, err := State{EntityID: "light.porch", State: "off", Attributes: map[string]any{"brightness": nil}}.Light()
+	if err != nil {
+		t.Fatalf("Light() error: %v", err)
+	}
+	if light.IsOn() {
+		t.Error("expected light to be off")
+	}
+	if _, ok := light.BrightnessPct(); ok {
+		t.Error("expected no brightness while off")
+	}
+}
+
+func TestState_LightInvalidAttributes(t *testing.T) {
+	s := mustState(t, `{
+		"entity_id": "light.desk",
+		"state": "on",
+		"attributes": {
+			"brightness": 127.5,
+			"color_temp_kelvin": "warm",
+			"color_mode": "color_temp",
+			"supported_features": 4
+		}
+	}`)
+
+	light, err := s.Light()
+	if err != nil {
+		t.Fatalf("Light() error: %v", err)
+	}
+	if light.Brightness != nil || light.ColorTempKelvin != nil {
+		t.Errorf("invalid attributes should be left nil, got %v and %v", light.Brightness, light.ColorTempKelvin)
+	}
+	if light.ColorMode != ColorModeColorTemp || !light.Supports(LightSupportEffect) {
+		t.Errorf("valid attributes were not decoded: %+v", light)
+	}
+	if len(light.InvalidAttributes) != 2 ||
+		light.InvalidAttributes[0].Attribute != "brightness" ||
+		light.InvalidAttributes[1].Attribute != "color_temp_kelvin" {
+		t.Errorf("InvalidAttributes = %v", light.InvalidAttributes)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(light.InvalidAttributes[0], &typeErr) {
+		t.Errorf("AttributeError should wrap the decoding error, got %v", light.InvalidAttributes[0].Err)
+	}
+}
+
+func TestState_Climate(t *testing.T) {
+	s := mustState(t, `{
+		"entity_id": "climate.living_room",
+		"state": "heat",
+		"attributes": {
+			"hvac_modes": ["off", "heat", "cool"],
+			"hvac_action": "heating",
+			"current_temperature": 19.5,
+			"temperature": 21,
+			"preset_mode": "comfort",
+			"preset_modes": ["eco", "comfort"],
+			"supported_features": 401
+		}
+	}`)
+
+	climate, err := s.Climate()
+	if err != nil {
+		t.Fatalf("Climate() error: %v", err)
+	}
+	if climate.HVACMode() != HVACModeHeat || climate.HVACAction != HVACActionHeating {
+		t.Errorf("mode = %q, action = %q", climate.HVACMode(), climate.HVACAction)
+	}
+	if climate.CurrentTemperature == nil || *climate.CurrentTemperature != 19.5 {
+		t.Errorf("CurrentTemperature = %v, want 19.5", climate.CurrentTemperature)
+	}
+	if climate.Temperature == nil || *climate.Temperature != 21 {
+		t.Errorf("Temperature = %v, want 21", climate.Temperature)
+	}
+	if climate.TargetTempHigh != nil {
+		t.Errorf("TargetTempHigh = %v, want nil", *climate.TargetTempHigh)
+	}
+	for _, f := range []ClimateFeature{ClimateSupportTargetTemperature, ClimateSupportPresetMode, ClimateSupportTurnOff, ClimateSupportTurnOn} {
+		if !climate.Supports(f) {
+			t.Errorf("expected support for feature %d", f)
+		}
+	}
+	if climate.Supports(ClimateSupportFanMode) {
+		t.Error("unexpected fan mode support")
+	}
+}
+
+func TestState_Cover(t *testing.T) {
+	s := mustState(t, `{
+		"entity_id": "cover.garage",
+		"state": "opening",
+		"attributes": {"current_position": 40, "device_class": "garage", "supported_features": 15}
+	}`)
+
+	cover, err := s.Cover()
+	if err != nil {
+		t.Fatalf("Cover() error: %v", err)
+	}
+	if !cover.IsOpen() || !cover.IsMoving() || cover.IsClosed() {
+		t.Errorf("unexpected state helpers for %q", cover.State.State)
+	}
+	if cover.CurrentPosition == nil || *cover.CurrentPosition != 40 {
+		t.Errorf("CurrentPosition = %v, want 40", cover.CurrentPosition)
+	}
+	if !cover.Supports(CoverSupportSetPosition) || cover.Supports(CoverSupportOpenTilt) {
+		t.Errorf("unexpected features %d", cover.SupportedFeatures)
+	}
+}
+
+func TestState_MediaPlayer(t *testing.T) {
+	s := mustState(t, `{
+		"entity_id": "media_player.den",
+		"state": "playing",
+		"attributes": {
+			"volume_level": 0.4,
+			"is_volume_muted": false,
+			"media_title": "Song",
+			"media_artist": "Artist",
+			"media_duration": 200,
+			"media_position": 30,
+			"media_position_updated_at": "2024-01-01T12:00:00+00:00",
+			"source_list": ["TV", "Spotify"],
+			"supported_features": 21437
+		}
+	}`)
+
+	player, err := s.MediaPlayer()
+	if err != nil {
+		t.Fatalf("MediaPlayer() error: %v", err)
+	}
+	if !player.IsPlaying() {
+		t.Error("expected player to be playing")
+	}
+	if player.VolumeLevel == nil || *player.VolumeLevel != 0.4 {
+		t.Errorf("VolumeLevel = %v, want 0.4", player.VolumeLevel)
+	}
+	if player.MediaTitle != "Song" || len(player.SourceList) != 2 {
+		t.Errorf("unexpected media attributes %+v", player)
+	}
+
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if pos, ok := player.Position(updated.Add(10 * time.Second)); !ok || pos != 40*time.Second {
+		t.Errorf("Position() = %v, %v, want 40s, true", pos, ok)
+	}
+	if pos, _ := player.Position(updated.Add(time.Hour)); pos != 200*time.Second {
+		t.Errorf("Position() = %v, want capped at duration", pos)
+	}
+
+	for _, f := range []MediaPlayerFeature{MediaPlayerSupportPause, MediaPlayerSupportVolumeSet, MediaPlayerSupportPlay} {
+		if !player.Supports(f) {
+			t.Errorf("expected support for feature %d", f)
+		}
+	}
+	if player.Supports(MediaPlayerSupportSeek) {
+		t.Error("unexpected seek support")
+	}
+}
+
+func TestState_Sensor(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  *float64
+	}{
+		{"numeric", "21.5", Ptr(21.5)},
+		{"unavailable", "unavailable", nil},
+		{"text", "sunny", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sensor, err := State{
+				EntityID: "sensor.outdoor",
+				State:    tt.state,
+				Attributes: map[string]any{
+					"unit_of_measurement": "°C",
+					"device_class":        "temperature",
+					"state_class":         "measurement",
+				},
+			}.Sensor()
+			if err != nil {
+				t.Fatalf("Sensor() error: %v", err)
+			}
+			if (sensor.Value == nil) != (tt.want == nil) || (tt.want != nil && *sensor.Value != *tt.want) {
+				t.Errorf("Value = %v, want %v", sensor.Value, tt.want)
+			}
+			if sensor.IsNumeric() != (tt.want != nil) {
+				t.Errorf("IsNumeric() = %v", sensor.IsNumeric())
+			}
+			if sensor.UnitOfMeasurement != "°C" || sensor.DeviceClass != "temperature" || sensor.StateClass != StateClassMeasurement {
+				t.Errorf("unexpected attributes %+v", sensor)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package hago
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 )
 
 // Common errors returned by the client.
@@ -24,35 +26,104 @@ var (
 
 	// ErrMethodNotAllowed is returned when the HTTP method is not supported.
 	ErrMethodNotAllowed = errors.New("method not allowed")
+
+	// ErrForbidden is returned when the token lacks permission, for example
+	// when a non-admin user calls an admin-only API.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrServerError is returned when Home Assistant fails with a 5xx status
+	// or an unexpected internal error.
+	ErrServerError = errors.New("server error")
+
+	// ErrTimeout is returned when a request or command times out.
+	ErrTimeout = errors.New("timeout")
+
+	// ErrWebSocketClosed is returned when the WebSocket connection is lost
+	// while a command is waiting for its response.
+	ErrWebSocketClosed = errors.New("websocket connection closed")
 )
 
 // APIError represents an error response from the Home Assistant API.
+// It wraps the sentinel error for its status class, so both
+// errors.Is(err, ErrNotFound) and errors.As(err, &apiErr) work.
 type APIError struct {
 	StatusCode int
 	Status     string
 	Message    string
 	Body       string
+	// Method and Path identify the failing request.
+	Method string
+	Path   string
+	// EntityID is the entity the request targeted, if any.
+	EntityID string
+	// Err is the sentinel error for the status, such as ErrNotFound.
+	Err error
+	// Attempts is the number of attempts made, when retries are enabled.
+	Attempts int
 }
 
 // Error implements the error interface.
 func (e *APIError) Error() string {
-	if e.Message != "" {
-		return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Status, e.Message)
+	var msg string
+	switch {
+	case e.Message != "":
+		msg = fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Status, e.Message)
+	case e.Body != "":
+		msg = fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Status, e.Body)
+	case e.Err != nil:
+		msg = fmt.Sprintf("API error %d (%s): %v", e.StatusCode, e.Status, e.Err)
+	default:
+		msg = fmt.Sprintf("API error %d (%s)", e.StatusCode, e.Status)
+	}
+	if e.Method != "" {
+		msg = fmt.Sprintf("%s %s: %s", e.Method, e.Path, msg)
 	}
-	if e.Body != "" {
-		return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Status, e.Body)
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error for the status.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// statusError returns the sentinel error for an HTTP status code.
+func statusError(code int) error {
+	switch {
+	case code == 400:
+		return ErrBadRequest
+	case code == 401:
+		return ErrUnauthorized
+	case code == 403:
+		return ErrForbidden
+	case code == 404:
+		return ErrNotFound
+	case code == 405:
+		return ErrMethodNotAllowed
+	case code == 408 || code == 504:
+		return ErrTimeout
+	case code >= 500:
+		return ErrServerError
+	default:
+		return nil
 	}
-	return fmt.Sprintf("API error %d (%s)", e.StatusCode, e.Status)
 }
 
 // RequestError represents an error that occurred while making a request.
 type RequestError struct {
 	Op  string
 	Err error
+	// Attempts is the number of attempts made, when retries are enabled.
+	Attempts int
 }
 
 // Error implements the error interface.
 func (e *RequestError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("%s (after %d attempts): %v", e.Op, e.Attempts, e.Err)
+	}
 	return fmt.Sprintf("%s: %v", e.Op, e.Err)
 }
 
@@ -60,3 +131,74 @@ func (e *RequestError) Error() string {
 func (e *RequestError) Unwrap() error {
 	return e.Err
 }
+
+// Is reports whether the error matches target. Timeouts and deadline
+// expiries match ErrTimeout.
+func (e *RequestError) Is(target error) bool {
+	return target == ErrTimeout && isTimeout(e.Err)
+}
+
+// isTimeout reports whether err is a network timeout or deadline expiry.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WebSocket error codes returned by Home Assistant.
+const (
+	ErrCodeIDReuse            = "id_reuse"
+	ErrCodeInvalidFormat      = "invalid_format"
+	ErrCodeNotFound           = "not_found"
+	ErrCodeNotSupported       = "not_supported"
+	ErrCodeHomeAssistantError = "home_assistant_error"
+	ErrCodeServiceValidation  = "service_validation_error"
+	ErrCodeUnknownCommand     = "unknown_command"
+	ErrCodeUnknownError       = "unknown_error"
+	ErrCodeUnauthorized       = "unauthorized"
+	ErrCodeTimeout            = "timeout"
+	ErrCodeTemplateError      = "template_error"
+)
+
+// WebSocketError represents an error from the WebSocket API.
+// Common codes match the sentinel errors, so errors.Is(err, ErrNotFound)
+// works for a not_found result.
+type WebSocketError struct {
+	Code    string
+	Message string
+	// Command is the type of the failing command, such as "call_service".
+	Command string
+	// EntityID is the entity the command targeted, if any. For call_service
+	// it is taken from the target or service data; the first entity is used
+	// if several were targeted.
+	EntityID string
+}
+
+// Error implements the error interface.
+func (e *WebSocketError) Error() string {
+	msg := fmt.Sprintf("websocket error [%s]: %s", e.Code, e.Message)
+	if e.Command != "" {
+		msg = e.Command + ": " + msg
+	}
+	return msg
+}
+
+// Is reports whether the error code corresponds to target.
+func (e *WebSocketError) Is(target error) bool {
+	switch e.Code {
+	case ErrCodeNotFound:
+		return target == ErrNotFound
+	case ErrCodeInvalidFormat, ErrCodeServiceValidation:
+		return target == ErrBadRequest
+	case ErrCodeUnauthorized:
+		return target == ErrUnauthorized
+	case ErrCodeTimeout:
+		return target == ErrTimeout
+	case ErrCodeUnknownError, ErrCodeHomeAssistantError:
+		return target == ErrServerError
+	default:
+		return false
+	}
+}
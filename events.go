@@ -0,0 +1,118 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventMessage is an event received from the Home Assistant event bus.
+type EventMessage struct {
+	EventType string    `json:"event_type"`
+	Data      EventData `json:"data"`
+	Origin    string    `json:"origin,omitempty"`
+	TimeFired time.Time `json:"time_fired"`
+	Context   Context   `json:"context"`
+}
+
+// StateChangedData is the payload of a state_changed event.
+// OldState is nil when the entity was added; NewState is nil when it was removed.
+type StateChangedData struct {
+	EntityID string `json:"entity_id"`
+	OldState *State `json:"old_state"`
+	NewState *State `json:"new_state"`
+}
+
+// StateChanged decodes the event data of a state_changed event.
+func (e *EventMessage) StateChanged() (*StateChangedData, error) {
+	if e.EventType != "state_changed" {
+		return nil, fmt.Errorf("event %s is not state_changed", e.EventType)
+	}
+
+	raw, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("encode event data: %w", err)
+	}
+
+	var data StateChangedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("decode state_changed data: %w", err)
+	}
+	return &data, nil
+}
+
+// fireEventCmd is the WebSocket command to fire an event.
+type fireEventCmd struct {
+	Type      string    `json:"type"`
+	EventType string    `json:"event_type"`
+	EventData EventData `json:"event_data,omitempty"`
+}
+
+// FireEventTracked fires an event over WebSocket, regardless of the
+// configured transport, and returns the context of the fired event.
+// Listeners that act on the event carry the same context as their parent.
+func (c *Client) FireEventTracked(ctx context.Context, eventType string, data EventData) (*Context, error) {
+	return c.fireEventWebSocket(ctx, eventType, data)
+}
+
+// fireEventWebSocket fires an event with the fire_event WebSocket command.
+func (c *Client) fireEventWebSocket(ctx context.Context, eventType string, data EventData) (*Context, error) {
+	if eventType == "" {
+		return nil, fmt.Errorf("event type is required")
+	}
+
+	cmd := fireEventCmd{
+		Type:      "fire_event",
+		EventType: eventType,
+		EventData: data,
+	}
+
+	var result struct {
+		Context *Context `json:"context"`
+	}
+	if err := c.wsCommand(ctx, cmd, &result); err != nil {
+		return nil, fmt.Errorf("fire event %s: %w", eventType, err)
+	}
+	return result.Context, nil
+}
+
+// subscribeEventsCmd is the WebSocket command to subscribe to bus events.
+type subscribeEventsCmd struct {
+	Type      string `json:"type"`
+	EventType string `json:"event_type,omitempty"`
+}
+
+// SubscribeEvents subscribes to events on the Home Assistant event bus.
+// If eventType is empty, all events are delivered.
+//
+// Events are delivered on the returned channel until ctx is done, at which
+// point the subscription is cancelled with unsubscribe_events and the channel
+// is closed. If the WebSocket connection is lost the channel is closed too,
+// unless WithWebSocketReconnect is enabled: then the subscription is replayed
+// on the new connection and the channel stays open, closing only when
+// reconnection gives up or CloseWebSocket is called. Events fired while
+// disconnected are missed; the replay itself is not delivered as an event,
+// so use WithConnectionStateHandler to learn when to resynchronize.
+func (c *Client) SubscribeEvents(ctx context.Context, eventType string) (<-chan EventMessage, error) {
+	cmd := subscribeEventsCmd{
+		Type:      "subscribe_events",
+		EventType: eventType,
+	}
+
+	sub, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe events: %w", err)
+	}
+
+	events := make(chan EventMessage)
+	go deliver(ctx, sub, events, func(raw json.RawMessage) (EventMessage, bool) {
+		var event EventMessage
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return event, false
+		}
+		return event, true
+	})
+
+	return events, nil
+}
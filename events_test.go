@@ -0,0 +1,198 @@
+package hago
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_SubscribeEvents(t *testing.T) {
+	unsubscribed := make(chan float64, 1)
+
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		// Auth flow
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		// Read subscribe command
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+
+		if cmd["type"] != "subscribe_events" {
+			t.Errorf("expected subscribe_events, got %v", cmd["type"])
+		}
+		if cmd["event_type"] != "state_changed" {
+			t.Errorf("expected event_type state_changed, got %v", cmd["event_type"])
+		}
+
+		conn.WriteJSON(map[string]any{
+			"id":      cmd["id"],
+			"type":    "result",
+			"success": true,
+			"result":  nil,
+		})
+
+		// Send two events
+		for _, state := range []string{"on", "off"} {
+			conn.WriteJSON(map[string]any{
+				"id":   cmd["id"],
+				"type": "event",
+				"event": map[string]any{
+					"event_type": "state_changed",
+					"data": map[string]any{
+						"entity_id": "light.kitchen",
+						"new_state": map[string]any{
+							"entity_id": "light.kitchen",
+							"state":     state,
+						},
+						"old_state": nil,
+					},
+					"origin":     "LOCAL",
+					"time_fired": "2024-01-01T12:00:00.000000+00:00",
+					"context":    map[string]any{"id": "ctx-" + state},
+				},
+			})
+		}
+
+		// Expect unsubscribe once the caller's context ends
+		var unsub map[string]any
+		if err := conn.ReadJSON(&unsub); err != nil {
+			t.Errorf("read unsubscribe: %v", err)
+			return
+		}
+		if unsub["type"] != "unsubscribe_events" {
+			t.Errorf("expected unsubscribe_events, got %v", unsub["type"])
+		}
+		sub, _ := unsub["subscription"].(float64)
+		unsubscribed <- sub
+
+		conn.WriteJSON(map[string]any{
+			"id":      unsub["id"],
+			"type":    "result",
+			"success": true,
+		})
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.SubscribeEvents(ctx, "state_changed")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	for _, want := range []string{"on", "off"} {
+		select {
+		case event := <-events:
+			if event.EventType != "state_changed" {
+				t.Errorf("EventType = %v, want state_changed", event.EventType)
+			}
+			if event.Context.ID != "ctx-"+want {
+				t.Errorf("Context.ID = %v, want ctx-%s", event.Context.ID, want)
+			}
+			if event.TimeFired.IsZero() {
+				t.Error("expected TimeFired to be set")
+			}
+
+			data, err := event.StateChanged()
+			if err != nil {
+				t.Fatalf("StateChanged() error = %v", err)
+			}
+			if data.EntityID != "light.kitchen" {
+				t.Errorf("EntityID = %v, want light.kitchen", data.EntityID)
+			}
+			if data.NewState == nil || data.NewState.State != want {
+				t.Errorf("NewState = %+v, want state %s", data.NewState, want)
+			}
+			if data.OldState != nil {
+				t.Errorf("OldState = %+v, want nil", data.OldState)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	cancel()
+
+	select {
+	case sub := <-unsubscribed:
+		if sub != 1 {
+			t.Errorf("unsubscribe subscription = %v, want 1", sub)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+
+	// Channel is closed once the subscription ends
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected events channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+}
+
+func TestEventMessage_StateChangedWrongType(t *testing.T) {
+	event := &EventMessage{EventType: "call_service"}
+	if _, err := event.StateChanged(); err == nil {
+		t.Error("expected error for non state_changed event")
+	}
+}
+
+func TestClient_FireEventTracked(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+		if cmd["type"] != "fire_event" || cmd["event_type"] != "doorbell_pressed" {
+			t.Errorf("unexpected command: %v", cmd)
+		}
+		data, _ := cmd["event_data"].(map[string]any)
+		if data["door"] != "front" {
+			t.Errorf("event_data = %v", cmd["event_data"])
+		}
+
+		conn.WriteJSON(map[string]any{
+			"id":      cmd["id"],
+			"type":    "result",
+			"success": true,
+			"result":  map[string]any{"context": map[string]any{"id": "ctx-event", "user_id": "user-1"}},
+		})
+
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	eventCtx, err := client.FireEventTracked(context.Background(), "doorbell_pressed", EventData{"door": "front"})
+	if err != nil {
+		t.Fatalf("FireEventTracked() error = %v", err)
+	}
+	if eventCtx == nil || eventCtx.ID != "ctx-event" || eventCtx.UserID != "user-1" {
+		t.Errorf("context = %+v", eventCtx)
+	}
+}
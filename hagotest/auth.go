@@ -0,0 +1,141 @@
+package hagotest
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// DefaultRefreshTokenID is the ID of the refresh token behind the server's
+// access token, as listed by auth/refresh_tokens.
+const DefaultRefreshTokenID = "hagotest-refresh-token"
+
+// initAuth sets up the current user and the refresh token of the server's
+// access token.
+func (s *Server) initAuth() {
+	provider := "homeassistant"
+	s.user = hago.CurrentUser{
+		ID:          "hagotest-user",
+		Name:        "hagotest",
+		IsOwner:     true,
+		IsAdmin:     true,
+		Credentials: []hago.UserCredential{{AuthProviderType: provider}},
+		MFAModules:  []hago.MFAModule{},
+	}
+	name := "hagotest"
+	s.refreshTokens = []hago.RefreshToken{{
+		ID:         DefaultRefreshTokenID,
+		ClientName: &name,
+		Type:       hago.RefreshTokenTypeLongLived,
+		CreatedAt:  time.Now().UTC(),
+	}}
+	s.accessTokens = map[string]string{s.token: DefaultRefreshTokenID}
+}
+
+// authorize checks an access token, records its use, and returns the ID of
+// its refresh token.
+func (s *Server) authorize(accessToken, remoteAddr string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.accessTokens[accessToken]
+	if !ok {
+		return "", false
+	}
+	if i := slices.IndexFunc(s.refreshTokens, func(t hago.RefreshToken) bool { return t.ID == id }); i >= 0 {
+		now := time.Now().UTC()
+		ip := remoteAddr
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			ip = host
+		}
+		s.refreshTokens[i].LastUsedAt = &now
+		s.refreshTokens[i].LastUsedIP = &ip
+	}
+	return id, true
+}
+
+// SetCurrentUser sets the user returned by auth/current_user.
+func (s *Server) SetCurrentUser(user hago.CurrentUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// AddRefreshToken adds a refresh token to those listed by
+// auth/refresh_tokens, e.g. a stale Long-Lived Access Token.
+func (s *Server) AddRefreshToken(token hago.RefreshToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshTokens = append(s.refreshTokens, token)
+}
+
+// RefreshTokens returns the refresh tokens of the current user.
+func (s *Server) RefreshTokens() []hago.RefreshToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.refreshTokens)
+}
+
+// handleAuth handles the auth/* commands. Long-Lived Access Tokens created
+// with auth/long_lived_access_token are accepted by the server until their
+// refresh token is deleted.
+func (c *wsConn) handleAuth(msg *wsMessage) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Type {
+	case "auth/current_user":
+		c.result(msg.ID, s.user)
+
+	case "auth/refresh_tokens":
+		tokens := slices.Clone(s.refreshTokens)
+		for i := range tokens {
+			tokens[i].IsCurrent = tokens[i].ID == c.refreshTokenID
+		}
+		c.result(msg.ID, tokens)
+
+	case "auth/long_lived_access_token":
+		if msg.ClientName == "" {
+			c.fail(msg.ID, hago.ErrCodeInvalidFormat, "required key not provided @ data['client_name']")
+			return
+		}
+		for _, t := range s.refreshTokens {
+			if t.Type == hago.RefreshTokenTypeLongLived && t.Name() == msg.ClientName {
+				c.fail(msg.ID, hago.ErrCodeUnknownError, "Can't have multiple long lived access tokens with the same name")
+				return
+			}
+		}
+		s.tokenSeq++
+		name := msg.ClientName
+		token := hago.RefreshToken{
+			ID:         fmt.Sprintf("token-%d", s.tokenSeq),
+			ClientName: &name,
+			Type:       hago.RefreshTokenTypeLongLived,
+			CreatedAt:  time.Now().UTC(),
+		}
+		accessToken := fmt.Sprintf("hagotest-llat-%d", s.tokenSeq)
+		s.refreshTokens = append(s.refreshTokens, token)
+		s.accessTokens[accessToken] = token.ID
+		c.result(msg.ID, accessToken)
+
+	case "auth/delete_refresh_token":
+		i := slices.IndexFunc(s.refreshTokens, func(t hago.RefreshToken) bool { return t.ID == msg.RefreshTokenID })
+		if i < 0 {
+			c.fail(msg.ID, "invalid_token_id", "Received invalid token")
+			return
+		}
+		s.refreshTokens = slices.Delete(s.refreshTokens, i, i+1)
+		for access, id := range s.accessTokens {
+			if id == msg.RefreshTokenID {
+				delete(s.accessTokens, access)
+			}
+		}
+		c.result(msg.ID, nil)
+
+	default:
+		c.fail(msg.ID, hago.ErrCodeUnknownCommand, "Unknown command.")
+	}
+}
@@ -0,0 +1,57 @@
+// Package hagotest provides an in-process fake Home Assistant server for
+// testing code built on hago.Client.
+//
+// The fake keeps an in-memory state machine behind the REST states API,
+// records service calls, runs an event bus shared by REST and WebSocket
+// clients, stores registry, Lovelace, automation and script config, and
+// manages users, persons and the refresh tokens of the current user.
+// Faults such as latency, error statuses and dropped sockets can be
+// injected to exercise retry and reconnect paths.
+//
+// # Basic Usage
+//
+//	func TestKitchenLights(t *testing.T) {
+//	    ha := hagotest.Start(t,
+//	        hagotest.WithState("light.kitchen", "off", nil),
+//	    )
+//	    client := ha.Client(t)
+//
+//	    if _, err := client.CallService(ctx, "light", "turn_on", &hago.ServiceCallRequest{
+//	        EntityID: "light.kitchen",
+//	    }); err != nil {
+//	        t.Fatal(err)
+//	    }
+//
+//	    if got := ha.State("light.kitchen").State; got != "on" {
+//	        t.Errorf("state = %s, want on", got)
+//	    }
+//	    if calls := ha.CallsTo("light", "turn_on"); len(calls) != 1 {
+//	        t.Errorf("got %d turn_on calls", len(calls))
+//	    }
+//	}
+//
+// # Services
+//
+// Without a handler, turn_on, turn_off and toggle update the state of the
+// targeted entities and every other service call simply succeeds. Register
+// a handler with HandleService to customize behavior:
+//
+//	ha.HandleService("script", "notify_all", func(call hagotest.ServiceCall) error {
+//	    ha.FireEvent("notified", hago.EventData{"target": call.Data["target"]})
+//	    return nil
+//	})
+//
+// Services that return data, such as weather.get_forecasts, use
+// HandleServiceResponse. Their response is returned to callers that pass
+// return_response:
+//
+//	ha.HandleServiceResponse("todo", "get_items", func(call hagotest.ServiceCall) (map[string]any, error) {
+//	    return map[string]any{"todo.shopping": map[string]any{"items": items}}, nil
+//	})
+//
+// # Fault Injection
+//
+//	ha.InjectFault(hagotest.Fault{Path: "/api/states", Status: 503, Times: 2})
+//	ha.SetLatency(50 * time.Millisecond)
+//	ha.DropWebSockets()
+package hagotest
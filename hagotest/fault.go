@@ -0,0 +1,117 @@
+package hagotest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault describes an injected failure for matching HTTP requests. The
+// WebSocket endpoint is matched as path /api/websocket, so a fault there
+// makes new connections fail.
+type Fault struct {
+	// Method matches the request method; empty matches any method.
+	Method string
+	// Path matches requests whose path starts with this prefix; empty
+	// matches any path.
+	Path string
+	// Status is the status code to respond with. Zero passes the request
+	// through after Latency, which is useful for slowing a single endpoint.
+	Status int
+	// Latency delays the response.
+	Latency time.Duration
+	// Drop closes the connection without sending a response.
+	Drop bool
+	// Times limits how many requests the fault applies to; zero means
+	// until ClearFaults is called.
+	Times int
+}
+
+// InjectFault adds a fault. Faults are checked in the order they were added
+// and the first match applies.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetLatency delays every REST response and WebSocket result by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// DropWebSockets closes all open WebSocket connections without a close
+// handshake, as happens when Home Assistant restarts.
+func (s *Server) DropWebSockets() {
+	for _, conn := range s.connections() {
+		conn.close()
+	}
+}
+
+// sleep waits for the configured latency.
+func (s *Server) sleep() {
+	s.mu.Lock()
+	d := s.latency
+	s.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// applyFault applies the first matching fault and reports whether the
+// request was handled.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	var fault Fault
+	found := false
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		fault = *f
+		found = true
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		break
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return false
+	}
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+
+	switch {
+	case fault.Drop:
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		panic(http.ErrAbortHandler)
+	case fault.Status != 0:
+		writeMessage(w, fault.Status, "%s", http.StatusText(fault.Status))
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cassetteVersion is the current cassette file format version.
+const cassetteVersion = 1
+
+// Cassette holds recorded REST exchanges and WebSocket sessions.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+	Sessions     []Session     `json:"websocket_sessions"`
+}
+
+// Interaction is a recorded REST request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request. Headers are not recorded, so the
+// bearer token never reaches the cassette.
+type Request struct {
+	Method string `json:"method"`
+	// URL is the request path and query, without scheme or host.
+	URL  string          `json:"url"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	// Text holds a body that is not JSON, such as a rendered template.
+	Text string `json:"text,omitempty"`
+}
+
+// Session is one recorded WebSocket connection.
+type Session struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a single WebSocket message.
+type Frame struct {
+	// Dir is "send" for client messages and "recv" for server messages.
+	Dir     string          `json:"dir"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Frame directions.
+const (
+	DirSend = "send"
+	DirRecv = "recv"
+)
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s: unsupported version %d", path, c.Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating parent directories.
+func (c *Cassette) Save(path string) error {
+	c.Version = cassetteVersion
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
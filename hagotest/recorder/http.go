@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := bufferBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		r.setToken(bearerToken(req.Header.Get("Authorization")))
+	}
+
+	live := Request{
+		Method: req.Method,
+		URL:    req.URL.RequestURI(),
+		Body:   r.encodeBody(body),
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, live)
+	}
+	return r.record(req, live)
+}
+
+// record forwards the request and stores the exchange.
+func (r *Recorder) record(req *http.Request, live Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recorder: read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	recorded := Response{
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	scrubbed := r.scrub(data)
+	if strings.Contains(recorded.ContentType, "json") && json.Valid(scrubbed) {
+		recorded.Body = scrubbed
+	} else {
+		recorded.Text = string(scrubbed)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: live, Response: recorded})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay serves the first unused matching interaction.
+func (r *Recorder) replay(req *http.Request, live Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !r.match(live, in.Request) {
+			continue
+		}
+		r.used[i] = true
+
+		body := []byte(in.Response.Text)
+		if in.Response.Body != nil {
+			body = in.Response.Body
+		}
+		header := http.Header{}
+		if in.Response.ContentType != "" {
+			header.Set("Content-Type", in.Response.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, live.Method, live.URL)
+}
+
+// encodeBody scrubs a request body and stores it as JSON. Bodies that are
+// not JSON are stored as a JSON string.
+func (r *Recorder) encodeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	body = r.scrub(body)
+	if json.Valid(body) {
+		return compact(body)
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// bufferBody reads a request body and returns a clone of the request with
+// the body buffered, leaving the caller's request as it was.
+func bufferBody(req *http.Request) (*http.Request, []byte, error) {
+	out := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return out, nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("recorder: read request body: %w", err)
+	}
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	out.ContentLength = int64(len(data))
+	return out, data, nil
+}
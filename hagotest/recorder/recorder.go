@@ -0,0 +1,301 @@
+// Package recorder records Home Assistant REST exchanges and WebSocket frames
+// to a cassette file and replays them offline, so integration tests captured
+// once against a real instance can run in CI without network access.
+//
+// Record a session against a live instance:
+//
+//	rec, err := recorder.New("testdata/lights.json",
+//	    recorder.WithMode(recorder.ModeRecord),
+//	    recorder.WithScrubAttributes("latitude", "longitude"),
+//	)
+//	client, err := hago.New(append([]hago.Option{
+//	    hago.WithBaseURL(os.Getenv("HAGO_URL")),
+//	    hago.WithToken(os.Getenv("HAGO_TOKEN")),
+//	}, rec.ClientOptions()...)...)
+//	// ... exercise the client ...
+//	err = rec.Stop() // writes the cassette
+//
+// Replay it later with the default ModeReplay; the base URL and token are
+// not used for anything but must be set:
+//
+//	rec, err := recorder.New("testdata/lights.json")
+//	client, err := hago.New(append([]hago.Option{
+//	    hago.WithBaseURL("http://homeassistant.invalid:8123"),
+//	    hago.WithToken("replay"),
+//	}, rec.ClientOptions()...)...)
+//
+// The bearer token is never written to a cassette: request headers are not
+// recorded, the access token in the WebSocket auth message is redacted, and
+// any other occurrence of the token is replaced.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// Redacted replaces scrubbed values in a cassette.
+const Redacted = "REDACTED"
+
+// ErrNoInteraction is returned when replaying a request that is not in the cassette.
+var ErrNoInteraction = errors.New("recorder: no recorded interaction")
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves requests from the cassette and never touches the
+	// network. Loading fails if the cassette does not exist.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to Home Assistant and records them.
+	// Stop writes the cassette, replacing any existing file.
+	ModeRecord
+	// ModeAuto replays if the cassette exists and records otherwise.
+	ModeAuto
+)
+
+// String returns the mode name.
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Matcher reports whether a live request matches a recorded one. Both are
+// scrubbed before comparison.
+type Matcher func(live, recorded Request) bool
+
+// DefaultMatcher matches on method, URL and body. Bodies are compared
+// ignoring whitespace.
+func DefaultMatcher(live, recorded Request) bool {
+	return live.Method == recorded.Method &&
+		live.URL == recorded.URL &&
+		bytes.Equal(compact(live.Body), compact(recorded.Body))
+}
+
+// compact removes insignificant whitespace from JSON.
+func compact(data []byte) []byte {
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMode sets the recording mode. The default is ModeReplay.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithScrubAttributes redacts the values of these JSON keys wherever they
+// appear, such as entity attributes or config fields. access_token and
+// refresh_token are always redacted.
+func WithScrubAttributes(keys ...string) Option {
+	return func(r *Recorder) {
+		r.scrubKeys = append(r.scrubKeys, keys...)
+	}
+}
+
+// WithScrubber adds a function applied to every recorded body and frame
+// after the built-in scrubbing.
+func WithScrubber(scrub func([]byte) []byte) Option {
+	return func(r *Recorder) {
+		r.scrubbers = append(r.scrubbers, scrub)
+	}
+}
+
+// WithMatcher sets how replayed requests are matched to recorded ones.
+func WithMatcher(match Matcher) Option {
+	return func(r *Recorder) {
+		r.match = match
+	}
+}
+
+// WithTransport sets the transport used to reach Home Assistant while
+// recording. The default is http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithWebSocketDialer sets the dialer used to reach Home Assistant while
+// recording. The default is hago.DefaultWebSocketDialer.
+func WithWebSocketDialer(dialer hago.WebSocketDialer) Option {
+	return func(r *Recorder) {
+		r.dialer = dialer
+	}
+}
+
+// Recorder records or replays Home Assistant traffic. It implements
+// http.RoundTripper for REST requests and hago.WebSocketDialer for
+// WebSocket connections.
+type Recorder struct {
+	path      string
+	mode      Mode
+	scrubKeys []string
+	scrubbers []func([]byte) []byte
+	match     Matcher
+	transport http.RoundTripper
+	dialer    hago.WebSocketDialer
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool // replay: interactions already served
+	sessions int    // replay: WebSocket sessions already served
+	token    string // record: token seen in requests, for scrubbing
+}
+
+// New creates a recorder for the cassette at path.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		scrubKeys: []string{"access_token", "refresh_token"},
+		match:     DefaultMatcher,
+		transport: http.DefaultTransport,
+		dialer:    hago.DefaultWebSocketDialer(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeReplay {
+		c, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+		r.used = make([]bool, len(c.Interactions))
+	} else {
+		r.cassette = &Cassette{}
+	}
+	return r, nil
+}
+
+// Mode returns the effective mode; ModeAuto is resolved when the recorder
+// is created.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// HTTPClient returns an HTTP client that records or replays through r.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// ClientOptions returns the hago options that route a client's REST and
+// WebSocket traffic through r.
+func (r *Recorder) ClientOptions() []hago.Option {
+	return []hago.Option{
+		hago.WithHTTPClient(r.HTTPClient()),
+		hago.WithWebSocketDialer(r),
+	}
+}
+
+// Cassette returns the recorded or loaded cassette.
+func (r *Recorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+// Stop finishes the recording. In record mode it writes the cassette.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+// setToken remembers the token so it can be scrubbed from bodies.
+func (r *Recorder) setToken(token string) {
+	if token == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.token = token
+}
+
+// scrub redacts the token, configured keys and custom patterns from data.
+func (r *Recorder) scrub(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+	if token != "" {
+		data = bytes.ReplaceAll(data, []byte(token), []byte(Redacted))
+	}
+
+	if json.Valid(data) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v any
+		if dec.Decode(&v) == nil {
+			if scrubbed, err := json.Marshal(scrubValue(v, r.scrubKeys)); err == nil {
+				data = scrubbed
+			}
+		}
+	}
+
+	for _, scrub := range r.scrubbers {
+		data = scrub(data)
+	}
+	return data
+}
+
+// scrubValue replaces the values of matching keys in decoded JSON.
+func scrubValue(v any, keys []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if slices.Contains(keys, k) {
+				v[k] = Redacted
+			} else {
+				v[k] = scrubValue(child, keys)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = scrubValue(child, keys)
+		}
+	}
+	return v
+}
+
+// bearerToken extracts the token from an Authorization header.
+func bearerToken(header string) string {
+	token, _ := strings.CutPrefix(header, "Bearer ")
+	return token
+}
@@ -0,0 +1,217 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+	"github.com/rmrfslashbin/hago/hagotest"
+)
+
+// session exercises REST and WebSocket calls and returns what it observed.
+func session(t *testing.T, client *hago.Client) (state string, areas int, event string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := client.SubscribeEvents(ctx, "state_changed")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	if _, err := client.CallService(ctx, "light", "turn_on", &hago.ServiceCallRequest{EntityID: "light.kitchen"}); err != nil {
+		t.Fatalf("CallService() error = %v", err)
+	}
+
+	st, err := client.State(ctx, "light.kitchen")
+	if err != nil {
+		t.Fatalf("State() error = %v", err)
+	}
+
+	registry, err := client.AreaRegistry(ctx)
+	if err != nil {
+		t.Fatalf("AreaRegistry() error = %v", err)
+	}
+
+	select {
+	case e := <-events:
+		data, _ := e.StateChanged()
+		event = data.EntityID + "=" + data.NewState.State
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for event")
+	}
+
+	return st.State, len(registry), event
+}
+
+func newClient(t *testing.T, url, token string, rec *Recorder) *hago.Client {
+	t.Helper()
+	client, err := hago.New(append([]hago.Option{
+		hago.WithBaseURL(url),
+		hago.WithToken(token),
+	}, rec.ClientOptions()...)...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(client.CloseWebSocket)
+	return client
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record against the fake server
+	ha := hagotest.Start(t,
+		hagotest.WithToken("super-secret-token"),
+		hagotest.WithState("light.kitchen", "off", map[string]any{"gps": "51.5,-0.1"}),
+	)
+	ha.SetAreaRegistry([]hago.AreaRegistryEntry{{AreaID: "kitchen", Name: "Kitchen"}})
+
+	rec, err := New(path, WithMode(ModeRecord), WithScrubAttributes("gps"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := newClient(t, ha.URL(), ha.Token(), rec)
+	wantState, wantAreas, wantEvent := session(t, client)
+	client.CloseWebSocket()
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	ha.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "super-secret-token") {
+		t.Error("cassette contains the access token")
+	}
+	if strings.Contains(string(data), "51.5,-0.1") {
+		t.Error("cassette contains a scrubbed attribute")
+	}
+
+	// Replay with the server gone
+	rec, err = New(path)
+	if err != nil {
+		t.Fatalf("New() replay error = %v", err)
+	}
+	if rec.Mode() != ModeReplay {
+		t.Errorf("Mode() = %v, want replay", rec.Mode())
+	}
+	client = newClient(t, "http://homeassistant.invalid:8123", "replay", rec)
+	gotState, gotAreas, gotEvent := session(t, client)
+
+	if gotState != wantState || gotAreas != wantAreas || gotEvent != wantEvent {
+		t.Errorf("replay = (%s, %d, %s), want (%s, %d, %s)",
+			gotState, gotAreas, gotEvent, wantState, wantAreas, wantEvent)
+	}
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := (&Cassette{}).Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	rec, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := newClient(t, "http://homeassistant.invalid:8123", "replay", rec)
+
+	_, err = client.Status(context.Background())
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+	if _, err := client.AreaRegistry(context.Background()); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction from WebSocket, got %v", err)
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing cassette in replay mode")
+	}
+}
+
+func TestModeAuto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auto.json")
+
+	rec, err := New(path, WithMode(ModeAuto))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if rec.Mode() != ModeRecord {
+		t.Fatalf("Mode() = %v, want record without a cassette", rec.Mode())
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	rec, err = New(path, WithMode(ModeAuto))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if rec.Mode() != ModeReplay {
+		t.Errorf("Mode() = %v, want replay with a cassette", rec.Mode())
+	}
+}
+
+func TestRoundTripKeepsCallerRequest(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	rec, err := New(filepath.Join(t.TempDir(), "body.json"), WithMode(ModeRecord))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/services/light/turn_on", strings.NewReader(`{"entity_id":"light.kitchen"}`))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	body := req.Body
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if req.Body != body {
+		t.Error("RoundTrip() replaced the caller's request body")
+	}
+	if got != `{"entity_id":"light.kitchen"}` {
+		t.Errorf("server got body %q", got)
+	}
+	if resp.Request == req {
+		t.Error("RoundTrip() sent the caller's request instead of a clone")
+	}
+}
+
+func TestScrubber(t *testing.T) {
+	rec := &Recorder{scrubKeys: []string{"secret"}}
+	rec.scrubbers = append(rec.scrubbers, func(b []byte) []byte {
+		return []byte(strings.ReplaceAll(string(b), "home.example.com", "example.invalid"))
+	})
+	rec.setToken("abc123")
+
+	got := string(rec.scrub([]byte(`{"url":"https://home.example.com","nested":{"secret":42},"note":"token abc123"}`)))
+	for _, leaked := range []string{"home.example.com", "42", "abc123"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("scrubbed output %s still contains %q", got, leaked)
+		}
+	}
+}
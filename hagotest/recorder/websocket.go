@@ -0,0 +1,273 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// errReplayClosed is returned by reads on a closed replay connection.
+var errReplayClosed = errors.New("recorder: websocket closed")
+
+// DialWebSocket implements hago.WebSocketDialer. In record mode it dials Home
+// Assistant and records every frame; in replay mode it serves the next
+// recorded session.
+func (r *Recorder) DialWebSocket(ctx context.Context, url string, header http.Header) (hago.WebSocketConn, error) {
+	if r.mode == ModeReplay {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.sessions >= len(r.cassette.Sessions) {
+			return nil, fmt.Errorf("%w: no WebSocket session left", ErrNoInteraction)
+		}
+		session := r.cassette.Sessions[r.sessions]
+		r.sessions++
+		return newReplayConn(r, session.Frames), nil
+	}
+
+	conn, err := r.dialer.DialWebSocket(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cassette.Sessions = append(r.cassette.Sessions, Session{})
+	index := len(r.cassette.Sessions) - 1
+	r.mu.Unlock()
+
+	return &recordingConn{r: r, conn: conn, session: index}, nil
+}
+
+// recordingConn records frames passing through a live connection.
+type recordingConn struct {
+	r       *Recorder
+	conn    hago.WebSocketConn
+	session int
+}
+
+// ReadJSON implements hago.WebSocketConn.
+func (c *recordingConn) ReadJSON(v any) error {
+	var raw json.RawMessage
+	if err := c.conn.ReadJSON(&raw); err != nil {
+		return err
+	}
+	c.record(DirRecv, raw)
+	return json.Unmarshal(raw, v)
+}
+
+// WriteJSON implements hago.WebSocketConn.
+func (c *recordingConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var msg struct {
+		Type        string `json:"type"`
+		AccessToken string `json:"access_token"`
+	}
+	if json.Unmarshal(data, &msg) == nil && msg.Type == "auth" {
+		c.r.setToken(msg.AccessToken)
+	}
+
+	c.record(DirSend, data)
+	return c.conn.WriteJSON(json.RawMessage(data))
+}
+
+// Close implements hago.WebSocketConn.
+func (c *recordingConn) Close() error {
+	return c.conn.Close()
+}
+
+// record appends a scrubbed frame to the session.
+func (c *recordingConn) record(dir string, data []byte) {
+	data = c.r.scrub(data)
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	s := &c.r.cassette.Sessions[c.session]
+	s.Frames = append(s.Frames, Frame{Dir: dir, Message: data})
+}
+
+// replayConn serves a recorded session. Each message the client sends is
+// matched to a recorded send frame, and the received frames that followed it
+// are delivered with their message IDs rewritten to the client's IDs.
+type replayConn struct {
+	r      *Recorder
+	frames []Frame
+
+	mu        sync.Mutex
+	used      []bool
+	ids       map[int64]int64 // recorded message ID to live message ID
+	pending   [][]byte
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newReplayConn creates a replay connection and queues the frames the server
+// sent before the client's first message, such as auth_required.
+func newReplayConn(r *Recorder, frames []Frame) *replayConn {
+	c := &replayConn{
+		r:      r,
+		frames: frames,
+		used:   make([]bool, len(frames)),
+		ids:    make(map[int64]int64),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	c.mu.Lock()
+	c.deliverFrom(0)
+	c.mu.Unlock()
+	return c
+}
+
+// ReadJSON implements hago.WebSocketConn.
+func (c *replayConn) ReadJSON(v any) error {
+	for {
+		c.mu.Lock()
+		if len(c.pending) > 0 {
+			data := c.pending[0]
+			c.pending = c.pending[1:]
+			c.mu.Unlock()
+			return json.Unmarshal(data, v)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.notify:
+		case <-c.done:
+			return errReplayClosed
+		}
+	}
+}
+
+// WriteJSON implements hago.WebSocketConn.
+func (c *replayConn) WriteJSON(v any) error {
+	select {
+	case <-c.done:
+		return errReplayClosed
+	default:
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	live := decodeFrame(c.r.scrub(data))
+	liveID, hasID := messageID(live)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findSend(live)
+	if i < 0 {
+		if hasID {
+			c.push(fmt.Appendf(nil,
+				`{"id":%d,"type":"result","success":false,"error":{"code":"unknown_error","message":"recorder: no recorded frame for %s"}}`,
+				liveID, live["type"]))
+		}
+		return nil
+	}
+
+	c.used[i] = true
+	if recordedID, ok := messageID(decodeFrame(c.frames[i].Message)); ok && hasID {
+		c.ids[recordedID] = liveID
+	}
+	c.deliverFrom(i + 1)
+	return nil
+}
+
+// Close implements hago.WebSocketConn.
+func (c *replayConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+// findSend returns the first unused send frame equal to the live message
+// apart from its ID, falling back to the first with the same type.
+func (c *replayConn) findSend(live map[string]any) int {
+	liveBody := withoutID(live)
+
+	fallback := -1
+	for i, f := range c.frames {
+		if c.used[i] || f.Dir != DirSend {
+			continue
+		}
+		recorded := decodeFrame(f.Message)
+		if reflect.DeepEqual(withoutID(recorded), liveBody) {
+			return i
+		}
+		if fallback < 0 && recorded["type"] == live["type"] {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// deliverFrom queues the unused receive frames starting at index i, up to
+// the next send frame. Callers hold c.mu.
+func (c *replayConn) deliverFrom(i int) {
+	for ; i < len(c.frames) && c.frames[i].Dir == DirRecv; i++ {
+		if c.used[i] {
+			continue
+		}
+		c.used[i] = true
+
+		msg := decodeFrame(c.frames[i].Message)
+		if id, ok := messageID(msg); ok {
+			if liveID, ok := c.ids[id]; ok {
+				msg["id"] = liveID
+			}
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		c.push(data)
+	}
+}
+
+// push queues a message for ReadJSON. Callers hold c.mu.
+func (c *replayConn) push(data []byte) {
+	c.pending = append(c.pending, data)
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// decodeFrame decodes a frame into a map, preserving number precision.
+func decodeFrame(data []byte) map[string]any {
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.Decode(&m)
+	return m
+}
+
+// messageID returns the "id" field of a message.
+func messageID(msg map[string]any) (int64, bool) {
+	switch id := msg["id"].(type) {
+	case json.Number:
+		n, err := id.Int64()
+		return n, err == nil
+	case int64:
+		return id, true
+	default:
+		return 0, false
+	}
+}
+
+// withoutID returns a copy of msg without its "id" field.
+func withoutID(msg map[string]any) map[string]any {
+	m := maps.Clone(msg)
+	delete(m, "id")
+	return m
+}
@@ -0,0 +1,301 @@
+package hagotest
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// routes returns the server's HTTP handler.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, hago.StatusResponse{Message: "API running."})
+	})
+	mux.HandleFunc("GET /api/config", s.handleConfig)
+	mux.HandleFunc("GET /api/components", s.handleComponents)
+	mux.HandleFunc("GET /api/states", s.handleStates)
+	mux.HandleFunc("GET /api/states/{entity_id}", s.handleGetState)
+	mux.HandleFunc("POST /api/states/{entity_id}", s.handleSetState)
+	mux.HandleFunc("DELETE /api/states/{entity_id}", s.handleDeleteState)
+	mux.HandleFunc("GET /api/services", s.handleServices)
+	mux.HandleFunc("POST /api/services/{domain}/{service}", s.handleCallService)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.HandleFunc("POST /api/events/{event_type}", s.handleFireEvent)
+	mux.HandleFunc("POST /api/template", s.handleTemplate)
+	mux.HandleFunc("GET /api/history/period/{timestamp}", s.handleHistory)
+	mux.HandleFunc("GET /api/logbook/{timestamp}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []any{})
+	})
+	mux.HandleFunc("GET /api/error_log", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(nil)
+	})
+	mux.HandleFunc("POST /api/config/core/check_config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"result": "valid", "errors": nil})
+	})
+	s.configRoutes(mux, "automation", func() map[string]map[string]any { return s.automations })
+	s.configRoutes(mux, "script", func() map[string]map[string]any { return s.scripts })
+	mux.HandleFunc("GET /api/websocket", s.handleWebSocket)
+
+	return s.middleware(mux)
+}
+
+// middleware applies faults and latency, and checks the bearer token.
+// The WebSocket endpoint authenticates in-band instead.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.applyFault(w, r) {
+			return
+		}
+		s.sleep()
+
+		if r.URL.Path != "/api/websocket" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if _, ok := s.authorize(token, r.RemoteAddr); !ok {
+				http.Error(w, "401: Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.config)
+}
+
+func (s *Server) handleComponents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.config.Components)
+}
+
+func (s *Server) handleStates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.States())
+}
+
+func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
+	state := s.State(r.PathValue("entity_id"))
+	if state == nil {
+		writeMessage(w, http.StatusNotFound, "Entity not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) handleSetState(w http.ResponseWriter, r *http.Request) {
+	var update hago.StateUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Invalid JSON specified.")
+		return
+	}
+	if update.State == "" {
+		writeMessage(w, http.StatusBadRequest, "No state specified.")
+		return
+	}
+
+	entityID := r.PathValue("entity_id")
+	status := http.StatusOK
+	if s.State(entityID) == nil {
+		status = http.StatusCreated
+	}
+	state := s.SetState(entityID, update.State, update.Attributes)
+	writeJSON(w, status, state)
+}
+
+func (s *Server) handleDeleteState(w http.ResponseWriter, r *http.Request) {
+	if !s.RemoveState(r.PathValue("entity_id")) {
+		writeMessage(w, http.StatusNotFound, "Entity not found.")
+		return
+	}
+	writeMessage(w, http.StatusOK, "Entity removed.")
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.serviceList())
+}
+
+// serviceList returns the registered services sorted by domain.
+func (s *Server) serviceList() []hago.Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]hago.Service, 0, len(s.services))
+	for _, domain := range slices.Sorted(maps.Keys(s.services)) {
+		result = append(result, hago.Service{Domain: domain, Services: maps.Clone(s.services[domain])})
+	}
+	return result
+}
+
+func (s *Server) handleCallService(w http.ResponseWriter, r *http.Request) {
+	var data map[string]any
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Data should be valid JSON.")
+			return
+		}
+	}
+
+	returnResponse := r.URL.Query().Has("return_response")
+	result, err := s.callService(r.PathValue("domain"), r.PathValue("service"), data, returnResponse)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+	changed := result.changed
+	if changed == nil {
+		changed = []hago.State{}
+	}
+	if returnResponse {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"changed_states":   changed,
+			"service_response": result.response,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, changed)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	counts := make(map[string]int)
+	for _, conn := range s.connections() {
+		for _, eventType := range conn.eventTypes() {
+			counts[eventType]++
+		}
+	}
+
+	result := make([]hago.Event, 0, len(counts))
+	for _, eventType := range slices.Sorted(maps.Keys(counts)) {
+		result = append(result, hago.Event{Event: eventType, ListenerCount: counts[eventType]})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleFireEvent(w http.ResponseWriter, r *http.Request) {
+	var data hago.EventData
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Event data should be valid JSON.")
+			return
+		}
+	}
+
+	eventType := r.PathValue("event_type")
+	s.FireEvent(eventType, data)
+	writeMessage(w, http.StatusOK, "Event %s fired.", eventType)
+}
+
+func (s *Server) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Template string `json:"template"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Invalid JSON specified.")
+		return
+	}
+
+	s.mu.Lock()
+	render := s.render
+	s.mu.Unlock()
+
+	result, err := render(req.Template)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "Error rendering template: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(result))
+}
+
+// handleHistory returns the current state of the filtered entities as their
+// only history entry.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	var filter []string
+	if f := r.URL.Query().Get("filter_entity_id"); f != "" {
+		filter = strings.Split(f, ",")
+	}
+
+	result := [][]hago.HistoryEntry{}
+	for _, st := range s.States() {
+		if filter != nil && !slices.Contains(filter, st.EntityID) {
+			continue
+		}
+		result = append(result, []hago.HistoryEntry{{
+			EntityID:    st.EntityID,
+			State:       st.State,
+			Attributes:  st.Attributes,
+			LastChanged: st.LastChanged,
+			LastUpdated: st.LastUpdated,
+		}})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// configRoutes registers the automation or script config endpoints backed by
+// the map returned by store. store is called with s.mu held.
+func (s *Server) configRoutes(mux *http.ServeMux, kind string, store func() map[string]map[string]any) {
+	base := "/api/config/" + kind + "/config"
+
+	mux.HandleFunc("GET "+base, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		configs := store()
+		result := make([]map[string]any, 0, len(configs))
+		for _, id := range slices.Sorted(maps.Keys(configs)) {
+			config := maps.Clone(configs[id])
+			config["id"] = id
+			result = append(result, config)
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("GET "+base+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := r.PathValue("id")
+		config, ok := store()[id]
+		if !ok {
+			writeMessage(w, http.StatusNotFound, "Resource not found")
+			return
+		}
+		config = maps.Clone(config)
+		config["id"] = id
+		writeJSON(w, http.StatusOK, config)
+	})
+
+	mux.HandleFunc("POST "+base+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var config map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Invalid JSON specified.")
+			return
+		}
+		if _, ok := config["id"]; ok {
+			writeMessage(w, http.StatusBadRequest, "Message malformed: extra keys not allowed @ data['id']")
+			return
+		}
+
+		s.mu.Lock()
+		store()[r.PathValue("id")] = config
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"result": "ok"})
+	})
+
+	mux.HandleFunc("DELETE "+base+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		configs := store()
+		id := r.PathValue("id")
+		if _, ok := configs[id]; !ok {
+			writeMessage(w, http.StatusNotFound, "Resource not found")
+			return
+		}
+		delete(configs, id)
+		writeJSON(w, http.StatusOK, map[string]string{"result": "ok"})
+	})
+}
@@ -0,0 +1,620 @@
+package hagotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// DefaultToken is the access token accepted by a Server unless WithToken is used.
+const DefaultToken = "hagotest-token"
+
+// Version is the Home Assistant version reported by the fake.
+const Version = "2025.1.0"
+
+// ServiceCall is a recorded service call.
+type ServiceCall struct {
+	Domain  string
+	Service string
+	// Data is the full service data, including entity_id.
+	Data map[string]any
+	// EntityIDs lists the targeted entities from entity_id.
+	EntityIDs []string
+	// ReturnResponse reports whether the caller asked for response data.
+	ReturnResponse bool
+	Context        hago.Context
+	Time           time.Time
+}
+
+// ServiceHandler handles a service call. Returning an error fails the call
+// with a 400 response (or an error result over WebSocket).
+type ServiceHandler func(call ServiceCall) error
+
+// ServiceResponseHandler handles a service call that returns response data,
+// such as weather.get_forecasts.
+type ServiceResponseHandler func(call ServiceCall) (map[string]any, error)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithToken sets the access token the server accepts.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithState adds an initial entity state.
+func WithState(entityID, state string, attributes map[string]any) Option {
+	return func(s *Server) {
+		s.setState(entityID, state, attributes, false)
+	}
+}
+
+// WithStates adds initial entity states.
+func WithStates(states ...hago.State) Option {
+	return func(s *Server) {
+		for _, st := range states {
+			s.setState(st.EntityID, st.State, st.Attributes, false)
+		}
+	}
+}
+
+// WithConfig sets the configuration returned by /api/config.
+func WithConfig(config hago.Config) Option {
+	return func(s *Server) {
+		s.config = config
+	}
+}
+
+// stateEntry is a stored state and the sequence number of its last update.
+type stateEntry struct {
+	state hago.State
+	seq   uint64
+}
+
+// Server is a fake Home Assistant server. It is safe for concurrent use.
+type Server struct {
+	srv   *httptest.Server
+	token string
+
+	mu          sync.Mutex
+	states      map[string]*stateEntry
+	seq         uint64
+	calls       []ServiceCall
+	events      []hago.EventMessage
+	handlers    map[string]ServiceHandler
+	responders  map[string]ServiceResponseHandler
+	services    map[string]map[string]hago.ServiceDetails
+	config      hago.Config
+	render      func(template string) (string, error)
+	entities    []hago.EntityRegistryEntry
+	devices     []hago.DeviceRegistryEntry
+	areas       []hago.AreaRegistryEntry
+	labels      []hago.LabelRegistryEntry
+	floors      []hago.FloorRegistryEntry
+	dashboards  []hago.Dashboard
+	lovelace    map[string]json.RawMessage
+	resources   []hago.Resource
+	automations map[string]map[string]any
+	scripts     map[string]map[string]any
+	faults      []*Fault
+	latency     time.Duration
+
+	user          hago.CurrentUser
+	refreshTokens []hago.RefreshToken
+	accessTokens  map[string]string // access token to refresh token ID
+	tokenSeq      int
+	users         []hago.User
+	passwords     map[string]string // username to password
+	userSeq       int
+	persons       []hago.Person
+
+	connMu sync.Mutex
+	conns  map[*wsConn]struct{}
+}
+
+// NewServer starts a fake Home Assistant server. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		token:       DefaultToken,
+		states:      make(map[string]*stateEntry),
+		handlers:    make(map[string]ServiceHandler),
+		responders:  make(map[string]ServiceResponseHandler),
+		services:    make(map[string]map[string]hago.ServiceDetails),
+		lovelace:    make(map[string]json.RawMessage),
+		automations: make(map[string]map[string]any),
+		scripts:     make(map[string]map[string]any),
+		conns:       make(map[*wsConn]struct{}),
+		config: hago.Config{
+			LocationName: "Home",
+			TimeZone:     "UTC",
+			Version:      Version,
+			State:        "RUNNING",
+			Components:   []string{"api", "websocket_api"},
+		},
+		render: func(template string) (string, error) { return template, nil },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.initAuth()
+	s.initUsers()
+
+	s.srv = httptest.NewServer(s.routes())
+	return s
+}
+
+// Start starts a fake Home Assistant server that is closed when the test ends.
+func Start(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	s := NewServer(opts...)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Token returns the access token the server accepts.
+func (s *Server) Token() string {
+	return s.token
+}
+
+// Close drops all WebSocket connections and shuts down the server.
+func (s *Server) Close() {
+	s.DropWebSockets()
+	s.srv.Close()
+}
+
+// Client returns a hago.Client connected to the server. Extra options are
+// applied after the base URL and token. The client's WebSocket connection is
+// closed when the test ends.
+func (s *Server) Client(t testing.TB, opts ...hago.Option) *hago.Client {
+	t.Helper()
+	opts = append([]hago.Option{hago.WithBaseURL(s.URL()), hago.WithToken(s.token)}, opts...)
+	client, err := hago.New(opts...)
+	if err != nil {
+		t.Fatalf("hagotest: create client: %v", err)
+	}
+	t.Cleanup(client.CloseWebSocket)
+	return client
+}
+
+// State returns the current state of an entity, or nil if it does not exist.
+func (s *Server) State(entityID string) *hago.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.states[entityID]
+	if !ok {
+		return nil
+	}
+	st := e.state
+	return &st
+}
+
+// States returns all current states sorted by entity ID.
+func (s *Server) States() []hago.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedStates()
+}
+
+// SetState sets an entity's state and fires state_changed. Attributes
+// replace the existing attributes.
+func (s *Server) SetState(entityID, state string, attributes map[string]any) hago.State {
+	return s.setState(entityID, state, attributes, true)
+}
+
+// RemoveState removes an entity and fires state_changed with no new state.
+// It reports whether the entity existed.
+func (s *Server) RemoveState(entityID string) bool {
+	s.mu.Lock()
+	e, ok := s.states[entityID]
+	if ok {
+		delete(s.states, entityID)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		old := e.state
+		s.publishStateChange(entityID, &old, nil)
+	}
+	return ok
+}
+
+// setState stores a state, optionally publishing the change.
+func (s *Server) setState(entityID, state string, attributes map[string]any, publish bool) hago.State {
+	now := time.Now().UTC()
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	s.mu.Lock()
+	s.seq++
+	prev, existed := s.states[entityID]
+	next := hago.State{
+		EntityID:    entityID,
+		State:       state,
+		Attributes:  maps.Clone(attributes),
+		LastChanged: now,
+		LastUpdated: now,
+		Context:     newContext(),
+	}
+	if existed && prev.state.State == state {
+		next.LastChanged = prev.state.LastChanged
+	}
+	s.states[entityID] = &stateEntry{state: next, seq: s.seq}
+	s.mu.Unlock()
+
+	if publish {
+		var old *hago.State
+		if existed {
+			o := prev.state
+			old = &o
+		}
+		s.publishStateChange(entityID, old, &next)
+	}
+	return next
+}
+
+// sortedStates returns all states sorted by entity ID. Callers hold s.mu.
+func (s *Server) sortedStates() []hago.State {
+	result := make([]hago.State, 0, len(s.states))
+	for _, id := range slices.Sorted(maps.Keys(s.states)) {
+		result = append(result, s.states[id].state)
+	}
+	return result
+}
+
+// HandleService registers a handler for a service, replacing the default
+// behavior. The service is also listed by /api/services.
+func (s *Server) HandleService(domain, service string, handler ServiceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[domain+"."+service] = handler
+	delete(s.responders, domain+"."+service)
+	s.addService(domain, service)
+}
+
+// HandleServiceResponse registers a handler for a service that returns
+// response data. Calls that do not ask for the response still run the
+// handler; calls to services without a response handler that do ask fail,
+// as they do in Home Assistant.
+func (s *Server) HandleServiceResponse(domain, service string, handler ServiceResponseHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responders[domain+"."+service] = handler
+	delete(s.handlers, domain+"."+service)
+	s.addService(domain, service)
+}
+
+// addService lists a service in /api/services if it is not already. Callers
+// hold s.mu.
+func (s *Server) addService(domain, service string) {
+	if s.services[domain] == nil {
+		s.services[domain] = make(map[string]hago.ServiceDetails)
+	}
+	if _, ok := s.services[domain][service]; !ok {
+		s.services[domain][service] = hago.ServiceDetails{}
+	}
+}
+
+// SetServices sets the service descriptions returned by /api/services.
+func (s *Server) SetServices(services []hago.Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = make(map[string]map[string]hago.ServiceDetails)
+	for _, svc := range services {
+		s.services[svc.Domain] = maps.Clone(svc.Services)
+	}
+}
+
+// Calls returns all recorded service calls in order.
+func (s *Server) Calls() []ServiceCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.calls)
+}
+
+// CallsTo returns the recorded calls to one service.
+func (s *Server) CallsTo(domain, service string) []ServiceCall {
+	var result []ServiceCall
+	for _, call := range s.Calls() {
+		if call.Domain == domain && call.Service == service {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// FiredEvents returns all events fired on the bus, including state_changed
+// and call_service, in order.
+func (s *Server) FiredEvents() []hago.EventMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Reset clears recorded service calls and events.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+	s.events = nil
+}
+
+// FireEvent fires an event on the bus, delivering it to WebSocket subscribers.
+func (s *Server) FireEvent(eventType string, data hago.EventData) hago.EventMessage {
+	if data == nil {
+		data = hago.EventData{}
+	}
+	event := hago.EventMessage{
+		EventType: eventType,
+		Data:      data,
+		Origin:    "LOCAL",
+		TimeFired: time.Now().UTC(),
+		Context:   newContext(),
+	}
+	s.publish(event)
+	return event
+}
+
+// SetTemplateRenderer sets the function used to render templates. The
+// default returns the template unchanged.
+func (s *Server) SetTemplateRenderer(render func(template string) (string, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.render = render
+}
+
+// SetEntityRegistry sets the entity registry.
+func (s *Server) SetEntityRegistry(entries []hago.EntityRegistryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities = slices.Clone(entries)
+}
+
+// SetDeviceRegistry sets the device registry.
+func (s *Server) SetDeviceRegistry(entries []hago.DeviceRegistryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices = slices.Clone(entries)
+}
+
+// SetAreaRegistry sets the area registry.
+func (s *Server) SetAreaRegistry(entries []hago.AreaRegistryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.areas = slices.Clone(entries)
+}
+
+// SetLabelRegistry sets the label registry.
+func (s *Server) SetLabelRegistry(entries []hago.LabelRegistryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labels = slices.Clone(entries)
+}
+
+// SetFloorRegistry sets the floor registry.
+func (s *Server) SetFloorRegistry(entries []hago.FloorRegistryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.floors = slices.Clone(entries)
+}
+
+// SetLovelaceResources sets the Lovelace resources.
+func (s *Server) SetLovelaceResources(resources []hago.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = slices.Clone(resources)
+}
+
+// LovelaceConfig returns the stored config for a dashboard ("" for the
+// default dashboard), or nil if none has been saved.
+func (s *Server) LovelaceConfig(urlPath string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lovelace[urlPath]
+}
+
+// AutomationConfig returns the stored config for an automation, or nil.
+func (s *Server) AutomationConfig(id string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.automations[id])
+}
+
+// ScriptConfig returns the stored config for a script, or nil.
+func (s *Server) ScriptConfig(id string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.scripts[id])
+}
+
+// serviceResult is the outcome of a call handled by the fake.
+type serviceResult struct {
+	changed  []hago.State
+	context  hago.Context
+	response map[string]any
+}
+
+// callService records a service call, runs its handler and fires
+// call_service. It returns the states changed by the call and, when
+// returnResponse is set, the service response.
+func (s *Server) callService(domain, service string, data map[string]any, returnResponse bool) (serviceResult, error) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	call := ServiceCall{
+		Domain:         domain,
+		Service:        service,
+		Data:           data,
+		EntityIDs:      entityIDs(data["entity_id"]),
+		ReturnResponse: returnResponse,
+		Context:        newContext(),
+		Time:           time.Now().UTC(),
+	}
+	result := serviceResult{context: call.Context}
+
+	s.mu.Lock()
+	handler := s.handlers[domain+"."+service]
+	responder := s.responders[domain+"."+service]
+	if returnResponse && responder == nil {
+		s.mu.Unlock()
+		return result, errors.New("Service does not support responses. Remove return_response from request.")
+	}
+	s.calls = append(s.calls, call)
+	before := s.seq
+	s.mu.Unlock()
+
+	s.publish(hago.EventMessage{
+		EventType: "call_service",
+		Data: hago.EventData{
+			"domain":       domain,
+			"service":      service,
+			"service_data": data,
+		},
+		Origin:    "LOCAL",
+		TimeFired: call.Time,
+		Context:   call.Context,
+	})
+
+	var err error
+	switch {
+	case responder != nil:
+		var response map[string]any
+		response, err = responder(call)
+		if returnResponse {
+			result.response = response
+		}
+	case handler != nil:
+		err = handler(call)
+	default:
+		s.defaultService(call)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.sortedStates() {
+		if s.states[st.EntityID].seq > before {
+			result.changed = append(result.changed, st)
+		}
+	}
+	return result, nil
+}
+
+// defaultService implements turn_on, turn_off and toggle for existing entities.
+func (s *Server) defaultService(call ServiceCall) {
+	for _, id := range call.EntityIDs {
+		current := s.State(id)
+		if current == nil {
+			continue
+		}
+
+		var next string
+		switch call.Service {
+		case "turn_on":
+			next = "on"
+		case "turn_off":
+			next = "off"
+		case "toggle":
+			next = "on"
+			if current.State == "on" {
+				next = "off"
+			}
+		default:
+			return
+		}
+		s.SetState(id, next, current.Attributes)
+	}
+}
+
+// publish records an event and delivers it to WebSocket subscribers.
+func (s *Server) publish(event hago.EventMessage) {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+
+	for _, conn := range s.connections() {
+		conn.sendEvent(event)
+	}
+}
+
+// publishStateChange fires state_changed and updates entity subscriptions.
+func (s *Server) publishStateChange(entityID string, oldState, newState *hago.State) {
+	s.publish(hago.EventMessage{
+		EventType: "state_changed",
+		Data: hago.EventData{
+			"entity_id": entityID,
+			"old_state": oldState,
+			"new_state": newState,
+		},
+		Origin:    "LOCAL",
+		TimeFired: time.Now().UTC(),
+		Context:   newContext(),
+	})
+
+	for _, conn := range s.connections() {
+		conn.sendEntityChange(entityID, newState)
+	}
+}
+
+// entityIDs normalizes an entity_id value that may be a string, a
+// comma-separated string or a list.
+func entityIDs(v any) []string {
+	var ids []string
+	switch v := v.(type) {
+	case string:
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	case []any:
+		for _, id := range v {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	case []string:
+		ids = append(ids, v...)
+	}
+	return ids
+}
+
+// newContext returns a context with a random ID.
+func newContext() hago.Context {
+	b := make([]byte, 13)
+	rand.Read(b)
+	return hago.Context{ID: strings.ToUpper(hex.EncodeToString(b))}
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeMessage writes a {"message": ...} response as HA does for errors.
+func writeMessage(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"message": fmt.Sprintf(format, args...)})
+}
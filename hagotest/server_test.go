@@ -0,0 +1,577 @@
+package hagotest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+func TestServer_States(t *testing.T) {
+	ha := Start(t, WithState("light.kitchen", "off", map[string]any{"brightness": 0}))
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	state, err := client.State(ctx, "light.kitchen")
+	if err != nil {
+		t.Fatalf("State() error = %v", err)
+	}
+	if state.State != "off" {
+		t.Errorf("State = %v, want off", state.State)
+	}
+
+	if _, err := client.SetState(ctx, "sensor.temp", &hago.StateUpdate{State: "21.5"}); err != nil {
+		t.Fatalf("SetState() error = %v", err)
+	}
+	if got := ha.State("sensor.temp"); got == nil || got.State != "21.5" {
+		t.Errorf("server state = %+v, want 21.5", got)
+	}
+
+	states, err := client.States(ctx)
+	if err != nil {
+		t.Fatalf("States() error = %v", err)
+	}
+	if len(states) != 2 {
+		t.Errorf("len(States()) = %d, want 2", len(states))
+	}
+
+	if err := client.DeleteState(ctx, "sensor.temp"); err != nil {
+		t.Fatalf("DeleteState() error = %v", err)
+	}
+	if _, err := client.State(ctx, "sensor.temp"); !errors.Is(err, hago.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServer_LastChanged(t *testing.T) {
+	ha := Start(t)
+	first := ha.SetState("sensor.temp", "20", nil)
+	time.Sleep(time.Millisecond)
+	second := ha.SetState("sensor.temp", "20", map[string]any{"unit": "C"})
+
+	if !second.LastChanged.Equal(first.LastChanged) {
+		t.Error("LastChanged moved without a state change")
+	}
+	if !second.LastUpdated.After(first.LastUpdated) {
+		t.Error("LastUpdated did not move")
+	}
+}
+
+func TestServer_CallService(t *testing.T) {
+	ha := Start(t, WithState("light.kitchen", "off", nil), WithState("light.hall", "on", nil))
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	changed, err := client.CallService(ctx, "light", "toggle", &hago.ServiceCallRequest{
+		EntityID: "light.kitchen,light.hall",
+		Data:     map[string]any{"transition": 2},
+	})
+	if err != nil {
+		t.Fatalf("CallService() error = %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("len(changed) = %d, want 2", len(changed))
+	}
+	if ha.State("light.kitchen").State != "on" || ha.State("light.hall").State != "off" {
+		t.Error("toggle did not flip states")
+	}
+
+	calls := ha.CallsTo("light", "toggle")
+	if len(calls) != 1 {
+		t.Fatalf("len(calls) = %d, want 1", len(calls))
+	}
+	if len(calls[0].EntityIDs) != 2 || calls[0].Data["transition"] != float64(2) {
+		t.Errorf("unexpected call: %+v", calls[0])
+	}
+}
+
+func TestServer_HandleService(t *testing.T) {
+	ha := Start(t)
+	ha.HandleService("notify", "mobile", func(call ServiceCall) error {
+		if call.Data["message"] == nil {
+			return errors.New("message is required")
+		}
+		return nil
+	})
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	_, err := client.CallService(ctx, "notify", "mobile", &hago.ServiceCallRequest{})
+	var apiErr *hago.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "message is required" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	services, err := client.Services(ctx)
+	if err != nil {
+		t.Fatalf("Services() error = %v", err)
+	}
+	if len(services) != 1 || services[0].Domain != "notify" {
+		t.Errorf("Services() = %+v", services)
+	}
+}
+
+func TestServer_HandleServiceResponse(t *testing.T) {
+	ha := Start(t)
+	ha.HandleServiceResponse("weather", "get_forecasts", func(call ServiceCall) (map[string]any, error) {
+		return map[string]any{
+			"weather.home": map[string]any{"forecast": []any{map[string]any{"temperature": 21}}},
+		}, nil
+	})
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	result, err := client.CallServiceWithResponse(ctx, "weather", "get_forecasts", &hago.ServiceCallRequest{
+		EntityID: "weather.home",
+		Data:     map[string]any{"type": "daily"},
+	})
+	if err != nil {
+		t.Fatalf("CallServiceWithResponse() error = %v", err)
+	}
+
+	var response map[string]struct {
+		Forecast []map[string]any `json:"forecast"`
+	}
+	if err := result.DecodeResponse(&response); err != nil {
+		t.Fatalf("DecodeResponse() error = %v", err)
+	}
+	if got := response["weather.home"].Forecast; len(got) != 1 || got[0]["temperature"] != float64(21) {
+		t.Errorf("response = %+v", response)
+	}
+	if calls := ha.CallsTo("weather", "get_forecasts"); len(calls) != 1 || !calls[0].ReturnResponse {
+		t.Errorf("calls = %+v", calls)
+	}
+
+	// Services without responses reject return_response.
+	_, err = client.CallServiceWithResponse(ctx, "light", "turn_on", &hago.ServiceCallRequest{})
+	if !errors.Is(err, hago.ErrBadRequest) {
+		t.Errorf("expected ErrBadRequest, got %v", err)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	ha := Start(t, WithState("switch.fan", "off", nil))
+	client := ha.Client(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.SubscribeEvents(ctx, "state_changed")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	if _, err := client.CallService(ctx, "switch", "turn_on", &hago.ServiceCallRequest{EntityID: "switch.fan"}); err != nil {
+		t.Fatalf("CallService() error = %v", err)
+	}
+
+	select {
+	case event := <-events:
+		data, err := event.StateChanged()
+		if err != nil {
+			t.Fatalf("StateChanged() error = %v", err)
+		}
+		if data.EntityID != "switch.fan" || data.OldState.State != "off" || data.NewState.State != "on" {
+			t.Errorf("unexpected change: %+v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for state_changed")
+	}
+
+	if err := client.FireEvent(ctx, "custom_event", hago.EventData{"key": "value"}); err != nil {
+		t.Fatalf("FireEvent() error = %v", err)
+	}
+	fired := ha.FiredEvents()
+	if last := fired[len(fired)-1]; last.EventType != "custom_event" || last.Data["key"] != "value" {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestServer_MirrorStates(t *testing.T) {
+	ha := Start(t, WithState("light.kitchen", "off", nil), WithState("light.hall", "off", nil))
+	client := ha.Client(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mirror, err := client.MirrorStates(ctx)
+	if err != nil {
+		t.Fatalf("MirrorStates() error = %v", err)
+	}
+	if mirror.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", mirror.Len())
+	}
+
+	ha.SetState("light.kitchen", "on", nil)
+	ha.RemoveState("light.hall")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if st, ok := mirror.Get("light.kitchen"); ok && st.State == "on" && mirror.Len() == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("mirror did not converge: %+v", mirror.Snapshot())
+}
+
+func TestServer_Registries(t *testing.T) {
+	ha := Start(t)
+	ha.SetAreaRegistry([]hago.AreaRegistryEntry{{AreaID: "kitchen", Name: "Kitchen"}})
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	areas, err := client.AreaRegistry(ctx)
+	if err != nil {
+		t.Fatalf("AreaRegistry() error = %v", err)
+	}
+	if len(areas) != 1 || areas[0].Name != "Kitchen" {
+		t.Errorf("AreaRegistry() = %+v", areas)
+	}
+
+	entities, err := client.EntityRegistry(ctx)
+	if err != nil {
+		t.Fatalf("EntityRegistry() error = %v", err)
+	}
+	if len(entities) != 0 {
+		t.Errorf("EntityRegistry() = %+v, want empty", entities)
+	}
+}
+
+func TestServer_Select(t *testing.T) {
+	kitchen := "kitchen"
+	ha := Start(t,
+		WithState("light.kitchen", "on", nil),
+		WithState("light.hall", "off", nil),
+		WithState("sensor.kitchen_temperature", "21", map[string]any{"device_class": "temperature"}),
+	)
+	ha.SetEntityRegistry([]hago.EntityRegistryEntry{
+		{EntityID: "light.kitchen", AreaID: &kitchen},
+		{EntityID: "sensor.kitchen_temperature", AreaID: &kitchen},
+	})
+	ha.SetAreaRegistry([]hago.AreaRegistryEntry{{AreaID: kitchen, Name: "Kitchen"}})
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	ids, err := client.SelectEntityIDs(ctx, "area:kitchen !device_class:temperature")
+	if err != nil {
+		t.Fatalf("SelectEntityIDs() error = %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "light.kitchen" {
+		t.Errorf("SelectEntityIDs() = %v, want [light.kitchen]", ids)
+	}
+
+	states, err := client.Select(ctx, "light.*")
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(states) != 2 || states[0].EntityID != "light.hall" {
+		t.Errorf("Select() = %+v, want light.hall and light.kitchen", states)
+	}
+
+	if _, err := client.Select(ctx, "colour:red"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestServer_Lovelace(t *testing.T) {
+	ha := Start(t)
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	if _, err := client.LovelaceGetConfig(ctx, nil, false); err == nil {
+		t.Error("expected error for missing config")
+	}
+
+	dashboard, err := client.LovelaceCreateDashboard(ctx, &hago.CreateDashboardRequest{URLPath: "my-home", Title: "Mine"})
+	if err != nil {
+		t.Fatalf("LovelaceCreateDashboard() error = %v", err)
+	}
+	if dashboard.ID != "my_home" || dashboard.Title != "Mine" {
+		t.Errorf("dashboard = %+v", dashboard)
+	}
+
+	urlPath := "my-home"
+	if err := client.LovelaceSaveConfig(ctx, &urlPath, map[string]any{"title": "Home"}); err != nil {
+		t.Fatalf("LovelaceSaveConfig() error = %v", err)
+	}
+	config, err := client.LovelaceGetConfigParsed(ctx, &urlPath)
+	if err != nil {
+		t.Fatalf("LovelaceGetConfigParsed() error = %v", err)
+	}
+	if config.Title != "Home" {
+		t.Errorf("Title = %v, want Home", config.Title)
+	}
+
+	err = client.LovelaceDeleteDashboard(ctx, "missing")
+	if !errors.Is(err, hago.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServer_AutomationConfig(t *testing.T) {
+	ha := Start(t)
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	err := client.AutomationSave(ctx, &hago.AutomationConfig{
+		ID:      "morning",
+		Alias:   "Morning",
+		Trigger: []any{map[string]any{"trigger": "time", "at": "07:00"}},
+		Action:  []any{map[string]any{"action": "light.turn_on"}},
+	})
+	if err != nil {
+		t.Fatalf("AutomationSave() error = %v", err)
+	}
+	if ha.AutomationConfig("morning")["alias"] != "Morning" {
+		t.Errorf("stored config = %v", ha.AutomationConfig("morning"))
+	}
+
+	config, err := client.AutomationGet(ctx, "morning")
+	if err != nil {
+		t.Fatalf("AutomationGet() error = %v", err)
+	}
+	if config.ID != "morning" || len(config.Trigger) != 1 {
+		t.Errorf("AutomationGet() = %+v", config)
+	}
+
+	if err := client.AutomationDeleteConfig(ctx, "morning"); err != nil {
+		t.Fatalf("AutomationDeleteConfig() error = %v", err)
+	}
+	if _, err := client.AutomationGet(ctx, "morning"); !errors.Is(err, hago.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	ha := Start(t)
+	client := ha.Client(t, hago.WithToken("wrong"))
+	ctx := context.Background()
+
+	if _, err := client.Status(ctx); !errors.Is(err, hago.ErrUnauthorized) {
+		t.Errorf("REST: expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := client.AreaRegistry(ctx); !errors.Is(err, hago.ErrUnauthorized) {
+		t.Errorf("WebSocket: expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestServer_FaultStatus(t *testing.T) {
+	ha := Start(t)
+	ha.InjectFault(Fault{Path: "/api/config", Status: http.StatusServiceUnavailable, Times: 2})
+
+	client := ha.Client(t, hago.WithRetry(hago.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}))
+	ctx := context.Background()
+
+	if _, err := client.Config(ctx); err != nil {
+		t.Fatalf("Config() error = %v, want success on third attempt", err)
+	}
+
+	ha.InjectFault(Fault{Method: http.MethodGet, Status: http.StatusInternalServerError})
+	if _, err := client.Status(ctx); !errors.Is(err, hago.ErrServerError) {
+		t.Errorf("expected ErrServerError, got %v", err)
+	}
+
+	ha.ClearFaults()
+	if _, err := client.Status(ctx); err != nil {
+		t.Errorf("Status() after ClearFaults error = %v", err)
+	}
+}
+
+func TestServer_FaultDrop(t *testing.T) {
+	ha := Start(t)
+	ha.InjectFault(Fault{Path: "/api/states", Drop: true, Times: 1})
+	client := ha.Client(t)
+
+	_, err := client.States(context.Background())
+	var reqErr *hago.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Errorf("expected *RequestError, got %v", err)
+	}
+}
+
+func TestServer_FaultLatency(t *testing.T) {
+	ha := Start(t)
+	ha.SetLatency(100 * time.Millisecond)
+	client := ha.Client(t, hago.WithTimeout(20*time.Millisecond))
+
+	if _, err := client.Status(context.Background()); !errors.Is(err, hago.ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestServer_DropWebSockets(t *testing.T) {
+	ha := Start(t, WithState("light.kitchen", "off", nil))
+
+	states := make(chan hago.ConnectionState, 16)
+	client := ha.Client(t,
+		hago.WithWebSocketReconnect(hago.ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}),
+		hago.WithConnectionStateHandler(func(s hago.ConnectionState) { states <- s }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.SubscribeEvents(ctx, "state_changed")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	waitFor := func(want hago.ConnectionState) {
+		t.Helper()
+		for {
+			select {
+			case s := <-states:
+				if s == want {
+					return
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timed out waiting for %s", want)
+			}
+		}
+	}
+	waitFor(hago.ConnectionConnected)
+
+	ha.DropWebSockets()
+	waitFor(hago.ConnectionReconnecting)
+	waitFor(hago.ConnectionConnected)
+
+	// The subscription was replayed on the new connection
+	ha.SetState("light.kitchen", "on", nil)
+	select {
+	case event := <-events:
+		if data, _ := event.StateChanged(); data == nil || data.EntityID != "light.kitchen" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event after reconnect")
+	}
+}
+
+func TestServer_RefreshTokens(t *testing.T) {
+	ha := Start(t)
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	user, err := client.CurrentUser(ctx)
+	if err != nil {
+		t.Fatalf("CurrentUser() error = %v", err)
+	}
+	if !user.IsAdmin {
+		t.Errorf("user = %+v, want admin", user)
+	}
+
+	token, err := client.CreateLongLivedToken(ctx, "ci", 30)
+	if err != nil {
+		t.Fatalf("CreateLongLivedToken() error = %v", err)
+	}
+	if _, err := client.CreateLongLivedToken(ctx, "ci", 30); err == nil {
+		t.Error("expected error for duplicate token name")
+	}
+
+	// The new token authenticates
+	ci := ha.Client(t, hago.WithToken(token))
+	if _, err := ci.Status(ctx); err != nil {
+		t.Fatalf("Status() with new token error = %v", err)
+	}
+
+	tokens, err := client.RefreshTokens(ctx)
+	if err != nil {
+		t.Fatalf("RefreshTokens() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want 2", len(tokens))
+	}
+	if tokens[0].ID != DefaultRefreshTokenID || !tokens[0].IsCurrent {
+		t.Errorf("tokens[0] = %+v, want current default token", tokens[0])
+	}
+	if tokens[1].Name() != "ci" || tokens[1].IsCurrent || tokens[1].LastUsedAt == nil {
+		t.Errorf("tokens[1] = %+v, want used ci token", tokens[1])
+	}
+
+	if err := client.DeleteRefreshToken(ctx, tokens[1].ID); err != nil {
+		t.Fatalf("DeleteRefreshToken() error = %v", err)
+	}
+	if _, err := ci.Status(ctx); !errors.Is(err, hago.ErrUnauthorized) {
+		t.Errorf("Status() with revoked token: expected ErrUnauthorized, got %v", err)
+	}
+	if err := client.DeleteRefreshToken(ctx, "missing"); err == nil {
+		t.Error("expected error deleting missing token")
+	}
+}
+
+func TestServer_UsersAndPersons(t *testing.T) {
+	ha := Start(t)
+	client := ha.Client(t)
+	ctx := context.Background()
+
+	user, err := client.CreateUser(ctx, &hago.CreateUserRequest{Name: "Contractor", GroupIDs: []string{hago.GroupUsers}})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := client.CreateUserCredentials(ctx, user.ID, "contractor", "s3cret"); err != nil {
+		t.Fatalf("CreateUserCredentials() error = %v", err)
+	}
+	if err := client.CreateUserCredentials(ctx, user.ID, "other", "s3cret"); err == nil {
+		t.Error("expected error for second credentials")
+	}
+	if password, _ := ha.Password("contractor"); password != "s3cret" {
+		t.Errorf("password = %q", password)
+	}
+
+	person, err := client.CreatePerson(ctx, &hago.CreatePersonRequest{
+		Name:           "Contractor",
+		UserID:         user.ID,
+		DeviceTrackers: []string{"device_tracker.contractor_phone"},
+	})
+	if err != nil {
+		t.Fatalf("CreatePerson() error = %v", err)
+	}
+	if _, err := client.CreatePerson(ctx, &hago.CreatePersonRequest{Name: "Again", UserID: user.ID}); err == nil {
+		t.Error("expected error linking a user to two persons")
+	}
+
+	ha.AddPerson(hago.Person{ID: "guest", Name: "Guest", DeviceTrackers: []string{}, YAML: true})
+	persons, err := client.Persons(ctx)
+	if err != nil {
+		t.Fatalf("Persons() error = %v", err)
+	}
+	if len(persons) != 2 || persons[0].ID != "contractor" || !persons[1].YAML {
+		t.Errorf("persons = %+v", persons)
+	}
+
+	// Offboard: deactivate, then delete
+	active := false
+	if user, err = client.UpdateUser(ctx, user.ID, &hago.UpdateUserRequest{IsActive: &active}); err != nil || user.IsActive {
+		t.Fatalf("UpdateUser() = %+v, %v", user, err)
+	}
+	if err := client.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if ps := ha.Persons(); ps[0].UserID != nil {
+		t.Errorf("person %s still linked to deleted user", person.ID)
+	}
+	if len(ha.Users()) != 1 {
+		t.Errorf("users = %+v", ha.Users())
+	}
+
+	me, err := client.CurrentUser(ctx)
+	if err != nil {
+		t.Fatalf("CurrentUser() error = %v", err)
+	}
+	if err := client.DeleteUser(ctx, me.ID); err == nil {
+		t.Error("expected error deleting the current user")
+	}
+	if err := client.ChangePassword(ctx, "wrong", "new"); err == nil {
+		t.Error("expected error for wrong current password")
+	}
+	if err := client.ChangePassword(ctx, DefaultPassword, "new"); err != nil {
+		t.Errorf("ChangePassword() error = %v", err)
+	}
+}
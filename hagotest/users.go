@@ -0,0 +1,289 @@
+package hagotest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// DefaultPassword is the password of the current user, whose username is
+// "hagotest".
+const DefaultPassword = "hagotest-password"
+
+// initUsers sets up the user list with the current user.
+func (s *Server) initUsers() {
+	username := "hagotest"
+	s.users = []hago.User{{
+		ID:          s.user.ID,
+		Username:    &username,
+		Name:        s.user.Name,
+		IsOwner:     s.user.IsOwner,
+		IsActive:    true,
+		GroupIDs:    []string{hago.GroupAdmin},
+		Credentials: []hago.CredentialInfo{{Type: "homeassistant"}},
+	}}
+	s.passwords = map[string]string{username: DefaultPassword}
+}
+
+// Users returns the users listed by config/auth/list.
+func (s *Server) Users() []hago.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.users)
+}
+
+// Password returns the password of a username of the homeassistant auth
+// provider.
+func (s *Server) Password(username string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	password, ok := s.passwords[username]
+	return password, ok
+}
+
+// AddPerson adds a person, e.g. one with YAML set as if configured in
+// configuration.yaml.
+func (s *Server) AddPerson(person hago.Person) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.persons = append(s.persons, person)
+}
+
+// Persons returns the persons listed by person/list.
+func (s *Server) Persons() []hago.Person {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.persons)
+}
+
+// handleUsers handles the config/auth/* and
+// config/auth_provider/homeassistant/* commands. The current user cannot
+// be deleted, and deleting a user unlinks its person.
+func (c *wsConn) handleUsers(msg *wsMessage) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userIndex := func() int {
+		i := slices.IndexFunc(s.users, func(u hago.User) bool { return u.ID == msg.UserID.String() })
+		if i < 0 {
+			c.fail(msg.ID, hago.ErrCodeNotFound, "User not found")
+		}
+		return i
+	}
+
+	switch msg.Type {
+	case "config/auth/list":
+		c.result(msg.ID, s.users)
+
+	case "config/auth/create":
+		if msg.Name == nil {
+			c.fail(msg.ID, hago.ErrCodeInvalidFormat, "required key not provided @ data['name']")
+			return
+		}
+		s.userSeq++
+		user := hago.User{
+			ID:          fmt.Sprintf("user-%d", s.userSeq),
+			Name:        *msg.Name,
+			IsActive:    true,
+			GroupIDs:    msg.GroupIDs,
+			Credentials: []hago.CredentialInfo{},
+		}
+		if user.GroupIDs == nil {
+			user.GroupIDs = []string{}
+		}
+		if msg.LocalOnly != nil {
+			user.LocalOnly = *msg.LocalOnly
+		}
+		s.users = append(s.users, user)
+		c.result(msg.ID, map[string]any{"user": user})
+
+	case "config/auth/update":
+		i := userIndex()
+		if i < 0 {
+			return
+		}
+		user := &s.users[i]
+		if msg.Name != nil {
+			user.Name = *msg.Name
+		}
+		if msg.IsActive != nil {
+			user.IsActive = *msg.IsActive
+		}
+		if msg.GroupIDs != nil {
+			user.GroupIDs = msg.GroupIDs
+		}
+		if msg.LocalOnly != nil {
+			user.LocalOnly = *msg.LocalOnly
+		}
+		c.result(msg.ID, map[string]any{"user": *user})
+
+	case "config/auth/delete":
+		if msg.UserID.String() == s.user.ID {
+			c.fail(msg.ID, "no_delete_self", "Unable to delete your own account")
+			return
+		}
+		i := userIndex()
+		if i < 0 {
+			return
+		}
+		if username := s.users[i].Username; username != nil {
+			delete(s.passwords, *username)
+		}
+		s.users = slices.Delete(s.users, i, i+1)
+		for j := range s.persons {
+			if p := &s.persons[j]; p.UserID != nil && *p.UserID == msg.UserID.String() {
+				p.UserID = nil
+			}
+		}
+		c.result(msg.ID, nil)
+
+	case "config/auth_provider/homeassistant/create":
+		i := userIndex()
+		if i < 0 {
+			return
+		}
+		if len(s.users[i].Credentials) > 0 {
+			c.fail(msg.ID, "credentials_exist", "User already has credentials.")
+			return
+		}
+		if _, ok := s.passwords[msg.Username]; ok {
+			c.fail(msg.ID, "username_exists", "Username already exists")
+			return
+		}
+		username := msg.Username
+		s.users[i].Username = &username
+		s.users[i].Credentials = []hago.CredentialInfo{{Type: "homeassistant"}}
+		s.passwords[username] = msg.Password
+		c.result(msg.ID, nil)
+
+	case "config/auth_provider/homeassistant/change_password":
+		i := slices.IndexFunc(s.users, func(u hago.User) bool { return u.ID == s.user.ID })
+		if i < 0 || s.users[i].Username == nil {
+			c.fail(msg.ID, "credentials_not_found", "Credentials not found")
+			return
+		}
+		username := *s.users[i].Username
+		if s.passwords[username] != msg.CurrentPassword {
+			c.fail(msg.ID, "invalid_current_password", "Invalid current password")
+			return
+		}
+		s.passwords[username] = msg.NewPassword
+		c.result(msg.ID, nil)
+
+	default:
+		c.fail(msg.ID, hago.ErrCodeUnknownCommand, "Unknown command.")
+	}
+}
+
+// handlePersons handles the person/* commands. Persons with YAML set are
+// listed but cannot be changed, and a user can be linked to one person.
+func (c *wsConn) handlePersons(msg *wsMessage) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	personIndex := func() int {
+		i := slices.IndexFunc(s.persons, func(p hago.Person) bool { return p.ID == msg.PersonID && !p.YAML })
+		if i < 0 {
+			c.fail(msg.ID, hago.ErrCodeNotFound, "Unable to find person_id "+msg.PersonID)
+		}
+		return i
+	}
+	userTaken := func(personID string) bool {
+		userID := msg.UserID.String()
+		if userID == "" {
+			return false
+		}
+		if !slices.ContainsFunc(s.users, func(u hago.User) bool { return u.ID == userID }) {
+			c.fail(msg.ID, hago.ErrCodeInvalidFormat, "User does not exist")
+			return true
+		}
+		for _, p := range s.persons {
+			if p.ID != personID && p.UserID != nil && *p.UserID == userID {
+				c.fail(msg.ID, hago.ErrCodeInvalidFormat, "User already taken")
+				return true
+			}
+		}
+		return false
+	}
+
+	switch msg.Type {
+	case "person/list":
+		storage, config := []hago.Person{}, []hago.Person{}
+		for _, p := range s.persons {
+			if p.YAML {
+				p.YAML = false
+				config = append(config, p)
+			} else {
+				storage = append(storage, p)
+			}
+		}
+		c.result(msg.ID, map[string]any{"storage": storage, "config": config})
+
+	case "person/create":
+		if msg.Name == nil || *msg.Name == "" {
+			c.fail(msg.ID, hago.ErrCodeInvalidFormat, "required key not provided @ data['name']")
+			return
+		}
+		if userTaken("") {
+			return
+		}
+		person := hago.Person{ID: s.personID(*msg.Name), DeviceTrackers: []string{}}
+		applyPersonUpdate(&person, msg)
+		s.persons = append(s.persons, person)
+		c.result(msg.ID, person)
+
+	case "person/update":
+		i := personIndex()
+		if i < 0 || userTaken(msg.PersonID) {
+			return
+		}
+		applyPersonUpdate(&s.persons[i], msg)
+		c.result(msg.ID, s.persons[i])
+
+	case "person/delete":
+		i := personIndex()
+		if i < 0 {
+			return
+		}
+		s.persons = slices.Delete(s.persons, i, i+1)
+		c.result(msg.ID, nil)
+
+	default:
+		c.fail(msg.ID, hago.ErrCodeUnknownCommand, "Unknown command.")
+	}
+}
+
+// personID returns an unused ID derived from a person's name.
+func (s *Server) personID(name string) string {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+	id := base
+	for n := 2; slices.ContainsFunc(s.persons, func(p hago.Person) bool { return p.ID == id }); n++ {
+		id = fmt.Sprintf("%s_%d", base, n)
+	}
+	return id
+}
+
+// applyPersonUpdate copies the optional person fields from a command.
+func applyPersonUpdate(p *hago.Person, msg *wsMessage) {
+	if msg.Name != nil {
+		p.Name = *msg.Name
+	}
+	if msg.UserID.Set {
+		p.UserID = msg.UserID.Value
+	}
+	if msg.DeviceTrackers != nil {
+		p.DeviceTrackers = *msg.DeviceTrackers
+	}
+	if msg.Picture.Set {
+		p.Picture = msg.Picture.Value
+	}
+}
@@ -0,0 +1,512 @@
+package hagotest
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/rmrfslashbin/hago"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsMessage is an incoming WebSocket message. It holds the fields of every
+// command the fake understands.
+type wsMessage struct {
+	ID          int64  `json:"id"`
+	Type        string `json:"type"`
+	AccessToken string `json:"access_token"`
+
+	// subscribe_events, unsubscribe_events, fire_event
+	EventType    string         `json:"event_type"`
+	EventData    hago.EventData `json:"event_data"`
+	Subscription int64          `json:"subscription"`
+
+	// subscribe_entities
+	EntityIDs []string `json:"entity_ids"`
+
+	// call_service
+	Domain         string         `json:"domain"`
+	Service        string         `json:"service"`
+	ServiceData    map[string]any `json:"service_data"`
+	Target         map[string]any `json:"target"`
+	ReturnResponse bool           `json:"return_response"`
+
+	// render_template
+	Template string `json:"template"`
+
+	// lovelace
+	URLPath         *string         `json:"url_path"`
+	Config          json.RawMessage `json:"config"`
+	DashboardID     string          `json:"dashboard_id"`
+	Title           *string         `json:"title"`
+	Icon            *string         `json:"icon"`
+	ShowInSidebar   *bool           `json:"show_in_sidebar"`
+	RequireAdmin    *bool           `json:"require_admin"`
+	AllowSingleWord *bool           `json:"allow_single_word"`
+
+	// auth
+	ClientName     string `json:"client_name"`
+	Lifespan       int    `json:"lifespan"`
+	RefreshTokenID string `json:"refresh_token_id"`
+
+	// config/auth, config/auth_provider/homeassistant, person
+	Name            *string   `json:"name"`
+	IsActive        *bool     `json:"is_active"`
+	GroupIDs        []string  `json:"group_ids"`
+	LocalOnly       *bool     `json:"local_only"`
+	UserID          nullable  `json:"user_id"`
+	Username        string    `json:"username"`
+	Password        string    `json:"password"`
+	CurrentPassword string    `json:"current_password"`
+	NewPassword     string    `json:"new_password"`
+	PersonID        string    `json:"person_id"`
+	DeviceTrackers  *[]string `json:"device_trackers"`
+	Picture         nullable  `json:"picture"`
+}
+
+// nullable is an optional string field that can be sent as null, such as
+// the user_id of a person. Set reports whether the field was present.
+type nullable struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON records that the field was present, even if null.
+func (n *nullable) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	return json.Unmarshal(data, &n.Value)
+}
+
+// String returns the value, or "" for null or absent.
+func (n nullable) String() string {
+	if n.Value == nil {
+		return ""
+	}
+	return *n.Value
+}
+
+// wsConn is an authenticated WebSocket client connection.
+type wsConn struct {
+	s    *Server
+	conn *websocket.Conn
+	// refreshTokenID is the refresh token of the access token the
+	// connection authenticated with.
+	refreshTokenID string
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	events   map[int64]string   // subscription ID to event type ("" for all)
+	entities map[int64][]string // subscription ID to entity filter (nil for all)
+
+	closeOnce sync.Once
+}
+
+// connections returns a snapshot of the open WebSocket connections.
+func (s *Server) connections() []*wsConn {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return slices.Collect(maps.Keys(s.conns))
+}
+
+// handleWebSocket upgrades the connection, authenticates it, and serves
+// commands until the client disconnects.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &wsConn{
+		s:        s,
+		conn:     conn,
+		events:   make(map[int64]string),
+		entities: make(map[int64][]string),
+	}
+	defer c.close()
+
+	if !c.authenticate() {
+		return
+	}
+
+	s.connMu.Lock()
+	s.conns[c] = struct{}{}
+	s.connMu.Unlock()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		s.sleep()
+		c.handle(&msg)
+	}
+}
+
+// authenticate runs the auth handshake and reports whether it succeeded.
+func (c *wsConn) authenticate() bool {
+	c.write(map[string]any{"type": "auth_required", "ha_version": Version})
+
+	var msg wsMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		return false
+	}
+	id, ok := c.s.authorize(msg.AccessToken, c.conn.RemoteAddr().String())
+	if msg.Type != "auth" || !ok {
+		c.write(map[string]any{"type": "auth_invalid", "message": "Invalid access token or password"})
+		return false
+	}
+	c.refreshTokenID = id
+	c.write(map[string]any{"type": "auth_ok", "ha_version": Version})
+	return true
+}
+
+// close closes the connection and unregisters it.
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		c.s.connMu.Lock()
+		delete(c.s.conns, c)
+		c.s.connMu.Unlock()
+		c.conn.Close()
+	})
+}
+
+// write sends a message, serializing concurrent writers.
+func (c *wsConn) write(v any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteJSON(v)
+}
+
+// result sends a successful result.
+func (c *wsConn) result(id int64, result any) {
+	c.write(map[string]any{"id": id, "type": "result", "success": true, "result": result})
+}
+
+// fail sends an error result.
+func (c *wsConn) fail(id int64, code, message string) {
+	c.write(map[string]any{
+		"id":      id,
+		"type":    "result",
+		"success": false,
+		"error":   map[string]string{"code": code, "message": message},
+	})
+}
+
+// event sends a subscription event.
+func (c *wsConn) event(id int64, event any) {
+	c.write(map[string]any{"id": id, "type": "event", "event": event})
+}
+
+// eventTypes returns the event types subscribed on this connection, with
+// "*" for all events.
+func (c *wsConn) eventTypes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var types []string
+	for _, t := range c.events {
+		if t == "" {
+			t = "*"
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// sendEvent delivers an event to matching subscribe_events subscriptions.
+func (c *wsConn) sendEvent(event hago.EventMessage) {
+	c.mu.Lock()
+	var ids []int64
+	for id, eventType := range c.events {
+		if eventType == "" || eventType == event.EventType {
+			ids = append(ids, id)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		c.event(id, event)
+	}
+}
+
+// sendEntityChange delivers a state change to matching subscribe_entities
+// subscriptions. A nil state is sent as a removal.
+func (c *wsConn) sendEntityChange(entityID string, state *hago.State) {
+	c.mu.Lock()
+	var ids []int64
+	for id, filter := range c.entities {
+		if filter == nil || slices.Contains(filter, entityID) {
+			ids = append(ids, id)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		if state == nil {
+			c.event(id, map[string]any{"r": []string{entityID}})
+		} else {
+			c.event(id, map[string]any{"a": map[string]any{entityID: compress(*state)}})
+		}
+	}
+}
+
+// compress converts a state to the subscribe_entities compressed format.
+func compress(st hago.State) map[string]any {
+	return map[string]any{
+		"s":  st.State,
+		"a":  st.Attributes,
+		"c":  st.Context.ID,
+		"lc": float64(st.LastChanged.UnixMicro()) / 1e6,
+		"lu": float64(st.LastUpdated.UnixMicro()) / 1e6,
+	}
+}
+
+// handle dispatches a command.
+func (c *wsConn) handle(msg *wsMessage) {
+	s := c.s
+
+	switch msg.Type {
+	case "ping":
+		c.write(map[string]any{"id": msg.ID, "type": "pong"})
+
+	case "subscribe_events":
+		c.mu.Lock()
+		c.events[msg.ID] = msg.EventType
+		c.mu.Unlock()
+		c.result(msg.ID, nil)
+
+	case "subscribe_entities":
+		c.result(msg.ID, nil)
+
+		// Hold c.mu so no change is delivered before the initial snapshot
+		c.mu.Lock()
+		initial := make(map[string]any)
+		for _, st := range s.States() {
+			if msg.EntityIDs == nil || slices.Contains(msg.EntityIDs, st.EntityID) {
+				initial[st.EntityID] = compress(st)
+			}
+		}
+		c.event(msg.ID, map[string]any{"a": initial})
+		c.entities[msg.ID] = msg.EntityIDs
+		c.mu.Unlock()
+
+	case "unsubscribe_events":
+		c.mu.Lock()
+		_, isEvents := c.events[msg.Subscription]
+		_, isEntities := c.entities[msg.Subscription]
+		delete(c.events, msg.Subscription)
+		delete(c.entities, msg.Subscription)
+		c.mu.Unlock()
+		if !isEvents && !isEntities {
+			c.fail(msg.ID, hago.ErrCodeNotFound, "Subscription not found.")
+			return
+		}
+		c.result(msg.ID, nil)
+
+	case "render_template":
+		s.mu.Lock()
+		render := s.render
+		s.mu.Unlock()
+		result, err := render(msg.Template)
+		if err != nil {
+			c.fail(msg.ID, hago.ErrCodeTemplateError, err.Error())
+			return
+		}
+		c.result(msg.ID, nil)
+		c.event(msg.ID, map[string]any{
+			"result":    result,
+			"listeners": map[string]any{"all": false, "domains": []string{}, "entities": []string{}, "time": false},
+		})
+
+	case "call_service":
+		data := maps.Clone(msg.ServiceData)
+		if data == nil {
+			data = make(map[string]any)
+		}
+		maps.Copy(data, msg.Target)
+		result, err := s.callService(msg.Domain, msg.Service, data, msg.ReturnResponse)
+		if err != nil {
+			c.fail(msg.ID, hago.ErrCodeServiceValidation, err.Error())
+			return
+		}
+		payload := map[string]any{"context": result.context}
+		if msg.ReturnResponse {
+			payload["response"] = result.response
+		}
+		c.result(msg.ID, payload)
+
+	case "fire_event":
+		event := s.FireEvent(msg.EventType, msg.EventData)
+		c.result(msg.ID, map[string]any{"context": event.Context})
+
+	case "get_states":
+		c.result(msg.ID, s.States())
+
+	case "get_config":
+		s.mu.Lock()
+		config := s.config
+		s.mu.Unlock()
+		c.result(msg.ID, config)
+
+	case "get_services":
+		services := make(map[string]map[string]hago.ServiceDetails)
+		for _, svc := range s.serviceList() {
+			services[svc.Domain] = svc.Services
+		}
+		c.result(msg.ID, services)
+
+	case "config/entity_registry/list":
+		c.registry(msg.ID, &s.entities)
+	case "config/device_registry/list":
+		c.registry(msg.ID, &s.devices)
+	case "config/area_registry/list":
+		c.registry(msg.ID, &s.areas)
+	case "config/label_registry/list":
+		c.registry(msg.ID, &s.labels)
+	case "config/floor_registry/list":
+		c.registry(msg.ID, &s.floors)
+
+	default:
+		if strings.HasPrefix(msg.Type, "lovelace/") {
+			c.handleLovelace(msg)
+			return
+		}
+		if strings.HasPrefix(msg.Type, "auth/") {
+			c.handleAuth(msg)
+			return
+		}
+		if strings.HasPrefix(msg.Type, "config/auth") {
+			c.handleUsers(msg)
+			return
+		}
+		if strings.HasPrefix(msg.Type, "person/") {
+			c.handlePersons(msg)
+			return
+		}
+		c.fail(msg.ID, hago.ErrCodeUnknownCommand, "Unknown command.")
+	}
+}
+
+// registry sends a registry list, reading it under the server lock.
+func (c *wsConn) registry(id int64, entries any) {
+	c.s.mu.Lock()
+	data, _ := json.Marshal(entries)
+	c.s.mu.Unlock()
+
+	if string(data) == "null" {
+		data = []byte("[]")
+	}
+	c.result(id, json.RawMessage(data))
+}
+
+// handleLovelace handles the lovelace/* commands.
+func (c *wsConn) handleLovelace(msg *wsMessage) {
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urlPath := ""
+	if msg.URLPath != nil {
+		urlPath = *msg.URLPath
+	}
+
+	switch msg.Type {
+	case "lovelace/config":
+		config, ok := s.lovelace[urlPath]
+		if !ok {
+			c.fail(msg.ID, "config_not_found", "No config found.")
+			return
+		}
+		c.result(msg.ID, config)
+
+	case "lovelace/config/save":
+		s.lovelace[urlPath] = msg.Config
+		c.result(msg.ID, nil)
+
+	case "lovelace/config/delete":
+		delete(s.lovelace, urlPath)
+		c.result(msg.ID, nil)
+
+	case "lovelace/resources":
+		resources := s.resources
+		if resources == nil {
+			resources = []hago.Resource{}
+		}
+		c.result(msg.ID, resources)
+
+	case "lovelace/dashboards/list":
+		dashboards := s.dashboards
+		if dashboards == nil {
+			dashboards = []hago.Dashboard{}
+		}
+		c.result(msg.ID, dashboards)
+
+	case "lovelace/dashboards/create":
+		if urlPath == "" || (!strings.Contains(urlPath, "-") && (msg.AllowSingleWord == nil || !*msg.AllowSingleWord)) {
+			c.fail(msg.ID, hago.ErrCodeInvalidFormat, "Url path needs to contain a hyphen (-)")
+			return
+		}
+		for _, d := range s.dashboards {
+			if d.URLPath == urlPath {
+				c.fail(msg.ID, hago.ErrCodeHomeAssistantError, "Dashboard url path needs to be unique")
+				return
+			}
+		}
+		d := hago.Dashboard{
+			ID:      strings.ReplaceAll(urlPath, "-", "_"),
+			URLPath: urlPath,
+			Mode:    "storage",
+		}
+		applyDashboardUpdate(&d, msg)
+		s.dashboards = append(s.dashboards, d)
+		c.result(msg.ID, d)
+
+	case "lovelace/dashboards/update":
+		i := slices.IndexFunc(s.dashboards, func(d hago.Dashboard) bool { return d.ID == msg.DashboardID })
+		if i < 0 {
+			c.fail(msg.ID, hago.ErrCodeNotFound, "Unable to find dashboard_id "+msg.DashboardID)
+			return
+		}
+		applyDashboardUpdate(&s.dashboards[i], msg)
+		c.result(msg.ID, s.dashboards[i])
+
+	case "lovelace/dashboards/delete":
+		i := slices.IndexFunc(s.dashboards, func(d hago.Dashboard) bool { return d.ID == msg.DashboardID })
+		if i < 0 {
+			c.fail(msg.ID, hago.ErrCodeNotFound, "Unable to find dashboard_id "+msg.DashboardID)
+			return
+		}
+		delete(s.lovelace, s.dashboards[i].URLPath)
+		s.dashboards = slices.Delete(s.dashboards, i, i+1)
+		c.result(msg.ID, nil)
+
+	default:
+		c.fail(msg.ID, hago.ErrCodeUnknownCommand, "Unknown command.")
+	}
+}
+
+// applyDashboardUpdate copies the optional dashboard fields from a command.
+func applyDashboardUpdate(d *hago.Dashboard, msg *wsMessage) {
+	if msg.Title != nil {
+		d.Title = *msg.Title
+	}
+	if msg.Icon != nil {
+		d.Icon = *msg.Icon
+	}
+	if msg.ShowInSidebar != nil {
+		d.ShowInSidebar = *msg.ShowInSidebar
+	}
+	if msg.RequireAdmin != nil {
+		d.RequireAdmin = *msg.RequireAdmin
+	}
+	if msg.AllowSingleWord != nil {
+		d.AllowSingleWord = *msg.AllowSingleWord
+	}
+}
@@ -0,0 +1,60 @@
+package hago
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// pingCmd is the WebSocket command to check the connection is alive.
+type pingCmd struct {
+	Type string `json:"type"`
+}
+
+// WithWebSocketHeartbeat enables WebSocket heartbeats.
+// Every interval the client sends a ping command and waits up to timeout for
+// the pong. If no pong arrives the connection is treated as dead and torn
+// down, so waiting commands fail fast instead of blocking until their context
+// expires, and automatic reconnection (if enabled) starts immediately.
+func WithWebSocketHeartbeat(interval, timeout time.Duration) Option {
+	return func(c *Client) error {
+		if interval <= 0 || timeout <= 0 {
+			return fmt.Errorf("heartbeat interval and timeout must be positive")
+		}
+		c.heartbeatInterval = interval
+		c.heartbeatTimeout = timeout
+		return nil
+	}
+}
+
+// WebSocketLatency returns the round-trip time of the most recent heartbeat
+// ping, or zero if no heartbeat has completed yet.
+func (c *Client) WebSocketLatency() time.Duration {
+	return time.Duration(c.wsLatency.Load())
+}
+
+// heartbeat pings the connection until it closes, recording the round-trip
+// time of each pong. A missed pong closes the connection.
+func (c *Client) heartbeat(ws *wsConn) {
+	ticker := time.NewTicker(c.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.heartbeatTimeout)
+		start := time.Now()
+		_, err := ws.sendCommand(ctx, pingCmd{Type: "ping"})
+		cancel()
+
+		if err != nil {
+			ws.close()
+			return
+		}
+		c.wsLatency.Store(int64(time.Since(start)))
+	}
+}
@@ -0,0 +1,105 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_WebSocketHeartbeatLatency(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd["type"] != "ping" {
+				t.Errorf("expected ping, got %v", cmd["type"])
+			}
+			conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "pong"})
+		}
+	})
+	defer server.Close()
+
+	client, err := New(
+		WithBaseURL(server.URL),
+		WithToken("test-token"),
+		WithWebSocketHeartbeat(10*time.Millisecond, time.Second),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.CloseWebSocket()
+
+	if got := client.WebSocketLatency(); got != 0 {
+		t.Errorf("WebSocketLatency() before connect = %v, want 0", got)
+	}
+
+	if err := client.connectWebSocket(context.Background()); err != nil {
+		t.Fatalf("connectWebSocket() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.WebSocketLatency() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for heartbeat latency")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClient_WebSocketHeartbeatDeadConnection(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		// Behave like a half-open connection: read everything, answer nothing
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(
+		WithBaseURL(server.URL),
+		WithToken("test-token"),
+		WithWebSocketHeartbeat(20*time.Millisecond, 50*time.Millisecond),
+	)
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.EntityRegistry(ctx)
+	if !errors.Is(err, ErrWebSocketClosed) {
+		t.Fatalf("EntityRegistry() error = %v, want ErrWebSocketClosed", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command took %v to fail, expected heartbeat to tear down the connection quickly", elapsed)
+	}
+}
+
+func TestWithWebSocketHeartbeat_Invalid(t *testing.T) {
+	_, err := New(
+		WithBaseURL("http://localhost:8123"),
+		WithToken("test-token"),
+		WithWebSocketHeartbeat(0, time.Second),
+	)
+	if err == nil {
+		t.Error("expected error for zero heartbeat interval")
+	}
+}
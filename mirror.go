@@ -0,0 +1,342 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"sync"
+	"time"
+)
+
+// StateChange describes a change to a mirrored entity.
+// OldState is nil when the entity was added; NewState is nil when it was removed.
+type StateChange struct {
+	EntityID string
+	OldState *State
+	NewState *State
+}
+
+// StateMirror is an always-current, in-memory copy of entity states kept in
+// sync with Home Assistant through the subscribe_entities WebSocket command.
+// It is safe for concurrent use by multiple goroutines.
+type StateMirror struct {
+	mu        sync.RWMutex
+	states    map[string]State
+	listeners map[*queue[StateChange]]struct{}
+
+	ready chan struct{}
+	done  chan struct{}
+}
+
+// subscribeEntitiesCmd is the WebSocket command to subscribe to entity states.
+type subscribeEntitiesCmd struct {
+	Type      string   `json:"type"`
+	EntityIDs []string `json:"entity_ids,omitempty"`
+}
+
+// entitiesMessage is an event from a subscribe_entities subscription.
+// HA sends full states under "a", diffs under "c" and removed entity IDs under "r".
+type entitiesMessage struct {
+	Add    map[string]compressedState `json:"a"`
+	Change map[string]compressedDiff  `json:"c"`
+	Remove []string                   `json:"r"`
+}
+
+// compressedState is HA's compact state representation.
+// Fields are pointers so partial states in diffs can be told apart from zero values.
+type compressedState struct {
+	State       *string         `json:"s"`
+	Attributes  map[string]any  `json:"a"`
+	Context     json.RawMessage `json:"c"`
+	LastChanged *float64        `json:"lc"`
+	LastUpdated *float64        `json:"lu"`
+}
+
+// compressedDiff is a change to a compressed state.
+type compressedDiff struct {
+	Additions *compressedState `json:"+"`
+	Removals  *struct {
+		Attributes []string `json:"a"`
+	} `json:"-,"` // key is a literal "-"
+}
+
+// MirrorStates starts mirroring entity states. If entityIDs is empty, all
+// entities are mirrored. It returns once the initial states have been loaded.
+//
+// The mirror stays current until ctx is done or the WebSocket connection is
+// lost without automatic reconnection. With WithWebSocketReconnect the
+// mirror resynchronizes after each reconnect.
+func (c *Client) MirrorStates(ctx context.Context, entityIDs ...string) (*StateMirror, error) {
+	cmd := subscribeEntitiesCmd{
+		Type:      "subscribe_entities",
+		EntityIDs: entityIDs,
+	}
+
+	sub, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe entities: %w", err)
+	}
+
+	m := &StateMirror{
+		states:    make(map[string]State),
+		listeners: make(map[*queue[StateChange]]struct{}),
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go m.run(ctx, sub)
+
+	select {
+	case <-m.ready:
+		return m, nil
+	case <-m.done:
+		return nil, fmt.Errorf("subscribe entities: %w", ErrWebSocketClosed)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Get returns the current state of an entity.
+func (m *StateMirror) Get(entityID string) (State, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, ok := m.states[entityID]
+	return state, ok
+}
+
+// Snapshot returns a copy of all mirrored states keyed by entity ID.
+func (m *StateMirror) Snapshot() map[string]State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.states)
+}
+
+// Len returns the number of mirrored entities.
+func (m *StateMirror) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.states)
+}
+
+// Done returns a channel that is closed when the mirror stops updating.
+func (m *StateMirror) Done() <-chan struct{} {
+	return m.done
+}
+
+// Changes returns a channel of state changes applied to the mirror after this
+// call. The channel is closed when ctx is done or the mirror stops.
+func (m *StateMirror) Changes(ctx context.Context) <-chan StateChange {
+	q := newQueue[StateChange]()
+	out := make(chan StateChange)
+
+	m.mu.Lock()
+	m.listeners[q] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer func() {
+			m.mu.Lock()
+			delete(m.listeners, q)
+			m.mu.Unlock()
+		}()
+
+		for {
+			finished := false
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			case <-m.done:
+				finished = true
+			}
+
+			for _, change := range q.pop() {
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if finished {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// run applies subscription events until the subscription ends.
+func (m *StateMirror) run(ctx context.Context, sub *wsSubscription) {
+	defer close(m.done)
+
+	resync := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.done:
+			return
+		case <-sub.events.notify:
+		}
+
+		for _, raw := range sub.events.pop() {
+			if raw == nil {
+				// Replayed after a reconnect; the next full update replaces
+				// everything so entities removed meanwhile are dropped.
+				resync = true
+				continue
+			}
+
+			var msg entitiesMessage
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				continue
+			}
+			m.apply(&msg, resync)
+			resync = false
+
+			select {
+			case <-m.ready:
+			default:
+				close(m.ready)
+			}
+		}
+	}
+}
+
+// apply updates the mirror from a subscription message and notifies listeners.
+// When replace is set, entities missing from the message's additions are removed.
+func (m *StateMirror) apply(msg *entitiesMessage, replace bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changes []StateChange
+
+	if replace {
+		for entityID, old := range m.states {
+			if _, ok := msg.Add[entityID]; !ok {
+				delete(m.states, entityID)
+				changes = append(changes, StateChange{EntityID: entityID, OldState: &old})
+			}
+		}
+	}
+
+	for entityID, cs := range msg.Add {
+		state := cs.expand(entityID)
+		changes = append(changes, m.set(entityID, state))
+	}
+
+	for entityID, diff := range msg.Change {
+		old, ok := m.states[entityID]
+		if !ok {
+			continue
+		}
+		state := diff.applyTo(old)
+		changes = append(changes, m.set(entityID, state))
+	}
+
+	for _, entityID := range msg.Remove {
+		old, ok := m.states[entityID]
+		if !ok {
+			continue
+		}
+		delete(m.states, entityID)
+		changes = append(changes, StateChange{EntityID: entityID, OldState: &old})
+	}
+
+	for q := range m.listeners {
+		for _, change := range changes {
+			q.push(change)
+		}
+	}
+}
+
+// set stores a state and returns the resulting change. The caller must hold m.mu.
+func (m *StateMirror) set(entityID string, state State) StateChange {
+	change := StateChange{EntityID: entityID, NewState: &state}
+	if old, ok := m.states[entityID]; ok {
+		change.OldState = &old
+	}
+	m.states[entityID] = state
+	return change
+}
+
+// expand converts a full compressed state into a State.
+func (cs *compressedState) expand(entityID string) State {
+	state := State{
+		EntityID:   entityID,
+		Attributes: cs.Attributes,
+		Context:    decodeCompressedContext(cs.Context),
+	}
+	if state.Attributes == nil {
+		state.Attributes = make(map[string]any)
+	}
+	if cs.State != nil {
+		state.State = *cs.State
+	}
+	if cs.LastChanged != nil {
+		state.LastChanged = unixFloatTime(*cs.LastChanged)
+	}
+	// last_updated is omitted when it equals last_changed
+	state.LastUpdated = state.LastChanged
+	if cs.LastUpdated != nil {
+		state.LastUpdated = unixFloatTime(*cs.LastUpdated)
+	}
+	return state
+}
+
+// applyTo returns a copy of old with the diff applied.
+func (d *compressedDiff) applyTo(old State) State {
+	state := old
+	state.Attributes = maps.Clone(old.Attributes)
+	if state.Attributes == nil {
+		state.Attributes = make(map[string]any)
+	}
+
+	if add := d.Additions; add != nil {
+		if add.State != nil {
+			state.State = *add.State
+		}
+		maps.Copy(state.Attributes, add.Attributes)
+		if len(add.Context) > 0 {
+			state.Context = decodeCompressedContext(add.Context)
+		}
+		if add.LastChanged != nil {
+			state.LastChanged = unixFloatTime(*add.LastChanged)
+			state.LastUpdated = state.LastChanged
+		} else if add.LastUpdated != nil {
+			state.LastUpdated = unixFloatTime(*add.LastUpdated)
+		}
+	}
+
+	if d.Removals != nil {
+		for _, key := range d.Removals.Attributes {
+			delete(state.Attributes, key)
+		}
+	}
+
+	return state
+}
+
+// decodeCompressedContext decodes a compressed context, which is either a
+// bare context ID string or a full context object.
+func decodeCompressedContext(raw json.RawMessage) Context {
+	var ctx Context
+	if len(raw) == 0 {
+		return ctx
+	}
+	if err := json.Unmarshal(raw, &ctx.ID); err == nil {
+		return ctx
+	}
+	_ = json.Unmarshal(raw, &ctx)
+	return ctx
+}
+
+// unixFloatTime converts fractional Unix seconds to a time.
+func unixFloatTime(f float64) time.Time {
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3).UTC()
+}
@@ -0,0 +1,224 @@
+package hago
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_MirrorStates(t *testing.T) {
+	sendUpdates := make(chan struct{})
+
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		// Auth flow
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+		if cmd["type"] != "subscribe_entities" {
+			t.Errorf("expected subscribe_entities, got %v", cmd["type"])
+		}
+
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+
+		// Initial snapshot
+		conn.WriteJSON(map[string]any{
+			"id":   cmd["id"],
+			"type": "event",
+			"event": map[string]any{
+				"a": map[string]any{
+					"light.kitchen": map[string]any{
+						"s":  "off",
+						"a":  map[string]any{"friendly_name": "Kitchen", "brightness": nil},
+						"c":  "ctx-1",
+						"lc": 1704110400.5,
+					},
+					"sensor.temp": map[string]any{
+						"s":  "21.5",
+						"a":  map[string]any{"unit_of_measurement": "°C"},
+						"c":  map[string]any{"id": "ctx-2", "user_id": "user-1"},
+						"lc": 1704110400.0,
+						"lu": 1704110460.0,
+					},
+				},
+			},
+		})
+
+		<-sendUpdates
+
+		// Diff: light turns on, brightness added, attribute removed
+		conn.WriteJSON(map[string]any{
+			"id":   cmd["id"],
+			"type": "event",
+			"event": map[string]any{
+				"c": map[string]any{
+					"light.kitchen": map[string]any{
+						"+": map[string]any{
+							"s":  "on",
+							"a":  map[string]any{"brightness": 200},
+							"c":  "ctx-3",
+							"lc": 1704110500.0,
+						},
+						"-": map[string]any{"a": []string{"friendly_name"}},
+					},
+				},
+			},
+		})
+
+		// Removal
+		conn.WriteJSON(map[string]any{
+			"id":    cmd["id"],
+			"type":  "event",
+			"event": map[string]any{"r": []string{"sensor.temp"}},
+		})
+
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mirror, err := client.MirrorStates(ctx)
+	if err != nil {
+		t.Fatalf("MirrorStates() error = %v", err)
+	}
+
+	if mirror.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", mirror.Len())
+	}
+
+	light, ok := mirror.Get("light.kitchen")
+	if !ok {
+		t.Fatal("expected light.kitchen in mirror")
+	}
+	if light.State != "off" {
+		t.Errorf("light state = %v, want off", light.State)
+	}
+	if light.Context.ID != "ctx-1" {
+		t.Errorf("light context = %v, want ctx-1", light.Context.ID)
+	}
+	if !light.LastUpdated.Equal(light.LastChanged) {
+		t.Errorf("LastUpdated = %v, want LastChanged %v", light.LastUpdated, light.LastChanged)
+	}
+	if want := time.Date(2024, 1, 1, 12, 0, 0, 500_000_000, time.UTC); !light.LastChanged.Equal(want) {
+		t.Errorf("LastChanged = %v, want %v", light.LastChanged, want)
+	}
+
+	temp, _ := mirror.Get("sensor.temp")
+	if temp.Context.ID != "ctx-2" || temp.Context.UserID != "user-1" {
+		t.Errorf("sensor context = %+v, want id ctx-2 user user-1", temp.Context)
+	}
+	if !temp.LastUpdated.After(temp.LastChanged) {
+		t.Errorf("expected LastUpdated after LastChanged, got %v / %v", temp.LastUpdated, temp.LastChanged)
+	}
+
+	changes := mirror.Changes(ctx)
+	close(sendUpdates)
+
+	select {
+	case change := <-changes:
+		if change.EntityID != "light.kitchen" {
+			t.Fatalf("change entity = %v, want light.kitchen", change.EntityID)
+		}
+		if change.OldState == nil || change.OldState.State != "off" {
+			t.Errorf("OldState = %+v, want off", change.OldState)
+		}
+		if change.NewState == nil || change.NewState.State != "on" {
+			t.Fatalf("NewState = %+v, want on", change.NewState)
+		}
+		if change.NewState.Attributes["brightness"] != float64(200) {
+			t.Errorf("brightness = %v, want 200", change.NewState.Attributes["brightness"])
+		}
+		if _, ok := change.NewState.Attributes["friendly_name"]; ok {
+			t.Error("expected friendly_name to be removed")
+		}
+		if change.OldState.Attributes["friendly_name"] != "Kitchen" {
+			t.Error("expected old state attributes to be unchanged")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+
+	select {
+	case change := <-changes:
+		if change.EntityID != "sensor.temp" || change.NewState != nil {
+			t.Errorf("expected removal of sensor.temp, got %+v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for removal")
+	}
+
+	if _, ok := mirror.Get("sensor.temp"); ok {
+		t.Error("expected sensor.temp to be removed")
+	}
+
+	snapshot := mirror.Snapshot()
+	if len(snapshot) != 1 || snapshot["light.kitchen"].State != "on" {
+		t.Errorf("Snapshot() = %+v", snapshot)
+	}
+
+	cancel()
+	select {
+	case <-mirror.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for mirror to stop")
+	}
+}
+
+func TestStateMirror_ApplyResync(t *testing.T) {
+	on := "on"
+	m := &StateMirror{
+		states: map[string]State{
+			"light.a": {EntityID: "light.a", State: "off"},
+			"light.b": {EntityID: "light.b", State: "off"},
+		},
+		listeners: make(map[*queue[StateChange]]struct{}),
+	}
+
+	m.apply(&entitiesMessage{
+		Add: map[string]compressedState{
+			"light.a": {State: &on},
+		},
+	}, true)
+
+	if _, ok := m.Get("light.b"); ok {
+		t.Error("expected light.b to be dropped on resync")
+	}
+	if state, _ := m.Get("light.a"); state.State != "on" {
+		t.Errorf("light.a state = %v, want on", state.State)
+	}
+}
+
+func TestDecodeCompressedContext(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Context
+	}{
+		{name: "empty", raw: "", want: Context{}},
+		{name: "id only", raw: `"abc"`, want: Context{ID: "abc"}},
+		{name: "object", raw: `{"id":"abc","parent_id":"p","user_id":"u"}`, want: Context{ID: "abc", ParentID: "p", UserID: "u"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeCompressedContext([]byte(tt.raw)); got != tt.want {
+				t.Errorf("decodeCompressedContext() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
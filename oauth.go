@@ -0,0 +1,264 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the access token for requests. Implementations
+// must be safe for concurrent use.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource for a token that does not expire, such as a
+// Long-Lived Access Token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// tokenRefreshMargin is how long before expiry an OAuth access token is
+// refreshed, so that it does not expire in flight.
+const tokenRefreshMargin = time.Minute
+
+// OAuthToken is an access token issued by the Home Assistant auth API,
+// together with the refresh token used to renew it.
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+}
+
+// Expired reports whether the access token has expired or is about to. A
+// token without an expiry never expires.
+func (t *OAuthToken) Expired() bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return time.Until(t.Expiry) < tokenRefreshMargin
+}
+
+// OAuthConfig describes an OAuth client of a Home Assistant instance.
+//
+// Home Assistant follows IndieAuth: the client ID is the URL of the client,
+// and the redirect URL must be on the same host unless the client ID page
+// lists it. For command-line tools a loopback address works for both:
+//
+//	config := &hago.OAuthConfig{
+//		BaseURL:     "http://homeassistant.local:8123",
+//		ClientID:    "http://127.0.0.1:49152/",
+//		RedirectURL: "http://127.0.0.1:49152/callback",
+//	}
+//	// Send the user to config.AuthCodeURL(state), then receive the code
+//	// on the redirect URL.
+//	token, err := config.Exchange(ctx, code)
+//	client, err := hago.New(
+//		hago.WithBaseURL(config.BaseURL),
+//		hago.WithTokenSource(config.TokenSource(token, nil)),
+//	)
+type OAuthConfig struct {
+	// BaseURL is the Home Assistant URL, e.g. "http://homeassistant.local:8123".
+	BaseURL string
+	// ClientID is the URL identifying the client.
+	ClientID string
+	// RedirectURL is where Home Assistant sends the user after authorizing.
+	RedirectURL string
+	// HTTPClient is used for token requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// AuthCodeURL returns the URL of the Home Assistant login page. After the
+// user logs in, the browser is redirected to RedirectURL with the
+// authorization code and state in the query string.
+func (o *OAuthConfig) AuthCodeURL(state string) string {
+	values := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.ClientID},
+		"redirect_uri":  {o.RedirectURL},
+		"state":         {state},
+	}
+	return strings.TrimSuffix(o.BaseURL, "/") + "/auth/authorize?" + values.Encode()
+}
+
+// Exchange exchanges an authorization code for a token.
+func (o *OAuthConfig) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	return o.tokenRequest(ctx, url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+		"client_id":  {o.ClientID},
+	})
+}
+
+// Refresh uses a refresh token to get a new access token. The returned
+// token keeps the refresh token, which Home Assistant does not rotate.
+func (o *OAuthConfig) Refresh(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	token, err := o.tokenRequest(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {o.ClientID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// Revoke revokes a refresh token and the access tokens issued from it.
+func (o *OAuthConfig) Revoke(ctx context.Context, refreshToken string) error {
+	resp, err := o.post(ctx, "/auth/revoke", url.Values{"token": {refreshToken}})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// tokenRequest posts to the token endpoint and decodes the token.
+func (o *OAuthConfig) tokenRequest(ctx context.Context, form url.Values) (*OAuthToken, error) {
+	resp, err := o.post(ctx, "/auth/token", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &RequestError{Op: "decode token response", Err: err}
+	}
+	if result.AccessToken == "" {
+		return nil, &RequestError{Op: "decode token response", Err: fmt.Errorf("no access token in response")}
+	}
+
+	token := &OAuthToken{
+		AccessToken:  result.AccessToken,
+		TokenType:    result.TokenType,
+		RefreshToken: result.RefreshToken,
+	}
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// post sends a form to an auth endpoint and checks the response status.
+// On success the caller must close the response body.
+func (o *OAuthConfig) post(ctx context.Context, path string, form url.Values) (*http.Response, error) {
+	reqURL := strings.TrimSuffix(o.BaseURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &RequestError{Op: "create request", Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpClient := o.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, &RequestError{Op: "execute request", Err: err}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, oauthError(resp)
+}
+
+// oauthError converts a failed auth API response to an APIError. The auth
+// endpoints report errors as {"error": ..., "error_description": ...}.
+func oauthError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(body),
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.Path,
+		Err:        statusError(resp.StatusCode),
+	}
+	var errResp struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if json.Unmarshal(body, &errResp) == nil {
+		switch {
+		case errResp.Error != "" && errResp.Description != "":
+			apiErr.Message = errResp.Error + ": " + errResp.Description
+		case errResp.Error != "":
+			apiErr.Message = errResp.Error
+		}
+	}
+	return apiErr
+}
+
+// OAuthTokenSource is a TokenSource that refreshes its access token shortly
+// before it expires.
+type OAuthTokenSource struct {
+	config    *OAuthConfig
+	onRefresh func(*OAuthToken)
+
+	mu    sync.Mutex
+	token *OAuthToken
+}
+
+// TokenSource returns a TokenSource that starts with token and refreshes it
+// as needed. If onRefresh is not nil it is called with each new token, for
+// example to save it; it must not block for long.
+func (o *OAuthConfig) TokenSource(token *OAuthToken, onRefresh func(*OAuthToken)) *OAuthTokenSource {
+	return &OAuthTokenSource{config: o, onRefresh: onRefresh, token: token}
+}
+
+// Token returns the current access token, refreshing it first if it has
+// expired or is about to.
+func (s *OAuthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && s.token.AccessToken != "" && !s.token.Expired() {
+		return s.token.AccessToken, nil
+	}
+	if s.token == nil || s.token.RefreshToken == "" {
+		return "", fmt.Errorf("access token expired and no refresh token: %w", ErrUnauthorized)
+	}
+
+	token, err := s.config.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("refresh token: %w", err)
+	}
+	s.token = token
+	if s.onRefresh != nil {
+		s.onRefresh(token)
+	}
+	return token.AccessToken, nil
+}
+
+// Current returns a copy of the current token.
+func (s *OAuthTokenSource) Current() OAuthToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return OAuthToken{}
+	}
+	return *s.token
+}
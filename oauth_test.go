@@ -0,0 +1,210 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockAuthServer serves /auth/token and /auth/revoke. It issues access
+// tokens "access-1", "access-2", ... that expire after expiresIn seconds,
+// and accepts them on /api/.
+func mockAuthServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if r.Form.Get("client_id") != "http://127.0.0.1:1234/" {
+			t.Errorf("client_id = %q", r.Form.Get("client_id"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		resp := map[string]any{"token_type": "Bearer", "expires_in": expiresIn}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "good-code" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_request","error_description":"Invalid code"}`)
+				return
+			}
+			resp["refresh_token"] = "refresh-1"
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+		default:
+			t.Errorf("unexpected grant_type %q", r.Form.Get("grant_type"))
+		}
+		resp["access_token"] = fmt.Sprintf("access-%d", issued.Add(1))
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("POST /auth/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("token") != "refresh-1" {
+			t.Errorf("revoke token = %q", r.FormValue("token"))
+		}
+	})
+	mux.HandleFunc("GET /api/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer access-%d", issued.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"message":"API running."}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func testOAuthConfig(baseURL string) *OAuthConfig {
+	return &OAuthConfig{
+		BaseURL:     baseURL,
+		ClientID:    "http://127.0.0.1:1234/",
+		RedirectURL: "http://127.0.0.1:1234/callback",
+	}
+}
+
+func TestOAuthConfig_AuthCodeURL(t *testing.T) {
+	config := testOAuthConfig("http://ha.local:8123/")
+	u, err := url.Parse(config.AuthCodeURL("xyz"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if u.Host != "ha.local:8123" || u.Path != "/auth/authorize" {
+		t.Errorf("URL = %s", u)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type": "code",
+		"client_id":     "http://127.0.0.1:1234/",
+		"redirect_uri":  "http://127.0.0.1:1234/callback",
+		"state":         "xyz",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+}
+
+func TestOAuthConfig_ExchangeAndRefresh(t *testing.T) {
+	server, _ := mockAuthServer(t, 1800)
+	config := testOAuthConfig(server.URL)
+	ctx := context.Background()
+
+	token, err := config.Exchange(ctx, "good-code")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" || token.TokenType != "Bearer" {
+		t.Errorf("token = %+v", token)
+	}
+	if d := time.Until(token.Expiry); d < 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("expiry in %v, want ~30m", d)
+	}
+
+	refreshed, err := config.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.AccessToken != "access-2" || refreshed.RefreshToken != "refresh-1" {
+		t.Errorf("refreshed = %+v", refreshed)
+	}
+
+	if err := config.Revoke(ctx, token.RefreshToken); err != nil {
+		t.Errorf("Revoke() error = %v", err)
+	}
+}
+
+func TestOAuthConfig_ExchangeError(t *testing.T) {
+	server, _ := mockAuthServer(t, 1800)
+	_, err := testOAuthConfig(server.URL).Exchange(context.Background(), "bad-code")
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("error = %v, want ErrBadRequest", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "invalid_request: Invalid code" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestOAuthTokenSource_RefreshesExpiredToken(t *testing.T) {
+	server, issued := mockAuthServer(t, 1800)
+	config := testOAuthConfig(server.URL)
+
+	var saved []string
+	ts := config.TokenSource(&OAuthToken{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(30 * time.Second), // inside the refresh margin
+	}, func(token *OAuthToken) { saved = append(saved, token.AccessToken) })
+
+	client, err := New(WithBaseURL(server.URL), WithTokenSource(ts))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for range 3 {
+		if _, err := client.Status(context.Background()); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	}
+
+	if issued.Load() != 1 {
+		t.Errorf("issued %d tokens, want 1", issued.Load())
+	}
+	if len(saved) != 1 || saved[0] != "access-1" {
+		t.Errorf("onRefresh calls = %v", saved)
+	}
+	if current := ts.Current(); current.AccessToken != "access-1" || current.RefreshToken != "refresh-1" {
+		t.Errorf("Current() = %+v", current)
+	}
+}
+
+func TestOAuthTokenSource_NoRefreshToken(t *testing.T) {
+	ts := testOAuthConfig("http://unused").TokenSource(&OAuthToken{
+		AccessToken: "old",
+		Expiry:      time.Now().Add(-time.Hour),
+	}, nil)
+	if _, err := ts.Token(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Token() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClient_WebSocketUsesTokenSource(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		if err := conn.ReadJSON(&auth); err != nil {
+			t.Errorf("read auth: %v", err)
+			return
+		}
+		if auth["access_token"] != "from-source" {
+			t.Errorf("access_token = %v", auth["access_token"])
+		}
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+		time.Sleep(100 * time.Millisecond)
+	})
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithTokenSource(StaticToken("from-source")))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.CloseWebSocket()
+
+	if err := client.connectWebSocket(context.Background()); err != nil {
+		t.Fatalf("connectWebSocket() error = %v", err)
+	}
+}
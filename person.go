@@ -0,0 +1,146 @@
+package hago
+
+import (
+	"context"
+	"fmt"
+)
+
+// Person is a person of the person integration. A person can be linked to
+// a user and is home when one of its device trackers is.
+type Person struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	UserID         *string  `json:"user_id"`
+	DeviceTrackers []string `json:"device_trackers"`
+	Picture        *string  `json:"picture"`
+	// YAML is set for persons configured in configuration.yaml, which
+	// cannot be changed over the API.
+	YAML bool `json:"yaml,omitempty"`
+}
+
+// CreatePersonRequest is the request to create a person.
+type CreatePersonRequest struct {
+	Name           string
+	UserID         string
+	DeviceTrackers []string
+	Picture        string
+}
+
+// UpdatePersonRequest is the request to update a person. Nil fields are
+// left unchanged; a pointer to "" clears UserID or Picture, and a non-nil
+// empty DeviceTrackers removes all device trackers.
+type UpdatePersonRequest struct {
+	Name           *string
+	UserID         *string
+	DeviceTrackers []string
+	Picture        *string
+}
+
+// personCreateCmd is the person/create command.
+type personCreateCmd struct {
+	Type           string   `json:"type"`
+	Name           string   `json:"name"`
+	UserID         *string  `json:"user_id,omitempty"`
+	DeviceTrackers []string `json:"device_trackers"`
+	Picture        *string  `json:"picture,omitempty"`
+}
+
+// personDeleteCmd is the person/delete command.
+type personDeleteCmd struct {
+	Type     string `json:"type"`
+	PersonID string `json:"person_id"`
+}
+
+// personListResult is the result of person/list: persons stored by the
+// integration and persons from configuration.yaml.
+type personListResult struct {
+	Storage []Person `json:"storage"`
+	Config  []Person `json:"config"`
+}
+
+// Persons lists all persons, both those managed over the API and those
+// configured in configuration.yaml. Requires admin authentication.
+func (c *Client) Persons(ctx context.Context) ([]Person, error) {
+	cmd := map[string]string{"type": "person/list"}
+	var result personListResult
+	if err := c.wsCommand(ctx, cmd, &result); err != nil {
+		return nil, fmt.Errorf("list persons: %w", err)
+	}
+	persons := result.Storage
+	for _, p := range result.Config {
+		p.YAML = true
+		persons = append(persons, p)
+	}
+	return persons, nil
+}
+
+// CreatePerson creates a person. A user can be linked to at most one
+// person. Requires admin authentication.
+func (c *Client) CreatePerson(ctx context.Context, req *CreatePersonRequest) (*Person, error) {
+	cmd := personCreateCmd{
+		Type:           "person/create",
+		Name:           req.Name,
+		DeviceTrackers: req.DeviceTrackers,
+	}
+	if cmd.DeviceTrackers == nil {
+		cmd.DeviceTrackers = []string{}
+	}
+	if req.UserID != "" {
+		cmd.UserID = &req.UserID
+	}
+	if req.Picture != "" {
+		cmd.Picture = &req.Picture
+	}
+	var person Person
+	if err := c.wsCommand(ctx, cmd, &person); err != nil {
+		return nil, fmt.Errorf("create person: %w", err)
+	}
+	return &person, nil
+}
+
+// UpdatePerson updates a person. Requires admin authentication.
+func (c *Client) UpdatePerson(ctx context.Context, personID string, req *UpdatePersonRequest) (*Person, error) {
+	// A map rather than a struct, since clearing a field sends null
+	cmd := map[string]any{
+		"type":      "person/update",
+		"person_id": personID,
+	}
+	if req.Name != nil {
+		cmd["name"] = *req.Name
+	}
+	if req.UserID != nil {
+		cmd["user_id"] = nullIfEmpty(*req.UserID)
+	}
+	if req.DeviceTrackers != nil {
+		cmd["device_trackers"] = req.DeviceTrackers
+	}
+	if req.Picture != nil {
+		cmd["picture"] = nullIfEmpty(*req.Picture)
+	}
+	var person Person
+	if err := c.wsCommand(ctx, cmd, &person); err != nil {
+		return nil, fmt.Errorf("update person: %w", err)
+	}
+	return &person, nil
+}
+
+// DeletePerson deletes a person. The linked user, if any, is kept.
+// Requires admin authentication.
+func (c *Client) DeletePerson(ctx context.Context, personID string) error {
+	cmd := personDeleteCmd{
+		Type:     "person/delete",
+		PersonID: personID,
+	}
+	if err := c.wsCommand(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("delete person: %w", err)
+	}
+	return nil
+}
+
+// nullIfEmpty returns nil for "", which encodes as JSON null.
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package hago
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClient_Persons(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "person/list" {
+			t.Errorf("type = %v", cmd["type"])
+		}
+		return map[string]any{
+			"storage": []map[string]any{
+				{"id": "alice", "name": "Alice", "user_id": "u1", "device_trackers": []string{"device_tracker.alice_phone"}, "picture": nil},
+			},
+			"config": []map[string]any{
+				{"id": "guest", "name": "Guest", "user_id": nil, "device_trackers": []string{}},
+			},
+		}, nil
+	})
+
+	persons, err := client.Persons(context.Background())
+	if err != nil {
+		t.Fatalf("Persons() error = %v", err)
+	}
+	if len(persons) != 2 {
+		t.Fatalf("got %d persons, want 2", len(persons))
+	}
+	alice, guest := persons[0], persons[1]
+	if alice.YAML || *alice.UserID != "u1" || alice.DeviceTrackers[0] != "device_tracker.alice_phone" {
+		t.Errorf("alice = %+v", alice)
+	}
+	if !guest.YAML || guest.UserID != nil {
+		t.Errorf("guest = %+v", guest)
+	}
+}
+
+func TestClient_CreatePerson(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "person/create" || cmd["name"] != "Bob" || cmd["user_id"] != "u2" {
+			t.Errorf("cmd = %v", cmd)
+		}
+		if trackers, ok := cmd["device_trackers"].([]any); !ok || len(trackers) != 0 {
+			t.Errorf("device_trackers = %v, want []", cmd["device_trackers"])
+		}
+		if _, ok := cmd["picture"]; ok {
+			t.Errorf("picture sent: %v", cmd["picture"])
+		}
+		return map[string]any{"id": "bob", "name": "Bob", "user_id": "u2", "device_trackers": []string{}}, nil
+	})
+
+	person, err := client.CreatePerson(context.Background(), &CreatePersonRequest{Name: "Bob", UserID: "u2"})
+	if err != nil {
+		t.Fatalf("CreatePerson() error = %v", err)
+	}
+	if person.ID != "bob" || *person.UserID != "u2" {
+		t.Errorf("person = %+v", person)
+	}
+}
+
+func TestClient_UpdateAndDeletePerson(t *testing.T) {
+	var cmds []map[string]any
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		cmds = append(cmds, cmd)
+		if cmd["type"] == "person/update" {
+			return map[string]any{"id": "bob", "name": "Bob", "user_id": nil, "device_trackers": cmd["device_trackers"]}, nil
+		}
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	unlink := ""
+	person, err := client.UpdatePerson(ctx, "bob", &UpdatePersonRequest{
+		UserID:         &unlink,
+		DeviceTrackers: []string{"device_tracker.bob_phone"},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePerson() error = %v", err)
+	}
+	if person.UserID != nil || person.DeviceTrackers[0] != "device_tracker.bob_phone" {
+		t.Errorf("person = %+v", person)
+	}
+	if err := client.DeletePerson(ctx, "bob"); err != nil {
+		t.Fatalf("DeletePerson() error = %v", err)
+	}
+
+	update := cmds[0]
+	if update["person_id"] != "bob" {
+		t.Errorf("update cmd = %v", update)
+	}
+	if userID, ok := update["user_id"]; !ok || userID != nil {
+		t.Errorf("user_id = %v, want null", userID)
+	}
+	for _, key := range []string{"name", "picture"} {
+		if _, ok := update[key]; ok {
+			t.Errorf("update cmd sent unchanged field %s", key)
+		}
+	}
+	if cmds[1]["type"] != "person/delete" || cmds[1]["person_id"] != "bob" {
+		t.Errorf("delete cmd = %v", cmds[1])
+	}
+}
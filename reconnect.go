@@ -0,0 +1,173 @@
+package hago
+
+import (
+	"context"
+	"time"
+)
+
+// reconnectTimeout bounds a single reconnect attempt (dial, auth and
+// subscription replay).
+const reconnectTimeout = 30 * time.Second
+
+// ConnectionState describes the state of the WebSocket connection.
+type ConnectionState int
+
+// WebSocket connection states reported to the connection state handler.
+const (
+	// ConnectionConnected means a connection was established and authenticated.
+	ConnectionConnected ConnectionState = iota
+	// ConnectionDisconnected means the connection was lost.
+	ConnectionDisconnected
+	// ConnectionReconnecting means a reconnect attempt is about to start.
+	ConnectionReconnecting
+	// ConnectionClosed means reconnection gave up after MaxAttempts.
+	ConnectionClosed
+)
+
+// String returns the name of the connection state.
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionConnected:
+		return "connected"
+	case ConnectionDisconnected:
+		return "disconnected"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	case ConnectionClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// ReconnectPolicy controls automatic WebSocket reconnection.
+// Zero values are replaced with the defaults from DefaultReconnectPolicy.
+type ReconnectPolicy struct {
+	// InitialBackoff is the delay before the first reconnect attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// MaxAttempts limits consecutive failed attempts. Zero means retry forever.
+	MaxAttempts int
+}
+
+// DefaultReconnectPolicy returns a policy that retries forever, starting at
+// one second and backing off to one minute.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}
+}
+
+// backoff returns the delay before the given attempt (starting at 1).
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	return backoffDelay(p.InitialBackoff, p.MaxBackoff, attempt)
+}
+
+// WithWebSocketReconnect enables automatic WebSocket reconnection.
+// When the connection is lost the client reconnects with exponential
+// backoff, re-authenticates, and re-issues all active subscriptions
+// (events, triggers, templates) so their channels keep delivering.
+// Commands in flight when the connection drops fail with ErrWebSocketClosed.
+func WithWebSocketReconnect(policy ReconnectPolicy) Option {
+	return func(c *Client) error {
+		defaults := DefaultReconnectPolicy()
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = defaults.InitialBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaults.MaxBackoff
+		}
+		if policy.MaxBackoff < policy.InitialBackoff {
+			policy.MaxBackoff = policy.InitialBackoff
+		}
+		c.reconnect = &policy
+		return nil
+	}
+}
+
+// WithConnectionStateHandler sets a function called whenever the WebSocket
+// connection state changes. It is called from a background goroutine and
+// should return quickly.
+func WithConnectionStateHandler(fn func(ConnectionState)) Option {
+	return func(c *Client) error {
+		c.onConnectionState = fn
+		return nil
+	}
+}
+
+// notifyConnectionState reports a connection state change to the handler.
+func (c *Client) notifyConnectionState(state ConnectionState) {
+	if c.onConnectionState != nil {
+		c.onConnectionState(state)
+	}
+}
+
+// monitor waits for a connection to end. Without a reconnect policy it ends
+// the subscriptions on that connection; otherwise it reconnects until it
+// succeeds, gives up, or CloseWebSocket is called.
+func (c *Client) monitor(ws *wsConn, stop chan struct{}) {
+	select {
+	case <-ws.done:
+	case <-stop:
+		return
+	}
+
+	// Nothing to do if CloseWebSocket was called or another caller has
+	// already replaced the connection.
+	c.wsMu.Lock()
+	replaced := c.wsStop != stop || (c.ws != nil && c.ws != ws)
+	c.wsMu.Unlock()
+	if replaced {
+		return
+	}
+
+	c.notifyConnectionState(ConnectionDisconnected)
+
+	if c.reconnect == nil {
+		c.wsMu.Lock()
+		c.closeSubscriptions(ws)
+		c.wsMu.Unlock()
+		return
+	}
+
+	for attempt := 1; c.reconnect.MaxAttempts == 0 || attempt <= c.reconnect.MaxAttempts; attempt++ {
+		timer := time.NewTimer(c.reconnect.backoff(attempt))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		c.notifyConnectionState(ConnectionReconnecting)
+		if c.reconnectWebSocket(stop) {
+			return
+		}
+	}
+
+	c.closeSubscriptions(nil)
+	c.notifyConnectionState(ConnectionClosed)
+}
+
+// reconnectWebSocket makes a single reconnect attempt and reports whether the
+// client is connected afterwards. It also returns true if CloseWebSocket was
+// called, since there is nothing left to do.
+func (c *Client) reconnectWebSocket(stop chan struct{}) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+
+	c.wsMu.Lock()
+	if c.wsStop != stop {
+		c.wsMu.Unlock()
+		return true
+	}
+	connected, err := c.connectLocked(ctx)
+	c.wsMu.Unlock()
+
+	if connected {
+		c.notifyConnectionState(ConnectionConnected)
+	}
+	return err == nil
+}
@@ -0,0 +1,262 @@
+package hago
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// stateRecorder collects connection state changes.
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (r *stateRecorder) record(s ConnectionState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, s)
+}
+
+func (r *stateRecorder) has(s ConnectionState) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, got := range r.states {
+		if got == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClient_WebSocketReconnectReplaysSubscriptions(t *testing.T) {
+	var connections atomic.Int32
+	subscribeIDs := make(chan float64, 2)
+
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		n := connections.Add(1)
+
+		// Auth flow
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		if cmd["type"] != "subscribe_events" {
+			t.Errorf("connection %d: expected subscribe_events, got %v", n, cmd["type"])
+		}
+		subscribeIDs <- cmd["id"].(float64)
+
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+		conn.WriteJSON(map[string]any{
+			"id":   cmd["id"],
+			"type": "event",
+			"event": map[string]any{
+				"event_type": "test_event",
+				"data":       map[string]any{"connection": n},
+				"time_fired": "2024-01-01T12:00:00+00:00",
+				"context":    map[string]any{"id": "ctx"},
+			},
+		})
+
+		if n == 1 {
+			// Simulate an HA restart by dropping the first connection
+			return
+		}
+
+		// Keep the second connection open until the client goes away
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	recorder := &stateRecorder{}
+	client, err := New(
+		WithBaseURL(server.URL),
+		WithToken("test-token"),
+		WithWebSocketReconnect(ReconnectPolicy{
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+		}),
+		WithConnectionStateHandler(recorder.record),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.SubscribeEvents(ctx, "test_event")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	for _, want := range []float64{1, 2} {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("events channel closed across reconnect")
+			}
+			if event.Data["connection"] != want {
+				t.Errorf("event from connection %v, want %v", event.Data["connection"], want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event from connection %v", want)
+		}
+	}
+
+	if got := connections.Load(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+	if len(subscribeIDs) != 2 {
+		t.Fatalf("expected 2 subscribe commands, got %d", len(subscribeIDs))
+	}
+
+	for _, state := range []ConnectionState{ConnectionConnected, ConnectionDisconnected, ConnectionReconnecting} {
+		if !recorder.has(state) {
+			t.Errorf("expected connection state %v to be reported", state)
+		}
+	}
+}
+
+func TestClient_WebSocketDropWithoutReconnect(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+		// Drop the connection
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	events, err := client.SubscribeEvents(context.Background(), "")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected events channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+}
+
+func TestClient_WebSocketReconnectGivesUp(t *testing.T) {
+	var connections atomic.Int32
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		if connections.Add(1) > 1 {
+			// Reject re-authentication
+			conn.WriteJSON(map[string]any{"type": "auth_required"})
+			var auth map[string]any
+			conn.ReadJSON(&auth)
+			conn.WriteJSON(map[string]any{"type": "auth_invalid", "message": "revoked"})
+			return
+		}
+
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+	})
+	defer server.Close()
+
+	recorder := &stateRecorder{}
+	client, _ := New(
+		WithBaseURL(server.URL),
+		WithToken("test-token"),
+		WithWebSocketReconnect(ReconnectPolicy{
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			MaxAttempts:    2,
+		}),
+		WithConnectionStateHandler(recorder.record),
+	)
+	defer client.CloseWebSocket()
+
+	events, err := client.SubscribeEvents(context.Background(), "")
+	if err != nil {
+		t.Fatalf("SubscribeEvents() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected events channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+
+	if !recorder.has(ConnectionClosed) {
+		t.Error("expected ConnectionClosed to be reported")
+	}
+	if got := connections.Load(); got != 3 {
+		t.Errorf("connections = %d, want 3 (initial + 2 attempts)", got)
+	}
+}
+
+func TestReconnectPolicy_Backoff(t *testing.T) {
+	policy := ReconnectPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: 100 * time.Millisecond},
+		{attempt: 2, max: 200 * time.Millisecond},
+		{attempt: 3, max: 400 * time.Millisecond},
+		{attempt: 10, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			got := policy.backoff(tt.attempt)
+			if got < tt.max/2 || got > tt.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestConnectionState_String(t *testing.T) {
+	tests := map[ConnectionState]string{
+		ConnectionConnected:    "connected",
+		ConnectionDisconnected: "disconnected",
+		ConnectionReconnecting: "reconnecting",
+		ConnectionClosed:       "closed",
+		ConnectionState(99):    "unknown",
+	}
+	for state, want := range tests {
+		if got := state.String(); got != want {
+			t.Errorf("String() = %v, want %v", got, want)
+		}
+	}
+}
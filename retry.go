@@ -0,0 +1,153 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls retries of failed REST requests.
+// Zero values are replaced with the defaults from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays
+	// requested by a Retry-After header.
+	MaxBackoff time.Duration
+	// RetryableStatuses lists the HTTP status codes that are retried.
+	RetryableStatuses []int
+	// RetryNonIdempotent also retries POST and PATCH requests. Only enable
+	// this if repeating a service call or event is harmless.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy that makes up to four attempts,
+// backing off from 500ms to 30s, and retries 429, 502, 503 and 504
+// responses as well as connection errors. This covers the window in which
+// Home Assistant is restarting.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetry enables retries with exponential backoff and jitter for REST
+// requests. By default only idempotent methods (GET, HEAD, PUT, DELETE,
+// OPTIONS) are retried. The number of attempts made is reported in the
+// Attempts field of the returned APIError or RequestError.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		defaults := DefaultRetryPolicy()
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = defaults.MaxAttempts
+		}
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = defaults.InitialBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaults.MaxBackoff
+		}
+		if policy.MaxBackoff < policy.InitialBackoff {
+			policy.MaxBackoff = policy.InitialBackoff
+		}
+		if policy.RetryableStatuses == nil {
+			policy.RetryableStatuses = defaults.RetryableStatuses
+		}
+		c.retry = &policy
+		return nil
+	}
+}
+
+// retryDelay reports whether a failed attempt should be retried and how long
+// to wait first. resp is nil if the request failed before a response arrived.
+func (c *Client) retryDelay(ctx context.Context, method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	p := c.retry
+	if p == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(method) {
+		return 0, false
+	}
+
+	if resp == nil {
+		// Transport error; don't retry if the caller gave up
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+	} else if !slices.Contains(p.RetryableStatuses, resp.StatusCode) {
+		return 0, false
+	}
+
+	delay := backoffDelay(p.InitialBackoff, p.MaxBackoff, attempt)
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			delay = min(after, p.MaxBackoff)
+		}
+	}
+	return delay, true
+}
+
+// isIdempotent reports whether an HTTP method can safely be repeated.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// backoffDelay returns the delay before the given attempt (starting at 1).
+// The delay doubles each attempt up to maxDelay, with up to 50% jitter so
+// that many clients do not retry in lockstep after an HA restart.
+func backoffDelay(initial, maxDelay time.Duration, attempt int) time.Duration {
+	delay := initial
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// withAttempts records the number of attempts on a request or API error.
+func withAttempts(err error, attempts int) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Attempts = attempts
+	}
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		reqErr.Attempts = attempts
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry is a retry policy with short delays for tests.
+func fastRetry() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}
+}
+
+func TestClient_RetryTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message": "API running."}`))
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(fastRetry()))
+
+	if _, err := client.Status(context.Background()); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestClient_RetryGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(fastRetry()))
+
+	_, err := client.Status(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", apiErr.Attempts)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestClient_RetrySkipsNonIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(fastRetry()))
+
+	if _, err := client.CallService(context.Background(), "light", "turn_on", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1 (POST must not be retried)", got)
+	}
+
+	policy := fastRetry()
+	policy.RetryNonIdempotent = true
+	client, _ = New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(policy))
+	calls.Store(0)
+
+	client.CallService(context.Background(), "light", "turn_on", nil)
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3 with RetryNonIdempotent", got)
+	}
+}
+
+func TestClient_RetryNotFoundNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(fastRetry()))
+
+	if _, err := client.State(context.Background(), "light.missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestClient_RetryConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client, _ := New(WithBaseURL(url), WithToken("test-token"), WithRetry(fastRetry()))
+
+	_, err := client.Status(context.Background())
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected *RequestError, got %v", err)
+	}
+	if reqErr.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", reqErr.Attempts)
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var first time.Time
+	var delay time.Duration
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		delay = time.Since(first)
+		w.Write([]byte(`{"message": "API running."}`))
+	}))
+	defer server.Close()
+
+	policy := fastRetry()
+	policy.MaxBackoff = 200 * time.Millisecond
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithRetry(policy))
+
+	if _, err := client.Status(context.Background()); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	// Retry-After of 1s is capped at MaxBackoff
+	if delay < 150*time.Millisecond || delay > 900*time.Millisecond {
+		t.Errorf("retry delay = %v, want ~200ms", delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("5"); !ok || d != 5*time.Second {
+		t.Errorf("parseRetryAfter(5) = %v, %v", d, ok)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(future); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("expected invalid value to be rejected")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := backoffDelay(100*time.Millisecond, time.Second, attempt)
+		if d < 50*time.Millisecond || d > time.Second {
+			t.Errorf("attempt %d: delay %v out of range", attempt, d)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package hago
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Selection query keys. A term without a key matches entity IDs.
+const (
+	SelectEntity      = "entity"
+	SelectDomain      = "domain"
+	SelectState       = "state"
+	SelectDeviceClass = "device_class"
+	SelectArea        = "area"
+	SelectFloor       = "floor"
+	SelectLabel       = "label"
+	SelectDevice      = "device"
+	SelectIntegration = "integration"
+)
+
+// selectKeys are the keys accepted in a selection query.
+var selectKeys = []string{
+	SelectEntity, SelectDomain, SelectState, SelectDeviceClass, SelectArea,
+	SelectFloor, SelectLabel, SelectDevice, SelectIntegration,
+}
+
+// SelectTerm is one condition of a selection query.
+type SelectTerm struct {
+	Key string
+	// Values are glob patterns; the term matches if any of them matches.
+	Values []string
+	// Negate inverts the term.
+	Negate bool
+}
+
+// SelectQuery selects entities by ID and metadata. It is parsed from a
+// space-separated list of terms, all of which must match:
+//
+//	light.*                          entity ID glob
+//	domain:sensor                    entity domain
+//	state:unavailable                entity state
+//	device_class:temperature         device_class attribute
+//	area:kitchen                     area ID or name, from the entity or its device
+//	floor:upstairs                   floor ID or name of the area
+//	label:critical                   label ID or name, on the entity or its device
+//	device:"Hall Sensor"             device ID or name
+//	integration:hue                  integration (platform) of the entity
+//
+// Values are case-insensitive globs as understood by path.Match. A term may
+// list alternatives separated by commas (domain:light,switch), and a leading
+// "!" negates it (!state:unavailable). Quote values that contain spaces.
+type SelectQuery struct {
+	Terms []SelectTerm
+}
+
+// ParseSelectQuery parses a selection query.
+func ParseSelectQuery(query string) (*SelectQuery, error) {
+	words, err := splitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty selection query")
+	}
+
+	q := &SelectQuery{}
+	for _, word := range words {
+		term := SelectTerm{Key: SelectEntity}
+		if rest, ok := strings.CutPrefix(word, "!"); ok {
+			term.Negate = true
+			word = rest
+		}
+		if key, value, ok := strings.Cut(word, ":"); ok {
+			key = strings.ToLower(key)
+			if !slices.Contains(selectKeys, key) {
+				return nil, fmt.Errorf("unknown selection key %q (use one of %s)", key, strings.Join(selectKeys, ", "))
+			}
+			term.Key = key
+			word = value
+		}
+		for _, v := range strings.Split(word, ",") {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v == "" {
+				continue
+			}
+			if _, err := path.Match(v, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", v, err)
+			}
+			term.Values = append(term.Values, v)
+		}
+		if len(term.Values) == 0 {
+			return nil, fmt.Errorf("missing value for %q", term.Key)
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q, nil
+}
+
+// splitQuery splits a query into words, honoring double quotes.
+func splitQuery(query string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		quoted bool
+		inWord bool
+	)
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in selection query")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// NeedsRegistries reports whether the query uses registry metadata, i.e.
+// areas, floors, labels, devices or integrations.
+func (q *SelectQuery) NeedsRegistries() bool {
+	for _, term := range q.Terms {
+		switch term.Key {
+		case SelectArea, SelectFloor, SelectLabel, SelectDevice, SelectIntegration:
+			return true
+		}
+	}
+	return false
+}
+
+// Registries holds the registries a selection query is evaluated against.
+type Registries struct {
+	Entities []EntityRegistryEntry
+	Devices  []DeviceRegistryEntry
+	Areas    []AreaRegistryEntry
+	Floors   []FloorRegistryEntry
+	Labels   []LabelRegistryEntry
+}
+
+// Filter returns the states matching the query. reg may be nil if the query
+// does not need registries.
+func (q *SelectQuery) Filter(states []State, reg *Registries) []State {
+	idx := newSelectIndex(reg)
+	var matched []State
+	for _, s := range states {
+		if q.match(s, idx) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// match reports whether a state matches all terms.
+func (q *SelectQuery) match(s State, idx *selectIndex) bool {
+	for _, term := range q.Terms {
+		if term.match(idx.candidates(term.Key, s)) == term.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether any candidate value matches any pattern.
+func (t SelectTerm) match(candidates []string) bool {
+	for _, c := range candidates {
+		c = strings.ToLower(c)
+		for _, pattern := range t.Values {
+			if ok, _ := path.Match(pattern, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectIndex looks up registry metadata by ID.
+type selectIndex struct {
+	entities map[string]EntityRegistryEntry
+	devices  map[string]DeviceRegistryEntry
+	areas    map[string]AreaRegistryEntry
+	floors   map[string]FloorRegistryEntry
+	labels   map[string]LabelRegistryEntry
+}
+
+func newSelectIndex(reg *Registries) *selectIndex {
+	idx := &selectIndex{
+		entities: make(map[string]EntityRegistryEntry),
+		devices:  make(map[string]DeviceRegistryEntry),
+		areas:    make(map[string]AreaRegistryEntry),
+		floors:   make(map[string]FloorRegistryEntry),
+		labels:   make(map[string]LabelRegistryEntry),
+	}
+	if reg == nil {
+		return idx
+	}
+	for _, e := range reg.Entities {
+		idx.entities[e.EntityID] = e
+	}
+	for _, d := range reg.Devices {
+		idx.devices[d.ID] = d
+	}
+	for _, a := range reg.Areas {
+		idx.areas[a.AreaID] = a
+	}
+	for _, f := range reg.Floors {
+		idx.floors[f.FloorID] = f
+	}
+	for _, l := range reg.Labels {
+		idx.labels[l.LabelID] = l
+	}
+	return idx
+}
+
+// candidates returns the values of key for a state that a term is matched
+// against. IDs and names are both candidates so either can be used.
+func (idx *selectIndex) candidates(key string, s State) []string {
+	entity, registered := idx.entities[s.EntityID]
+	var device *DeviceRegistryEntry
+	if registered && entity.DeviceID != nil {
+		if d, ok := idx.devices[*entity.DeviceID]; ok {
+			device = &d
+		}
+	}
+
+	switch key {
+	case SelectEntity:
+		return []string{s.EntityID}
+	case SelectDomain:
+		return []string{s.Domain()}
+	case SelectState:
+		return []string{s.State}
+	case SelectDeviceClass:
+		if dc, ok := s.Attributes["device_class"].(string); ok {
+			return []string{dc}
+		}
+	case SelectIntegration:
+		if registered {
+			return []string{entity.Platform}
+		}
+	case SelectDevice:
+		if device != nil {
+			return append([]string{device.ID, device.Name}, ptrValues(device.NameByUser)...)
+		}
+	case SelectArea, SelectFloor:
+		area, ok := idx.areas[idx.areaID(entity, device)]
+		if !ok {
+			return nil
+		}
+		if key == SelectArea {
+			return append([]string{area.AreaID, area.Name}, area.Aliases...)
+		}
+		if area.FloorID == nil {
+			return nil
+		}
+		values := []string{*area.FloorID}
+		if f, ok := idx.floors[*area.FloorID]; ok {
+			values = append(values, f.Name)
+			values = append(values, f.Aliases...)
+		}
+		return values
+	case SelectLabel:
+		ids := slices.Clone(entity.Labels)
+		if device != nil {
+			ids = append(ids, device.Labels...)
+		}
+		var values []string
+		for _, id := range ids {
+			values = append(values, id)
+			if l, ok := idx.labels[id]; ok {
+				values = append(values, l.Name)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// areaID returns the area of an entity, inheriting the device's area when
+// the entity has none of its own.
+func (idx *selectIndex) areaID(entity EntityRegistryEntry, device *DeviceRegistryEntry) string {
+	if entity.AreaID != nil {
+		return *entity.AreaID
+	}
+	if device != nil && device.AreaID != nil {
+		return *device.AreaID
+	}
+	return ""
+}
+
+// ptrValues returns the value of p as a slice, empty if p is nil.
+func ptrValues(p *string) []string {
+	if p == nil {
+		return nil
+	}
+	return []string{*p}
+}
+
+// Select returns the states of the entities matching a selection query,
+// sorted by entity ID. See SelectQuery for the query syntax. Registries are
+// only fetched if the query needs them.
+func (c *Client) Select(ctx context.Context, query string) ([]State, error) {
+	q, err := ParseSelectQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	states, err := c.States(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	var reg *Registries
+	if q.NeedsRegistries() {
+		if reg, err = c.registries(ctx); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+	}
+
+	matched := q.Filter(states, reg)
+	slices.SortFunc(matched, func(a, b State) int { return strings.Compare(a.EntityID, b.EntityID) })
+	return matched, nil
+}
+
+// SelectEntityIDs returns the IDs of the entities matching a selection query.
+func (c *Client) SelectEntityIDs(ctx context.Context, query string) ([]string, error) {
+	states, err := c.Select(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(states))
+	for i, s := range states {
+		ids[i] = s.EntityID
+	}
+	return ids, nil
+}
+
+// registries fetches all registries used by selection queries.
+func (c *Client) registries(ctx context.Context) (*Registries, error) {
+	var (
+		reg Registries
+		err error
+	)
+	if reg.Entities, err = c.EntityRegistry(ctx); err != nil {
+		return nil, err
+	}
+	if reg.Devices, err = c.DeviceRegistry(ctx); err != nil {
+		return nil, err
+	}
+	if reg.Areas, err = c.AreaRegistry(ctx); err != nil {
+		return nil, err
+	}
+	if reg.Floors, err = c.FloorRegistry(ctx); err != nil {
+		return nil, err
+	}
+	if reg.Labels, err = c.LabelRegistry(ctx); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
@@ -0,0 +1,106 @@
+package hago
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSelectQuery(t *testing.T) {
+	q, err := ParseSelectQuery(`light.* !state:unavailable domain:light,switch device:"Hall Sensor"`)
+	if err != nil {
+		t.Fatalf("ParseSelectQuery() error = %v", err)
+	}
+	want := []SelectTerm{
+		{Key: SelectEntity, Values: []string{"light.*"}},
+		{Key: SelectState, Values: []string{"unavailable"}, Negate: true},
+		{Key: SelectDomain, Values: []string{"light", "switch"}},
+		{Key: SelectDevice, Values: []string{"hall sensor"}},
+	}
+	if len(q.Terms) != len(want) {
+		t.Fatalf("Terms = %+v, want %+v", q.Terms, want)
+	}
+	for i, term := range q.Terms {
+		if term.Key != want[i].Key || term.Negate != want[i].Negate || !slices.Equal(term.Values, want[i].Values) {
+			t.Errorf("Terms[%d] = %+v, want %+v", i, term, want[i])
+		}
+	}
+	if !q.NeedsRegistries() {
+		t.Error("expected device term to need registries")
+	}
+}
+
+func TestParseSelectQuery_Errors(t *testing.T) {
+	for _, query := range []string{"", "colour:red", "area:", `device:"Hall`, "light.[a"} {
+		if _, err := ParseSelectQuery(query); err == nil {
+			t.Errorf("ParseSelectQuery(%q) expected error", query)
+		}
+	}
+}
+
+func TestSelectQuery_Filter(t *testing.T) {
+	strp := func(s string) *string { return &s }
+	states := []State{
+		{EntityID: "light.kitchen", State: "on"},
+		{EntityID: "light.hall", State: "unavailable"},
+		{EntityID: "sensor.kitchen_temperature", State: "21.5", Attributes: map[string]any{"device_class": "temperature"}},
+		{EntityID: "sensor.bedroom_temperature", State: "19", Attributes: map[string]any{"device_class": "temperature"}},
+		{EntityID: "switch.pump", State: "off"},
+	}
+	reg := &Registries{
+		Entities: []EntityRegistryEntry{
+			{EntityID: "light.kitchen", AreaID: strp("kitchen"), Platform: "hue", Labels: []string{"critical"}},
+			{EntityID: "light.hall", DeviceID: strp("dev-hall"), Platform: "hue"},
+			{EntityID: "sensor.kitchen_temperature", DeviceID: strp("dev-kitchen"), Platform: "zha"},
+			{EntityID: "sensor.bedroom_temperature", AreaID: strp("bedroom"), Platform: "zha"},
+		},
+		Devices: []DeviceRegistryEntry{
+			{ID: "dev-hall", Name: "Hall Sensor", AreaID: strp("hall")},
+			{ID: "dev-kitchen", Name: "Kitchen Multisensor", AreaID: strp("kitchen"), Labels: []string{"critical"}},
+		},
+		Areas: []AreaRegistryEntry{
+			{AreaID: "kitchen", Name: "Kitchen", FloorID: strp("ground")},
+			{AreaID: "hall", Name: "Hall", FloorID: strp("ground")},
+			{AreaID: "bedroom", Name: "Bedroom", FloorID: strp("first")},
+		},
+		Floors: []FloorRegistryEntry{
+			{FloorID: "ground", Name: "Ground Floor"},
+			{FloorID: "first", Name: "Upstairs"},
+		},
+		Labels: []LabelRegistryEntry{
+			{LabelID: "critical", Name: "Critical"},
+		},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"light.*", []string{"light.kitchen", "light.hall"}},
+		{"domain:light !state:unavailable", []string{"light.kitchen"}},
+		{"domain:light,switch state:off,unavailable", []string{"light.hall", "switch.pump"}},
+		{"domain:sensor device_class:temperature area:kitchen", []string{"sensor.kitchen_temperature"}},
+		{"area:Kitchen", []string{"light.kitchen", "sensor.kitchen_temperature"}},
+		{`floor:"ground floor"`, []string{"light.kitchen", "light.hall", "sensor.kitchen_temperature"}},
+		{"floor:upstairs", []string{"sensor.bedroom_temperature"}},
+		{"label:Critical", []string{"light.kitchen", "sensor.kitchen_temperature"}},
+		{`device:"hall sensor"`, []string{"light.hall"}},
+		{"integration:zha *bedroom*", []string{"sensor.bedroom_temperature"}},
+		{"!area:*", []string{"switch.pump"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseSelectQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSelectQuery() error = %v", err)
+			}
+			var got []string
+			for _, s := range q.Filter(states, reg) {
+				got = append(got, s.EntityID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ServiceCallResult is the outcome of a service call made with
+// CallServiceWithResponse or CallServiceTracked.
+type ServiceCallResult struct {
+	// ChangedStates holds the states changed by the call. Only the REST API
+	// reports them; it is empty for calls made over WebSocket.
+	ChangedStates []State `json:"changed_states"`
+	// Response is the service_response payload, typically keyed by entity ID,
+	// e.g. the forecasts returned by weather.get_forecasts.
+	Response json.RawMessage `json:"service_response,omitempty"`
+	// Context is the context Home Assistant assigned to the call. Only the
+	// WebSocket API reports it. State changes, events and logbook entries
+	// caused by the call carry the same context ID.
+	Context *Context `json:"context,omitempty"`
+}
+
+// DecodeResponse decodes the service response into v.
+func (r *ServiceCallResult) DecodeResponse(v any) error {
+	if len(r.Response) == 0 {
+		return fmt.Errorf("service returned no response")
+	}
+	return json.Unmarshal(r.Response, v)
+}
+
+// callServiceCmd is the WebSocket command to call a service.
+type callServiceCmd struct {
+	Type           string         `json:"type"`
+	Domain         string         `json:"domain"`
+	Service        string         `json:"service"`
+	ServiceData    map[string]any `json:"service_data,omitempty"`
+	Target         map[string]any `json:"target,omitempty"`
+	ReturnResponse bool           `json:"return_response,omitempty"`
+}
+
+// callServiceResult is the result of a call_service WebSocket command.
+type callServiceResult struct {
+	Context  *Context        `json:"context"`
+	Response json.RawMessage `json:"response"`
+}
+
+// CallServiceWithResponse calls a service that returns data, such as
+// weather.get_forecasts, calendar.get_events or todo.get_items, and returns
+// the changed states along with the service response.
+//
+// Services that do not support responses fail with ErrBadRequest.
+func (c *Client) CallServiceWithResponse(ctx context.Context, domain, service string, request *ServiceCallRequest) (*ServiceCallResult, error) {
+	if c.transport == TransportWebSocket {
+		return c.callServiceWebSocket(ctx, domain, service, request, true)
+	}
+	path := fmt.Sprintf("/api/services/%s/%s?return_response", domain, service)
+	var result ServiceCallResult
+	if err := c.doJSON(ctx, http.MethodPost, path, request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CallServiceTracked calls a service over WebSocket, regardless of the
+// configured transport, and returns the context of the call. Use the context
+// ID to correlate the call with later state_changed events and logbook
+// entries.
+func (c *Client) CallServiceTracked(ctx context.Context, domain, service string, request *ServiceCallRequest) (*ServiceCallResult, error) {
+	return c.callServiceWebSocket(ctx, domain, service, request, false)
+}
+
+// callServiceWebSocket calls a service with the call_service WebSocket
+// command. Unlike the REST API, the target is sent separately from the
+// service data and the result carries the context of the call.
+func (c *Client) callServiceWebSocket(ctx context.Context, domain, service string, request *ServiceCallRequest, returnResponse bool) (*ServiceCallResult, error) {
+	cmd := callServiceCmd{
+		Type:           "call_service",
+		Domain:         domain,
+		Service:        service,
+		ReturnResponse: returnResponse,
+	}
+	if request != nil {
+		cmd.ServiceData = request.Data
+		if target := request.targetFields(); len(target) > 0 {
+			cmd.Target = target
+		}
+	}
+
+	var result callServiceResult
+	if err := c.wsCommand(ctx, cmd, &result); err != nil {
+		return nil, fmt.Errorf("call service %s.%s: %w", domain, service, err)
+	}
+	return &ServiceCallResult{
+		Response: result.Response,
+		Context:  result.Context,
+	}, nil
+}
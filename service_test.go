@@ -0,0 +1,186 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_CallServiceWithResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/services/calendar/get_events" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if !r.URL.Query().Has("return_response") {
+			t.Errorf("missing return_response in query %q", r.URL.RawQuery)
+		}
+
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["entity_id"] != "calendar.family" || body["duration"] == nil {
+			t.Errorf("unexpected body: %v", body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"changed_states": []map[string]any{{"entity_id": "calendar.family", "state": "off"}},
+			"service_response": map[string]any{
+				"calendar.family": map[string]any{"events": []any{map[string]any{"summary": "Dentist"}}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+
+	result, err := client.CallServiceWithResponse(context.Background(), "calendar", "get_events", &ServiceCallRequest{
+		EntityID: "calendar.family",
+		Data:     map[string]any{"duration": map[string]any{"days": 7}},
+	})
+	if err != nil {
+		t.Fatalf("CallServiceWithResponse() error = %v", err)
+	}
+	if len(result.ChangedStates) != 1 || result.ChangedStates[0].EntityID != "calendar.family" {
+		t.Errorf("ChangedStates = %+v", result.ChangedStates)
+	}
+
+	var response map[string]struct {
+		Events []CalendarEvent `json:"events"`
+	}
+	if err := result.DecodeResponse(&response); err != nil {
+		t.Fatalf("DecodeResponse() error = %v", err)
+	}
+	if events := response["calendar.family"].Events; len(events) != 1 || events[0].Summary != "Dentist" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestClient_CallServiceWebSocket(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+
+		if cmd["type"] != "call_service" || cmd["domain"] != "todo" || cmd["service"] != "get_items" {
+			t.Errorf("unexpected command: %v", cmd)
+		}
+		if cmd["return_response"] != true {
+			t.Errorf("return_response = %v, want true", cmd["return_response"])
+		}
+		target, _ := cmd["target"].(map[string]any)
+		if target["entity_id"] != "todo.shopping" {
+			t.Errorf("target = %v", cmd["target"])
+		}
+		data, _ := cmd["service_data"].(map[string]any)
+		if data["status"] != "needs_action" {
+			t.Errorf("service_data = %v", cmd["service_data"])
+		}
+
+		conn.WriteJSON(map[string]any{
+			"id":      cmd["id"],
+			"type":    "result",
+			"success": true,
+			"result": map[string]any{
+				"context":  map[string]any{"id": "ctx-1", "user_id": "user-1"},
+				"response": map[string]any{"todo.shopping": map[string]any{"items": []any{}}},
+			},
+		})
+
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	result, err := client.callServiceWebSocket(context.Background(), "todo", "get_items", &ServiceCallRequest{
+		EntityID: "todo.shopping",
+		Data:     map[string]any{"status": "needs_action"},
+	}, true)
+	if err != nil {
+		t.Fatalf("callServiceWebSocket() error = %v", err)
+	}
+	if result.Context == nil || result.Context.ID != "ctx-1" || result.Context.UserID != "user-1" {
+		t.Errorf("Context = %+v", result.Context)
+	}
+	if len(result.Response) == 0 {
+		t.Error("expected response payload")
+	}
+}
+
+func TestClient_WithTransportWebSocket(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []map[string]any
+	)
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		for {
+			var cmd map[string]any
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			mu.Lock()
+			commands = append(commands, cmd)
+			mu.Unlock()
+			conn.WriteJSON(map[string]any{
+				"id":      cmd["id"],
+				"type":    "result",
+				"success": true,
+				"result":  map[string]any{"context": map[string]any{"id": "ctx"}},
+			})
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithTransport(TransportWebSocket))
+	ctx := context.Background()
+
+	states, err := client.CallService(ctx, "light", "turn_off", &ServiceCallRequest{
+		Target: NewTarget().Areas("kitchen"),
+	})
+	if err != nil {
+		t.Fatalf("CallService() error = %v", err)
+	}
+	if states != nil {
+		t.Errorf("CallService() states = %v, want nil", states)
+	}
+	if err := client.FireEvent(ctx, "my_event", nil); err != nil {
+		t.Fatalf("FireEvent() error = %v", err)
+	}
+	client.CloseWebSocket()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(commands) != 2 {
+		t.Fatalf("got %d commands, want 2", len(commands))
+	}
+	if commands[0]["type"] != "call_service" || commands[0]["return_response"] != nil {
+		t.Errorf("unexpected call_service command: %v", commands[0])
+	}
+	target, _ := commands[0]["target"].(map[string]any)
+	if areas, _ := target["area_id"].([]any); len(areas) != 1 || areas[0] != "kitchen" {
+		t.Errorf("target = %v", commands[0]["target"])
+	}
+	if commands[1]["type"] != "fire_event" || commands[1]["event_type"] != "my_event" {
+		t.Errorf("unexpected fire_event command: %v", commands[1])
+	}
+}
@@ -0,0 +1,303 @@
+// Package servicegen generates typed Go wrappers for Home Assistant services
+// from the service catalog returned by hago.Client.Services.
+//
+// One package is generated per domain, with a parameter struct and a
+// function per service:
+//
+//	_, err := light.TurnOn(ctx, client, hago.NewTarget().Areas("kitchen"), light.TurnOnParams{
+//	    BrightnessPct: hago.Ptr(80),
+//	})
+//
+// Field types are derived from the field selectors; fields without a
+// selector, or with a selector that has no natural Go type, are typed any.
+package servicegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+// Options controls code generation.
+type Options struct {
+	// Domains limits generation to these domains. Empty means all domains.
+	Domains []string
+}
+
+// File is a generated source file.
+type File struct {
+	// Path is the file path relative to the output directory, e.g.
+	// "light/light.go".
+	Path    string
+	Content []byte
+}
+
+// Generate returns one formatted Go file per domain.
+func Generate(services []hago.Service, opts Options) ([]File, error) {
+	var files []File
+	for _, svc := range services {
+		if len(opts.Domains) > 0 && !slices.Contains(opts.Domains, svc.Domain) {
+			continue
+		}
+		if len(svc.Services) == 0 {
+			continue
+		}
+
+		pkg := PackageName(svc.Domain)
+		src, err := generateDomain(pkg, svc)
+		if err != nil {
+			return nil, fmt.Errorf("generate %s: %w", svc.Domain, err)
+		}
+		files = append(files, File{Path: path.Join(pkg, pkg+".go"), Content: src})
+	}
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+	return files, nil
+}
+
+// generateDomain generates the package for one domain.
+func generateDomain(pkg string, svc hago.Service) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by hago gen services; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "// Package %s provides typed wrappers for the %s services.\n", pkg, svc.Domain)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\t\"context\"\n\n\t\"github.com/rmrfslashbin/hago\"\n)\n")
+
+	// Each service declares a function and its params struct, so both names
+	// are reserved: turn_on_params would otherwise clash with TurnOnParams.
+	names := make(map[string]string)
+	for _, service := range slices.Sorted(maps.Keys(svc.Services)) {
+		name := Identifier(service)
+		for _, ident := range []string{name, name + "Params"} {
+			if other, ok := names[ident]; ok {
+				return nil, fmt.Errorf("services %s and %s both map to %s", other, service, ident)
+			}
+		}
+		names[name] = service
+		names[name+"Params"] = service
+
+		details := svc.Services[service]
+		schema, err := hago.NewServiceSchema(svc.Domain, service, details)
+		if err != nil {
+			return nil, err
+		}
+		writeService(&b, svc.Domain, service, name, details, schema)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format source: %w", err)
+	}
+	return src, nil
+}
+
+// writeService writes the parameter struct and function for one service.
+func writeService(b *bytes.Buffer, domain, service, name string, details hago.ServiceDetails, schema *hago.ServiceSchema) {
+	params := name + "Params"
+
+	fmt.Fprintf(b, "\n// %s holds the data fields of %s.%s.\n", params, domain, service)
+	fmt.Fprintf(b, "type %s struct {\n", params)
+	fields := make(map[string]string)
+	for _, field := range slices.Sorted(maps.Keys(schema.Fields)) {
+		rule := schema.Fields[field]
+		ident := Identifier(field)
+		for fields[ident] != "" {
+			ident += "_"
+		}
+		fields[ident] = field
+
+		goType, tag := FieldType(rule)
+		b.WriteString(comment("\t", fieldDoc(rule)))
+		fmt.Fprintf(b, "\t%s %s `json:%q`\n", ident, goType, tag)
+	}
+	b.WriteString("}\n")
+
+	fmt.Fprintf(b, "\n// %s calls %s.%s.\n", name, domain, service)
+	if doc := strings.TrimSpace(details.Description); doc != "" {
+		b.WriteString("//\n" + comment("", doc))
+	}
+
+	targetParam, targetArg := "", "nil"
+	if details.Target != nil {
+		targetParam, targetArg = ", target *hago.Target", "target"
+	}
+
+	if details.Response != nil {
+		fmt.Fprintf(b, "func %s(ctx context.Context, c *hago.Client%s, params %s) (*hago.ServiceCallResult, error) {\n", name, targetParam, params)
+	} else {
+		fmt.Fprintf(b, "func %s(ctx context.Context, c *hago.Client%s, params %s) ([]hago.State, error) {\n", name, targetParam, params)
+	}
+	fmt.Fprintf(b, "\treq, err := hago.NewServiceCallRequest(%s, params)\n", targetArg)
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	if details.Response != nil {
+		fmt.Fprintf(b, "\treturn c.CallServiceWithResponse(ctx, %q, %q, req)\n", domain, service)
+	} else {
+		fmt.Fprintf(b, "\treturn c.CallService(ctx, %q, %q, req)\n", domain, service)
+	}
+	b.WriteString("}\n")
+}
+
+// FieldType returns the Go type and JSON tag of a field. Required fields use
+// value types. Optional numbers and booleans are pointers so zero values can
+// be sent; optional string-like fields are plain strings with omitempty, so
+// an empty string means "not set" and is never sent.
+func FieldType(rule hago.FieldRule) (goType, tag string) {
+	optional := !rule.Required
+	tag = rule.Name
+	if optional {
+		tag += ",omitempty"
+	}
+
+	sel := rule.Selector
+	if sel == nil {
+		return "any", tag
+	}
+
+	scalar := func(t string) string {
+		if optional {
+			return "*" + t
+		}
+		return t
+	}
+	switch sel.Type {
+	case "number":
+		if isInteger(sel.Step) && isInteger(sel.Min) && isInteger(sel.Max) {
+			return scalar("int"), tag
+		}
+		return scalar("float64"), tag
+	case "color_temp":
+		return scalar("int"), tag
+	case "boolean":
+		return scalar("bool"), tag
+	case "color_rgb":
+		return "[]int", tag
+	case "select", "entity", "text", "icon", "theme", "time", "date", "datetime",
+		"area", "device", "floor", "label", "conversation_agent", "language":
+		if sel.Multiple {
+			return "[]string", tag
+		}
+		return "string", tag
+	default:
+		return "any", tag
+	}
+}
+
+// isInteger reports whether f is unset or a whole number.
+func isInteger(f *float64) bool {
+	return f == nil || *f == float64(int64(*f))
+}
+
+// fieldDoc builds the doc comment of a field from its description and rules.
+func fieldDoc(rule hago.FieldRule) string {
+	var parts []string
+	if d := strings.TrimSpace(rule.Description); d != "" {
+		parts = append(parts, strings.TrimSuffix(d, ".")+".")
+	}
+	if rule.Required {
+		parts = append(parts, "Required.")
+	}
+	if sel := rule.Selector; sel != nil {
+		switch {
+		case sel.Min != nil && sel.Max != nil:
+			parts = append(parts, fmt.Sprintf("Range %v to %v%s.", *sel.Min, *sel.Max, unitSuffix(sel.Unit)))
+		case sel.Min != nil:
+			parts = append(parts, fmt.Sprintf("Minimum %v%s.", *sel.Min, unitSuffix(sel.Unit)))
+		case sel.Max != nil:
+			parts = append(parts, fmt.Sprintf("Maximum %v%s.", *sel.Max, unitSuffix(sel.Unit)))
+		}
+		if len(sel.Options) > 0 {
+			values := make([]string, len(sel.Options))
+			for i, opt := range sel.Options {
+				values[i] = opt.Value
+			}
+			parts = append(parts, "One of: "+strings.Join(values, ", ")+".")
+		}
+		if len(sel.Domains) > 0 {
+			parts = append(parts, "Entities in domain "+strings.Join(sel.Domains, " or ")+".")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// unitSuffix formats a unit for a range description.
+func unitSuffix(unit string) string {
+	if unit == "" {
+		return ""
+	}
+	return " " + unit
+}
+
+// comment formats text as a doc comment, wrapped at 77 columns.
+func comment(indent, text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	line := indent + "//"
+	for _, w := range words {
+		if len(line)+1+len(w) > 77 && line != indent+"//" {
+			b.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + w
+	}
+	b.WriteString(line + "\n")
+	return b.String()
+}
+
+// initialisms are name parts written in upper case in Go identifiers.
+var initialisms = map[string]bool{
+	"api": true, "hs": true, "hvac": true, "id": true, "ip": true, "json": true,
+	"rgb": true, "rgbw": true, "rgbww": true, "tts": true, "uri": true,
+	"url": true, "uuid": true, "xy": true,
+}
+
+// Identifier converts a snake_case service or field name to an exported Go
+// identifier, e.g. "set_hvac_mode" to "SetHVACMode".
+func Identifier(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	ident := b.String()
+	if ident == "" || unicode.IsDigit([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// PackageName converts a domain to a Go package name, e.g. "input_boolean"
+// to "inputboolean". Domains that are Go keywords, such as "select", get a
+// "domain" suffix; domains starting with a digit get an "ha" prefix.
+func PackageName(domain string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, domain)
+	if name == "" || token.IsKeyword(name) {
+		name += "domain"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "ha" + name
+	}
+	return name
+}
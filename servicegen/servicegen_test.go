@@ -0,0 +1,228 @@
+package servicegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/hago"
+)
+
+var testServices = []hago.Service{
+	{
+		Domain: "light",
+		Services: map[string]hago.ServiceDetails{
+			"turn_on": {
+				Description: "Turns on one or more lights and adjusts their properties, even when they are turned on already.",
+				Target:      &hago.ServiceTarget{Entity: []hago.TargetSelector{{Domain: []string{"light"}}}},
+				Fields: map[string]hago.ServiceField{
+					"transition": {
+						Description: "Duration it takes to get to next state.",
+						Selector:    map[string]any{"number": map[string]any{"min": 0.0, "max": 300.0, "step": 0.5, "unit_of_measurement": "seconds"}},
+					},
+					"advanced_fields": {Fields: map[string]hago.ServiceField{
+						"brightness_pct": {Selector: map[string]any{"number": map[string]any{"min": 0.0, "max": 100.0}}},
+						"rgb_color":      {Selector: map[string]any{"color_rgb": map[string]any{}}},
+						"flash":          {Selector: map[string]any{"select": map[string]any{"options": []any{"long", "short"}}}},
+					}},
+				},
+			},
+		},
+	},
+	{
+		Domain: "weather",
+		Services: map[string]hago.ServiceDetails{
+			"get_forecasts": {
+				Target:   &hago.ServiceTarget{Entity: []hago.TargetSelector{{Domain: []string{"weather"}}}},
+				Response: &hago.ServiceResponseSpec{},
+				Fields: map[string]hago.ServiceField{
+					"type": {Required: true, Selector: map[string]any{"select": map[string]any{"options": []any{"daily", "hourly"}}}},
+				},
+			},
+		},
+	},
+	{
+		Domain: "select",
+		Services: map[string]hago.ServiceDetails{
+			"select_next": {
+				Fields: map[string]hago.ServiceField{
+					"cycle": {Selector: map[string]any{"boolean": nil}},
+				},
+			},
+		},
+	},
+}
+
+func TestGenerate(t *testing.T) {
+	files, err := Generate(testServices, Options{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+
+	paths := []string{files[0].Path, files[1].Path, files[2].Path}
+	want := []string{"light/light.go", "selectdomain/selectdomain.go", "weather/weather.go"}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("paths = %v, want %v", paths, want)
+			break
+		}
+	}
+
+	light := normalize(files[0].Content)
+	for _, s := range []string{
+		"// Code generated by hago gen services; DO NOT EDIT.",
+		"package light",
+		"// TurnOn calls light.turn_on. // // Turns on one or more lights",
+		"// Duration it takes to get to next state. Range 0 to 300 seconds.",
+		"func TurnOn(ctx context.Context, c *hago.Client, target *hago.Target, params TurnOnParams) ([]hago.State, error) {",
+		"BrightnessPct *int `json:\"brightness_pct,omitempty\"`",
+		"Transition *float64 `json:\"transition,omitempty\"`",
+		"RGBColor []int `json:\"rgb_color,omitempty\"`",
+		"Flash string `json:\"flash,omitempty\"`",
+		"One of: long, short.",
+		"Range 0 to 300 seconds.",
+		`return c.CallService(ctx, "light", "turn_on", req)`,
+	} {
+		if !strings.Contains(light, s) {
+			t.Errorf("light.go missing %q:\n%s", s, light)
+		}
+	}
+
+	weather := normalize(files[2].Content)
+	for _, s := range []string{
+		"Type string `json:\"type\"`",
+		"(*hago.ServiceCallResult, error)",
+		`return c.CallServiceWithResponse(ctx, "weather", "get_forecasts", req)`,
+	} {
+		if !strings.Contains(weather, s) {
+			t.Errorf("weather.go missing %q:\n%s", s, weather)
+		}
+	}
+
+	sel := normalize(files[1].Content)
+	if !strings.Contains(sel, "func SelectNext(ctx context.Context, c *hago.Client, params SelectNextParams)") {
+		t.Errorf("select_next without target should not take one:\n%s", sel)
+	}
+}
+
+// normalize collapses runs of whitespace so checks ignore gofmt alignment
+// and comment wrapping.
+func normalize(src []byte) string {
+	return strings.Join(strings.Fields(string(src)), " ")
+}
+
+func TestGenerate_Domains(t *testing.T) {
+	files, err := Generate(testServices, Options{Domains: []string{"weather"}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "weather/weather.go" {
+		t.Errorf("files = %v", files)
+	}
+}
+
+func TestGenerate_CollidingFields(t *testing.T) {
+	services := []hago.Service{{
+		Domain: "light",
+		Services: map[string]hago.ServiceDetails{
+			"turn_on": {
+				Fields: map[string]hago.ServiceField{
+					"color_temp": {Selector: map[string]any{"color_temp": map[string]any{}}},
+					"color-temp": {Selector: map[string]any{"color_temp": map[string]any{}}},
+					"colorTemp":  {Selector: map[string]any{"color_temp": map[string]any{}}},
+				},
+			},
+		},
+	}}
+	files, err := Generate(services, Options{})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	light := normalize(files[0].Content)
+	for _, s := range []string{
+		"ColorTemp *int `json:\"color-temp,omitempty\"`",
+		"ColorTemp_ *int `json:\"colorTemp,omitempty\"`",
+		"ColorTemp__ *int `json:\"color_temp,omitempty\"`",
+	} {
+		if !strings.Contains(light, s) {
+			t.Errorf("light.go missing %q:\n%s", s, light)
+		}
+	}
+}
+
+func TestGenerate_CollidingServices(t *testing.T) {
+	for _, names := range [][]string{
+		{"turn_on", "turn-on"},
+		{"turn_on", "turn_on_params"},
+	} {
+		services := []hago.Service{{Domain: "light", Services: map[string]hago.ServiceDetails{}}}
+		for _, name := range names {
+			services[0].Services[name] = hago.ServiceDetails{}
+		}
+		_, err := Generate(services, Options{})
+		if err == nil || !strings.Contains(err.Error(), "both map to") {
+			t.Errorf("Generate(%v) error = %v, want a collision error", names, err)
+		}
+	}
+}
+
+func TestFieldType(t *testing.T) {
+	tests := []struct {
+		name     string
+		required bool
+		selector hago.Selector
+		wantType string
+		wantTag  string
+	}{
+		{"optional number", false, hago.Selector{Type: "number"}, "*int", "f,omitempty"},
+		{"required number", true, hago.Selector{Type: "number"}, "int", "f"},
+		{"optional boolean", false, hago.Selector{Type: "boolean"}, "*bool", "f,omitempty"},
+		// An empty string cannot be sent for optional string-like fields
+		{"optional text", false, hago.Selector{Type: "text"}, "string", "f,omitempty"},
+		{"optional select", false, hago.Selector{Type: "select"}, "string", "f,omitempty"},
+		{"required entity", true, hago.Selector{Type: "entity"}, "string", "f"},
+		{"multiple entities", false, hago.Selector{Type: "entity", Multiple: true}, "[]string", "f,omitempty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := tt.selector
+			goType, tag := FieldType(hago.FieldRule{Name: "f", Required: tt.required, Selector: &sel})
+			if goType != tt.wantType || tag != tt.wantTag {
+				t.Errorf("FieldType() = (%s, %s), want (%s, %s)", goType, tag, tt.wantType, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"turn_on":        "TurnOn",
+		"set_hvac_mode":  "SetHVACMode",
+		"rgbww_color":    "RGBWWColor",
+		"entity_id":      "EntityID",
+		"3d_print":       "X3dPrint",
+		"brightness-pct": "BrightnessPct",
+	}
+	for in, want := range tests {
+		if got := Identifier(in); got != want {
+			t.Errorf("Identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"light":         "light",
+		"input_boolean": "inputboolean",
+		"select":        "selectdomain",
+		"go":            "godomain",
+		"3dprinter":     "ha3dprinter",
+	}
+	for in, want := range tests {
+		if got := PackageName(in); got != want {
+			t.Errorf("PackageName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
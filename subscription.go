@@ -0,0 +1,257 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// unsubscribeTimeout bounds how long we wait for HA to acknowledge an
+// unsubscribe_events command when a subscription ends.
+const unsubscribeTimeout = 5 * time.Second
+
+// queue is an unbounded FIFO for a single consumer. Producers never block,
+// so a slow consumer cannot stall the connection reader (and with it every
+// other command on the connection).
+type queue[T any] struct {
+	mu     sync.Mutex
+	items  []T
+	notify chan struct{}
+}
+
+// newQueue creates an empty queue.
+func newQueue[T any]() *queue[T] {
+	return &queue[T]{notify: make(chan struct{}, 1)}
+}
+
+// push appends an item and wakes the consumer.
+func (q *queue[T]) push(item T) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns and clears all queued items.
+func (q *queue[T]) pop() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// wsSubscription is an active WebSocket subscription.
+// A nil event is queued when the subscription is replayed after a reconnect,
+// so consumers that keep derived state know to resynchronize.
+type wsSubscription struct {
+	cmd    any
+	events *queue[json.RawMessage]
+
+	mu sync.Mutex
+	ws *wsConn
+	id int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// unsubscribeCmd is the WebSocket command to end a subscription.
+type unsubscribeCmd struct {
+	Type         string `json:"type"`
+	Subscription int64  `json:"subscription"`
+}
+
+// newSubscription creates a subscription for the given command.
+func newSubscription(cmd any) *wsSubscription {
+	return &wsSubscription{
+		cmd:    cmd,
+		events: newQueue[json.RawMessage](),
+		done:   make(chan struct{}),
+	}
+}
+
+// close marks the subscription as finished.
+func (s *wsSubscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// subscribe registers the subscription on the connection and sends its command.
+// The subscription is registered before the command is sent because HA may
+// deliver the first event immediately after the result.
+func (ws *wsConn) subscribe(ctx context.Context, sub *wsSubscription) error {
+	id := ws.msgID.Add(1)
+
+	ws.pendingMu.Lock()
+	ws.subs[id] = sub
+	ws.pendingMu.Unlock()
+
+	sub.mu.Lock()
+	sub.ws = ws
+	sub.id = id
+	sub.mu.Unlock()
+
+	if _, err := ws.sendCommandID(ctx, id, sub.cmd); err != nil {
+		ws.pendingMu.Lock()
+		delete(ws.subs, id)
+		ws.pendingMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// unsubscribe removes a subscription from the connection and tells HA to stop
+// sending its events. Errors are ignored; the connection may already be gone.
+func (ws *wsConn) unsubscribe(id int64) {
+	ws.pendingMu.Lock()
+	delete(ws.subs, id)
+	ws.pendingMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), unsubscribeTimeout)
+	defer cancel()
+	_, _ = ws.sendCommand(ctx, unsubscribeCmd{
+		Type:         "unsubscribe_events",
+		Subscription: id,
+	})
+}
+
+// subscribe issues a subscription command and returns the subscription.
+// The subscription ends when ctx is done, when CloseWebSocket is called, or
+// when the connection is lost and automatic reconnection is not enabled.
+func (c *Client) subscribe(ctx context.Context, cmd any) (*wsSubscription, error) {
+	sub := newSubscription(cmd)
+
+	// Hold wsMu so a concurrent reconnect cannot replay the subscription
+	// before it has been registered.
+	c.wsMu.Lock()
+	connected, err := c.connectLocked(ctx)
+	if err == nil {
+		err = c.ws.subscribe(ctx, sub)
+	}
+	if err == nil {
+		c.subsMu.Lock()
+		c.subs[sub] = struct{}{}
+		c.subsMu.Unlock()
+	}
+	c.wsMu.Unlock()
+
+	if connected {
+		c.notifyConnectionState(ConnectionConnected)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.unsubscribe(sub)
+		case <-sub.done:
+		}
+		c.removeSubscription(sub)
+	}()
+
+	return sub, nil
+}
+
+// unsubscribe cancels a subscription on whichever connection it is currently on.
+func (c *Client) unsubscribe(sub *wsSubscription) {
+	sub.mu.Lock()
+	ws, id := sub.ws, sub.id
+	sub.mu.Unlock()
+
+	if ws != nil {
+		ws.unsubscribe(id)
+	}
+}
+
+// removeSubscription forgets a subscription and marks it finished.
+func (c *Client) removeSubscription(sub *wsSubscription) {
+	c.subsMu.Lock()
+	delete(c.subs, sub)
+	c.subsMu.Unlock()
+	sub.close()
+}
+
+// replaySubscriptions re-issues every active subscription on a new
+// connection. HA assigns events by message ID, so each subscription gets a
+// new ID. Subscriptions that HA rejects are ended. The caller must hold c.wsMu.
+func (c *Client) replaySubscriptions(ctx context.Context, ws *wsConn) {
+	c.subsMu.Lock()
+	subs := make([]*wsSubscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.subsMu.Unlock()
+
+	for _, sub := range subs {
+		sub.events.push(nil)
+		if err := ws.subscribe(ctx, sub); err != nil {
+			c.removeSubscription(sub)
+		}
+	}
+}
+
+// closeSubscriptions ends every subscription attached to ws, or every
+// subscription if ws is nil.
+func (c *Client) closeSubscriptions(ws *wsConn) {
+	c.subsMu.Lock()
+	var subs []*wsSubscription
+	for sub := range c.subs {
+		sub.mu.Lock()
+		attached := ws == nil || sub.ws == ws
+		sub.mu.Unlock()
+		if attached {
+			subs = append(subs, sub)
+		}
+	}
+	c.subsMu.Unlock()
+
+	for _, sub := range subs {
+		c.removeSubscription(sub)
+	}
+}
+
+// deliver decodes queued subscription events and sends them on out until the
+// subscription ends or ctx is done. Replay markers and events that decode
+// reports as not ok are skipped. out is closed on return.
+func deliver[T any](ctx context.Context, sub *wsSubscription, out chan<- T, decode func(json.RawMessage) (T, bool)) {
+	defer close(out)
+
+	for {
+		finished := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.events.notify:
+		case <-sub.done:
+			finished = true
+		}
+
+		for _, raw := range sub.events.pop() {
+			if raw == nil {
+				continue
+			}
+			v, ok := decode(raw)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if finished {
+			return
+		}
+	}
+}
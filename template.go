@@ -0,0 +1,123 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TemplateStreamOptions contains options for streaming template rendering.
+type TemplateStreamOptions struct {
+	// Timeout limits how long HA spends on the initial render.
+	Timeout time.Duration
+	// Strict makes references to undefined variables an error.
+	Strict bool
+	// ReportErrors delivers render errors and warnings as updates instead of
+	// only logging them in Home Assistant.
+	ReportErrors bool
+}
+
+// TemplateUpdate is delivered each time a streamed template is rendered.
+// Exactly one of Result or Err is meaningful: Err is set for errors and
+// warnings reported when ReportErrors is enabled.
+type TemplateUpdate struct {
+	// Result is the rendered value. HA parses results into native types, so
+	// this may be a string, number, bool, slice or map.
+	Result any
+	// Listeners describes what HA is tracking to re-render the template.
+	Listeners *TemplateListeners
+	// Err is a render error or warning reported by HA.
+	Err *TemplateError
+}
+
+// TemplateListeners describes what triggers a template to re-render.
+type TemplateListeners struct {
+	All      bool     `json:"all"`
+	Domains  []string `json:"domains"`
+	Entities []string `json:"entities"`
+	Time     bool     `json:"time"`
+}
+
+// TemplateError is a template error or warning reported by Home Assistant.
+type TemplateError struct {
+	Message string
+	// Level is "ERROR" or "WARNING".
+	Level string
+}
+
+// Error implements the error interface.
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template %s: %s", e.Level, e.Message)
+}
+
+// IsWarning reports whether the error is only a warning.
+func (e *TemplateError) IsWarning() bool {
+	return e.Level == "WARNING"
+}
+
+// renderTemplateCmd is the WebSocket command to subscribe to a template.
+type renderTemplateCmd struct {
+	Type         string         `json:"type"`
+	Template     string         `json:"template"`
+	Variables    map[string]any `json:"variables,omitempty"`
+	Timeout      float64        `json:"timeout,omitempty"`
+	Strict       bool           `json:"strict,omitempty"`
+	ReportErrors bool           `json:"report_errors,omitempty"`
+}
+
+// renderTemplateMessage is an event from a render_template subscription.
+type renderTemplateMessage struct {
+	Result    any                `json:"result"`
+	Listeners *TemplateListeners `json:"listeners"`
+	Error     *string            `json:"error"`
+	Level     string             `json:"level"`
+}
+
+// RenderTemplateStream renders a Jinja2 template and re-renders it whenever
+// the entities, domains or time it depends on change. An update is pushed on
+// the returned channel after the initial render and after every re-render.
+//
+// Errors in the initial render are returned directly. The channel is closed
+// when ctx is done, at which point the subscription is cancelled.
+func (c *Client) RenderTemplateStream(ctx context.Context, template string, variables map[string]any, opts *TemplateStreamOptions) (<-chan TemplateUpdate, error) {
+	cmd := renderTemplateCmd{
+		Type:      "render_template",
+		Template:  template,
+		Variables: variables,
+	}
+	if opts != nil {
+		cmd.Timeout = opts.Timeout.Seconds()
+		cmd.Strict = opts.Strict
+		cmd.ReportErrors = opts.ReportErrors
+	}
+
+	sub, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+
+	updates := make(chan TemplateUpdate)
+	go deliver(ctx, sub, updates, decodeTemplateUpdate)
+
+	return updates, nil
+}
+
+// decodeTemplateUpdate decodes a render_template event.
+func decodeTemplateUpdate(raw json.RawMessage) (TemplateUpdate, bool) {
+	var update TemplateUpdate
+
+	var msg renderTemplateMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return update, false
+	}
+
+	if msg.Error != nil {
+		update.Err = &TemplateError{Message: *msg.Error, Level: msg.Level}
+		return update, true
+	}
+
+	update.Result = msg.Result
+	update.Listeners = msg.Listeners
+	return update, true
+}
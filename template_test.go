@@ -0,0 +1,165 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_RenderTemplateStream(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		// Auth flow
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+
+		if cmd["type"] != "render_template" {
+			t.Errorf("expected render_template, got %v", cmd["type"])
+		}
+		if cmd["template"] != "{{ states('sensor.power') | float * factor }}" {
+			t.Errorf("unexpected template: %v", cmd["template"])
+		}
+		if cmd["strict"] != true || cmd["report_errors"] != true {
+			t.Errorf("expected strict and report_errors, got %v", cmd)
+		}
+		if cmd["timeout"] != float64(3) {
+			t.Errorf("timeout = %v, want 3", cmd["timeout"])
+		}
+		variables, _ := cmd["variables"].(map[string]any)
+		if variables["factor"] != float64(2) {
+			t.Errorf("unexpected variables: %v", cmd["variables"])
+		}
+
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+
+		listeners := map[string]any{
+			"all":      false,
+			"domains":  []string{},
+			"entities": []string{"sensor.power"},
+			"time":     false,
+		}
+		conn.WriteJSON(map[string]any{
+			"id":    cmd["id"],
+			"type":  "event",
+			"event": map[string]any{"result": 200.0, "listeners": listeners},
+		})
+		conn.WriteJSON(map[string]any{
+			"id":    cmd["id"],
+			"type":  "event",
+			"event": map[string]any{"error": "'unknown' is undefined", "level": "WARNING"},
+		})
+		conn.WriteJSON(map[string]any{
+			"id":    cmd["id"],
+			"type":  "event",
+			"event": map[string]any{"result": 250.0, "listeners": listeners},
+		})
+
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := client.RenderTemplateStream(ctx,
+		"{{ states('sensor.power') | float * factor }}",
+		map[string]any{"factor": 2},
+		&TemplateStreamOptions{Timeout: 3 * time.Second, Strict: true, ReportErrors: true},
+	)
+	if err != nil {
+		t.Fatalf("RenderTemplateStream() error = %v", err)
+	}
+
+	next := func() TemplateUpdate {
+		t.Helper()
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for template update")
+		}
+		return TemplateUpdate{}
+	}
+
+	first := next()
+	if first.Err != nil {
+		t.Fatalf("unexpected error update: %v", first.Err)
+	}
+	if first.Result != 200.0 {
+		t.Errorf("Result = %v, want 200", first.Result)
+	}
+	if first.Listeners == nil || len(first.Listeners.Entities) != 1 || first.Listeners.Entities[0] != "sensor.power" {
+		t.Errorf("Listeners = %+v, want sensor.power", first.Listeners)
+	}
+
+	warning := next()
+	if warning.Err == nil {
+		t.Fatal("expected error update")
+	}
+	if !warning.Err.IsWarning() {
+		t.Errorf("expected warning level, got %v", warning.Err.Level)
+	}
+	if warning.Result != nil {
+		t.Errorf("expected no result with error update, got %v", warning.Result)
+	}
+
+	if second := next(); second.Result != 250.0 {
+		t.Errorf("Result = %v, want 250", second.Result)
+	}
+}
+
+func TestClient_RenderTemplateStreamError(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(map[string]any{
+			"id":      cmd["id"],
+			"type":    "result",
+			"success": false,
+			"error":   map[string]any{"code": "template_error", "message": "unexpected '}'"},
+		})
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	_, err := client.RenderTemplateStream(context.Background(), "{{ }", nil, nil)
+	var wsErr *WebSocketError
+	if !errors.As(err, &wsErr) {
+		t.Fatalf("expected *WebSocketError, got %v", err)
+	}
+	if wsErr.Code != "template_error" {
+		t.Errorf("Code = %v, want template_error", wsErr.Code)
+	}
+}
+
+func TestTemplateError_Error(t *testing.T) {
+	err := &TemplateError{Message: "boom", Level: "ERROR"}
+	if got, want := err.Error(), "template ERROR: boom"; got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
+	}
+	if err.IsWarning() {
+		t.Error("expected ERROR level not to be a warning")
+	}
+}
@@ -0,0 +1,38 @@
+package hago
+
+// Transport selects the API used for control operations such as calling
+// services and firing events.
+type Transport int
+
+// Transports for control operations.
+const (
+	// TransportREST sends control operations as REST requests. This is the
+	// default.
+	TransportREST Transport = iota
+	// TransportWebSocket sends control operations as WebSocket commands,
+	// multiplexed on the connection shared with subscriptions.
+	TransportWebSocket
+)
+
+// String returns the name of the transport.
+func (t Transport) String() string {
+	switch t {
+	case TransportREST:
+		return "rest"
+	case TransportWebSocket:
+		return "websocket"
+	default:
+		return "unknown"
+	}
+}
+
+// WithTransport selects the transport used by CallService,
+// CallServiceWithResponse and FireEvent. Over WebSocket, CallService and
+// FireEvent avoid a request per call, but CallService returns no changed
+// states because the call_service command does not report them.
+func WithTransport(transport Transport) Option {
+	return func(c *Client) error {
+		c.transport = transport
+		return nil
+	}
+}
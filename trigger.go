@@ -0,0 +1,114 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TriggerEvent is delivered each time a subscribed trigger fires.
+type TriggerEvent struct {
+	// Variables holds all trigger variables, including the variables passed
+	// to SubscribeTrigger and the raw "trigger" object.
+	Variables map[string]any
+	// Trigger is the decoded "trigger" variable.
+	Trigger TriggerData
+	// Context is the context of the action that fired the trigger, if any.
+	Context *Context
+}
+
+// TriggerData is the "trigger" variable available to automations, i.e.
+// trigger.platform, trigger.to_state and so on.
+// Fields that do not apply to the trigger platform are left empty;
+// platform-specific values not covered here are available in Raw.
+type TriggerData struct {
+	ID          string `json:"id"`
+	Idx         string `json:"idx"`
+	Alias       string `json:"alias"`
+	Platform    string `json:"platform"`
+	Description string `json:"description"`
+	EntityID    string `json:"entity_id"`
+	Attribute   string `json:"attribute"`
+	FromState   *State `json:"from_state"`
+	ToState     *State `json:"to_state"`
+	// Raw holds the full trigger object as received.
+	Raw map[string]any `json:"-"`
+}
+
+// triggerMessage is an event from a subscribe_trigger subscription.
+type triggerMessage struct {
+	Variables json.RawMessage `json:"variables"`
+	Context   *Context        `json:"context"`
+}
+
+// subscribeTriggerCmd is the WebSocket command to subscribe to a trigger.
+type subscribeTriggerCmd struct {
+	Type      string         `json:"type"`
+	Trigger   any            `json:"trigger"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// SubscribeTrigger subscribes to one or more Home Assistant triggers using
+// HA's own trigger engine. The trigger uses the same structure as an
+// automation trigger, e.g.
+//
+//	map[string]any{"platform": "state", "entity_id": "binary_sensor.door", "to": "on"}
+//
+// or a slice of such maps. Variables are made available to templates in the
+// trigger and are echoed back in each TriggerEvent.
+//
+// Events are delivered on the returned channel until ctx is done, at which
+// point the subscription is cancelled and the channel is closed.
+func (c *Client) SubscribeTrigger(ctx context.Context, trigger any, variables map[string]any) (<-chan TriggerEvent, error) {
+	if trigger == nil {
+		return nil, fmt.Errorf("trigger is required")
+	}
+
+	cmd := subscribeTriggerCmd{
+		Type:      "subscribe_trigger",
+		Trigger:   trigger,
+		Variables: variables,
+	}
+
+	sub, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe trigger: %w", err)
+	}
+
+	events := make(chan TriggerEvent)
+	go deliver(ctx, sub, events, decodeTriggerEvent)
+
+	return events, nil
+}
+
+// decodeTriggerEvent decodes a subscribe_trigger event.
+func decodeTriggerEvent(raw json.RawMessage) (TriggerEvent, bool) {
+	var event TriggerEvent
+
+	var msg triggerMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return event, false
+	}
+	event.Context = msg.Context
+
+	var vars struct {
+		Trigger json.RawMessage `json:"trigger"`
+	}
+	if err := json.Unmarshal(msg.Variables, &event.Variables); err != nil {
+		return event, false
+	}
+	if err := json.Unmarshal(msg.Variables, &vars); err != nil {
+		return event, false
+	}
+
+	if len(vars.Trigger) > 0 {
+		if err := json.Unmarshal(vars.Trigger, &event.Trigger); err != nil {
+			return event, false
+		}
+		if err := json.Unmarshal(vars.Trigger, &event.Trigger.Raw); err != nil {
+			return event, false
+		}
+	}
+
+	return event, true
+}
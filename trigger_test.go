@@ -0,0 +1,121 @@
+package hago
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_SubscribeTrigger(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		// Auth flow
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		if err := conn.ReadJSON(&cmd); err != nil {
+			t.Errorf("read command: %v", err)
+			return
+		}
+
+		if cmd["type"] != "subscribe_trigger" {
+			t.Errorf("expected subscribe_trigger, got %v", cmd["type"])
+		}
+		trigger, _ := cmd["trigger"].(map[string]any)
+		if trigger["platform"] != "state" || trigger["entity_id"] != "binary_sensor.door" {
+			t.Errorf("unexpected trigger: %v", cmd["trigger"])
+		}
+		variables, _ := cmd["variables"].(map[string]any)
+		if variables["source"] != "test" {
+			t.Errorf("unexpected variables: %v", cmd["variables"])
+		}
+
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true})
+		conn.WriteJSON(map[string]any{
+			"id":   cmd["id"],
+			"type": "event",
+			"event": map[string]any{
+				"variables": map[string]any{
+					"source": "test",
+					"trigger": map[string]any{
+						"id":          "0",
+						"idx":         "0",
+						"alias":       nil,
+						"platform":    "state",
+						"entity_id":   "binary_sensor.door",
+						"description": "state of binary_sensor.door",
+						"from_state": map[string]any{
+							"entity_id": "binary_sensor.door",
+							"state":     "off",
+						},
+						"to_state": map[string]any{
+							"entity_id": "binary_sensor.door",
+							"state":     "on",
+						},
+						"for": nil,
+					},
+				},
+				"context": map[string]any{"id": "ctx-1", "parent_id": nil, "user_id": nil},
+			},
+		})
+
+		var discard map[string]any
+		for conn.ReadJSON(&discard) == nil {
+		}
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trigger := map[string]any{
+		"platform":  "state",
+		"entity_id": "binary_sensor.door",
+		"to":        "on",
+	}
+	events, err := client.SubscribeTrigger(ctx, trigger, map[string]any{"source": "test"})
+	if err != nil {
+		t.Fatalf("SubscribeTrigger() error = %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Trigger.Platform != "state" {
+			t.Errorf("Platform = %v, want state", event.Trigger.Platform)
+		}
+		if event.Trigger.EntityID != "binary_sensor.door" {
+			t.Errorf("EntityID = %v, want binary_sensor.door", event.Trigger.EntityID)
+		}
+		if event.Trigger.FromState == nil || event.Trigger.FromState.State != "off" {
+			t.Errorf("FromState = %+v, want off", event.Trigger.FromState)
+		}
+		if event.Trigger.ToState == nil || event.Trigger.ToState.State != "on" {
+			t.Errorf("ToState = %+v, want on", event.Trigger.ToState)
+		}
+		if _, ok := event.Trigger.Raw["for"]; !ok {
+			t.Error("expected Raw to contain platform-specific keys")
+		}
+		if event.Variables["source"] != "test" {
+			t.Errorf("Variables[source] = %v, want test", event.Variables["source"])
+		}
+		if event.Context == nil || event.Context.ID != "ctx-1" {
+			t.Errorf("Context = %+v, want ctx-1", event.Context)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for trigger event")
+	}
+}
+
+func TestClient_SubscribeTriggerRequiresTrigger(t *testing.T) {
+	client, _ := New(WithBaseURL("http://localhost:8123"), WithToken("test-token"))
+	if _, err := client.SubscribeTrigger(context.Background(), nil, nil); err == nil {
+		t.Error("expected error for nil trigger")
+	}
+}
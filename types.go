@@ -2,6 +2,7 @@ package hago
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -85,16 +86,26 @@ type ServiceDetails struct {
 	Description string                  `json:"description,omitempty"`
 	Fields      map[string]ServiceField `json:"fields,omitempty"`
 	Target      *ServiceTarget          `json:"target,omitempty"`
+	// Response is set for services that can return data.
+	Response *ServiceResponseSpec `json:"response,omitempty"`
+}
+
+// ServiceResponseSpec describes the response of a service that returns data.
+type ServiceResponseSpec struct {
+	// Optional is true if callers may omit return_response.
+	Optional bool `json:"optional"`
 }
 
 // ServiceField represents a field in a service call.
+// A field with nested Fields is a section that groups other fields in the UI.
 type ServiceField struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	Required    bool   `json:"required,omitempty"`
-	Example     any    `json:"example,omitempty"`
-	Default     any    `json:"default,omitempty"`
-	Selector    any    `json:"selector,omitempty"`
+	Name        string                  `json:"name,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Required    bool                    `json:"required,omitempty"`
+	Example     any                     `json:"example,omitempty"`
+	Default     any                     `json:"default,omitempty"`
+	Selector    any                     `json:"selector,omitempty"`
+	Fields      map[string]ServiceField `json:"fields,omitempty"`
 }
 
 // ServiceTarget represents the target specification for a service.
@@ -110,17 +121,118 @@ type TargetSelector struct {
 	Domain      []string `json:"domain,omitempty"`
 }
 
+// Target selects what a service call acts on. Home Assistant resolves
+// devices, areas, floors and labels to their member entities server-side.
+type Target struct {
+	EntityID []string `json:"entity_id,omitempty"`
+	DeviceID []string `json:"device_id,omitempty"`
+	AreaID   []string `json:"area_id,omitempty"`
+	FloorID  []string `json:"floor_id,omitempty"`
+	LabelID  []string `json:"label_id,omitempty"`
+}
+
+// NewTarget returns an empty target for use with the builder methods.
+func NewTarget() *Target {
+	return &Target{}
+}
+
+// Entities adds entity IDs to the target.
+func (t *Target) Entities(ids ...string) *Target {
+	t.EntityID = append(t.EntityID, ids...)
+	return t
+}
+
+// Devices adds device IDs to the target.
+func (t *Target) Devices(ids ...string) *Target {
+	t.DeviceID = append(t.DeviceID, ids...)
+	return t
+}
+
+// Areas adds area IDs to the target.
+func (t *Target) Areas(ids ...string) *Target {
+	t.AreaID = append(t.AreaID, ids...)
+	return t
+}
+
+// Floors adds floor IDs to the target.
+func (t *Target) Floors(ids ...string) *Target {
+	t.FloorID = append(t.FloorID, ids...)
+	return t
+}
+
+// Labels adds label IDs to the target.
+func (t *Target) Labels(ids ...string) *Target {
+	t.LabelID = append(t.LabelID, ids...)
+	return t
+}
+
+// IsEmpty reports whether the target selects nothing.
+func (t *Target) IsEmpty() bool {
+	return t == nil || len(t.EntityID)+len(t.DeviceID)+len(t.AreaID)+len(t.FloorID)+len(t.LabelID) == 0
+}
+
+// fields returns the non-empty target lists keyed by their service data name.
+func (t *Target) fields() map[string][]string {
+	m := make(map[string][]string)
+	if t == nil {
+		return m
+	}
+	for key, ids := range map[string][]string{
+		"entity_id": t.EntityID,
+		"device_id": t.DeviceID,
+		"area_id":   t.AreaID,
+		"floor_id":  t.FloorID,
+		"label_id":  t.LabelID,
+	} {
+		if len(ids) > 0 {
+			m[key] = ids
+		}
+	}
+	return m
+}
+
 // ServiceCallRequest represents a request to call a service.
+//
+// EntityID is kept for the common single-entity case. Target selects any
+// combination of entities, devices, areas, floors and labels; when both are
+// set, EntityID is merged into the target's entity list.
 type ServiceCallRequest struct {
 	EntityID string         `json:"entity_id,omitempty"`
+	Target   *Target        `json:"-"`
 	Data     map[string]any `json:"-"`
 }
 
-// MarshalJSON implements custom JSON marshaling to flatten the Data field.
+// NewServiceCallRequest builds a request from a target and a struct or map of
+// service data. Structs are converted using their JSON field names, so
+// omitempty fields that are unset are left out.
+func NewServiceCallRequest(target *Target, data any) (*ServiceCallRequest, error) {
+	req := &ServiceCallRequest{Target: target}
+	if data == nil {
+		return req, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encode service data: %w", err)
+	}
+	if err := json.Unmarshal(raw, &req.Data); err != nil {
+		return nil, fmt.Errorf("service data must be an object: %w", err)
+	}
+	return req, nil
+}
+
+// WithTarget sets the request's target and returns the request.
+func (s *ServiceCallRequest) WithTarget(target *Target) *ServiceCallRequest {
+	s.Target = target
+	return s
+}
+
+// MarshalJSON implements custom JSON marshaling to flatten the target and
+// Data fields into a single service data object, which is what the REST
+// API expects.
 func (s *ServiceCallRequest) MarshalJSON() ([]byte, error) {
 	m := make(map[string]any)
-	if s.EntityID != "" {
-		m["entity_id"] = s.EntityID
+	for k, v := range s.targetFields() {
+		m[k] = v
 	}
 	for k, v := range s.Data {
 		m[k] = v
@@ -128,6 +240,24 @@ func (s *ServiceCallRequest) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+// targetFields merges EntityID and Target into service data keys. A lone
+// EntityID stays a plain string for compatibility with older servers.
+func (s *ServiceCallRequest) targetFields() map[string]any {
+	m := make(map[string]any)
+	fields := s.Target.fields()
+	for k, v := range fields {
+		m[k] = v
+	}
+	if s.EntityID != "" {
+		if ids, ok := fields["entity_id"]; ok {
+			m["entity_id"] = append([]string{s.EntityID}, ids...)
+		} else {
+			m["entity_id"] = s.EntityID
+		}
+	}
+	return m
+}
+
 // HistoryEntry represents a historical state entry.
 type HistoryEntry struct {
 	EntityID    string         `json:"entity_id"`
@@ -233,3 +363,9 @@ type SimpleCard struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
 }
+
+// Ptr returns a pointer to v. It is convenient for optional fields such as
+// AutomationTriggerRequest.SkipCondition and generated service parameters.
+func Ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,186 @@
+package hago
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Built-in user groups.
+const (
+	// GroupAdmin grants full access, including user management.
+	GroupAdmin = "system-admin"
+	// GroupUsers is the group of regular users.
+	GroupUsers = "system-users"
+	// GroupReadOnly can view but not control Home Assistant.
+	GroupReadOnly = "system-read-only"
+)
+
+// User is a Home Assistant user as listed by config/auth/list.
+type User struct {
+	ID string `json:"id"`
+	// Username is the login of the homeassistant auth provider, or nil if
+	// the user cannot log in with a username and password.
+	Username        *string          `json:"username"`
+	Name            string           `json:"name"`
+	IsOwner         bool             `json:"is_owner"`
+	IsActive        bool             `json:"is_active"`
+	LocalOnly       bool             `json:"local_only"`
+	SystemGenerated bool             `json:"system_generated"`
+	GroupIDs        []string         `json:"group_ids"`
+	Credentials     []CredentialInfo `json:"credentials"`
+}
+
+// IsAdmin reports whether the user is in the admin group.
+func (u *User) IsAdmin() bool {
+	return slices.Contains(u.GroupIDs, GroupAdmin)
+}
+
+// CredentialInfo names the auth provider of one of a user's credentials.
+type CredentialInfo struct {
+	Type string `json:"type"`
+}
+
+// CreateUserRequest is the request to create a user. A user created
+// without credentials cannot log in until CreateUserCredentials is called.
+type CreateUserRequest struct {
+	Name string `json:"name"`
+	// GroupIDs are the user's groups, usually one of GroupAdmin,
+	// GroupUsers and GroupReadOnly.
+	GroupIDs  []string `json:"group_ids,omitempty"`
+	LocalOnly bool     `json:"local_only,omitempty"`
+}
+
+// UpdateUserRequest is the request to update a user. Nil fields are left
+// unchanged.
+type UpdateUserRequest struct {
+	Name      *string  `json:"name,omitempty"`
+	IsActive  *bool    `json:"is_active,omitempty"`
+	GroupIDs  []string `json:"group_ids,omitempty"`
+	LocalOnly *bool    `json:"local_only,omitempty"`
+}
+
+// createUserCmd is the config/auth/create command.
+type createUserCmd struct {
+	Type string `json:"type"`
+	CreateUserRequest
+}
+
+// updateUserCmd is the config/auth/update command.
+type updateUserCmd struct {
+	Type   string `json:"type"`
+	UserID string `json:"user_id"`
+	UpdateUserRequest
+}
+
+// deleteUserCmd is the config/auth/delete command.
+type deleteUserCmd struct {
+	Type   string `json:"type"`
+	UserID string `json:"user_id"`
+}
+
+// createCredentialsCmd is the config/auth_provider/homeassistant/create
+// command.
+type createCredentialsCmd struct {
+	Type     string `json:"type"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// changePasswordCmd is the config/auth_provider/homeassistant/change_password
+// command.
+type changePasswordCmd struct {
+	Type            string `json:"type"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// userResult is the result of config/auth/create and config/auth/update.
+type userResult struct {
+	User User `json:"user"`
+}
+
+// Users lists all users, including system-generated ones such as the
+// Supervisor. Requires admin authentication.
+func (c *Client) Users(ctx context.Context) ([]User, error) {
+	cmd := map[string]string{"type": "config/auth/list"}
+	var users []User
+	if err := c.wsCommand(ctx, cmd, &users); err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return users, nil
+}
+
+// CreateUser creates a user without credentials. Use CreateUserCredentials
+// to let the user log in with a username and password. Requires admin
+// authentication.
+func (c *Client) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	cmd := createUserCmd{
+		Type:              "config/auth/create",
+		CreateUserRequest: *req,
+	}
+	var result userResult
+	if err := c.wsCommand(ctx, cmd, &result); err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+	return &result.User, nil
+}
+
+// UpdateUser updates a user. Deactivating a user prevents it from logging
+// in without deleting it. Requires admin authentication.
+func (c *Client) UpdateUser(ctx context.Context, userID string, req *UpdateUserRequest) (*User, error) {
+	cmd := updateUserCmd{
+		Type:              "config/auth/update",
+		UserID:            userID,
+		UpdateUserRequest: *req,
+	}
+	var result userResult
+	if err := c.wsCommand(ctx, cmd, &result); err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	return &result.User, nil
+}
+
+// DeleteUser deletes a user along with its credentials and refresh tokens.
+// The owner and the authenticated user cannot be deleted. Requires admin
+// authentication.
+func (c *Client) DeleteUser(ctx context.Context, userID string) error {
+	cmd := deleteUserCmd{
+		Type:   "config/auth/delete",
+		UserID: userID,
+	}
+	if err := c.wsCommand(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
+
+// CreateUserCredentials gives a user a username and password with the
+// homeassistant auth provider. It fails if the user already has
+// credentials or the username is taken. Requires admin authentication.
+func (c *Client) CreateUserCredentials(ctx context.Context, userID, username, password string) error {
+	cmd := createCredentialsCmd{
+		Type:     "config/auth_provider/homeassistant/create",
+		UserID:   userID,
+		Username: username,
+		Password: password,
+	}
+	if err := c.wsCommand(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("create credentials: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword changes the password of the authenticated user.
+func (c *Client) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
+	cmd := changePasswordCmd{
+		Type:            "config/auth_provider/homeassistant/change_password",
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	}
+	if err := c.wsCommand(ctx, cmd, nil); err != nil {
+		return fmt.Errorf("change password: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package hago
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClient_Users(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "config/auth/list" {
+			t.Errorf("type = %v", cmd["type"])
+		}
+		return []map[string]any{
+			{
+				"id":               "u1",
+				"username":         "alice",
+				"name":             "Alice",
+				"is_owner":         true,
+				"is_active":        true,
+				"local_only":       false,
+				"system_generated": false,
+				"group_ids":        []string{"system-admin"},
+				"credentials":      []map[string]any{{"type": "homeassistant"}},
+			},
+			{
+				"id":               "u2",
+				"username":         nil,
+				"name":             "Supervisor",
+				"is_owner":         false,
+				"is_active":        true,
+				"local_only":       false,
+				"system_generated": true,
+				"group_ids":        []string{"system-admin"},
+				"credentials":      []map[string]any{},
+			},
+		}, nil
+	})
+
+	users, err := client.Users(context.Background())
+	if err != nil {
+		t.Fatalf("Users() error = %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("got %d users, want 2", len(users))
+	}
+	alice, supervisor := users[0], users[1]
+	if *alice.Username != "alice" || !alice.IsOwner || !alice.IsAdmin() || alice.Credentials[0].Type != "homeassistant" {
+		t.Errorf("alice = %+v", alice)
+	}
+	if supervisor.Username != nil || !supervisor.SystemGenerated {
+		t.Errorf("supervisor = %+v", supervisor)
+	}
+}
+
+func TestClient_CreateAndUpdateUser(t *testing.T) {
+	var cmds []map[string]any
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		cmds = append(cmds, cmd)
+		user := map[string]any{"id": "u3", "name": cmd["name"], "is_active": true, "group_ids": cmd["group_ids"]}
+		if cmd["type"] == "config/auth/update" {
+			user["is_active"] = cmd["is_active"]
+		}
+		return map[string]any{"user": user}, nil
+	})
+	ctx := context.Background()
+
+	user, err := client.CreateUser(ctx, &CreateUserRequest{
+		Name:      "Contractor",
+		GroupIDs:  []string{GroupReadOnly},
+		LocalOnly: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.ID != "u3" || user.Name != "Contractor" || user.GroupIDs[0] != GroupReadOnly {
+		t.Errorf("user = %+v", user)
+	}
+
+	active := false
+	user, err = client.UpdateUser(ctx, "u3", &UpdateUserRequest{IsActive: &active})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if user.IsActive {
+		t.Errorf("user = %+v, want inactive", user)
+	}
+
+	if cmds[0]["type"] != "config/auth/create" || cmds[0]["local_only"] != true {
+		t.Errorf("create cmd = %v", cmds[0])
+	}
+	update := cmds[1]
+	if update["user_id"] != "u3" || update["is_active"] != false {
+		t.Errorf("update cmd = %v", update)
+	}
+	for _, key := range []string{"name", "group_ids", "local_only"} {
+		if _, ok := update[key]; ok {
+			t.Errorf("update cmd sent unchanged field %s", key)
+		}
+	}
+}
+
+func TestClient_DeleteUser(t *testing.T) {
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		if cmd["type"] != "config/auth/delete" {
+			t.Errorf("type = %v", cmd["type"])
+		}
+		if cmd["user_id"] == "me" {
+			return nil, &WebSocketError{Code: "no_delete_self", Message: "Unable to delete your own account"}
+		}
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	if err := client.DeleteUser(ctx, "u3"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	err := client.DeleteUser(ctx, "me")
+	var wsErr *WebSocketError
+	if !errors.As(err, &wsErr) || wsErr.Code != "no_delete_self" {
+		t.Errorf("error = %v, want WebSocketError no_delete_self", err)
+	}
+}
+
+func TestClient_Credentials(t *testing.T) {
+	var cmds []map[string]any
+	client := mockWSCommandServer(t, func(cmd map[string]any) (any, *WebSocketError) {
+		cmds = append(cmds, cmd)
+		if cmd["current_password"] == "wrong" {
+			return nil, &WebSocketError{Code: "invalid_current_password", Message: "Invalid current password"}
+		}
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	if err := client.CreateUserCredentials(ctx, "u3", "bob", "s3cret"); err != nil {
+		t.Fatalf("CreateUserCredentials() error = %v", err)
+	}
+	if err := client.ChangePassword(ctx, "old", "new"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if err := client.ChangePassword(ctx, "wrong", "new"); err == nil {
+		t.Error("expected error for wrong current password")
+	}
+
+	create := cmds[0]
+	if create["type"] != "config/auth_provider/homeassistant/create" ||
+		create["user_id"] != "u3" || create["username"] != "bob" || create["password"] != "s3cret" {
+		t.Errorf("create cmd = %v", create)
+	}
+	change := cmds[1]
+	if change["type"] != "config/auth_provider/homeassistant/change_password" ||
+		change["current_password"] != "old" || change["new_password"] != "new" {
+		t.Errorf("change_password cmd = %v", change)
+	}
+}
@@ -0,0 +1,652 @@
+package hago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Selector is a parsed service field selector. Selectors describe how the
+// Home Assistant UI edits a field; they are also a good guide to the values
+// a service accepts. Only the settings relevant to the selector Type are set.
+type Selector struct {
+	// Type is the selector name, such as "number", "select" or "entity".
+	Type string
+	// Min and Max bound number and color_temp values.
+	Min *float64
+	Max *float64
+	// Step is the number increment.
+	Step *float64
+	// Unit is the unit of measurement of a number, or "kelvin" or "mired"
+	// for color_temp.
+	Unit string
+	// Options lists the values of a select selector.
+	Options []SelectOption
+	// CustomValue allows select values outside Options.
+	CustomValue bool
+	// Multiple allows a list of values.
+	Multiple bool
+	// Domains restricts entity selectors to these entity domains.
+	Domains []string
+	// DeviceClasses restricts entity selectors to these device classes.
+	DeviceClasses []string
+	// Multiline marks a text selector as multi-line.
+	Multiline bool
+	// Config is the raw selector configuration.
+	Config map[string]any
+}
+
+// SelectOption is an option of a select selector.
+type SelectOption struct {
+	Value string
+	Label string
+}
+
+// ParseSelector parses a selector as returned in ServiceField.Selector,
+// e.g. {"number": {"min": 0, "max": 255}}.
+func ParseSelector(raw any) (Selector, error) {
+	m, ok := raw.(map[string]any)
+	if !ok || len(m) != 1 {
+		return Selector{}, fmt.Errorf("selector must be an object with a single key, got %v", raw)
+	}
+
+	var sel Selector
+	for kind, config := range m {
+		sel.Type = kind
+		sel.Config, _ = config.(map[string]any)
+	}
+	cfg := sel.Config
+
+	sel.Multiple, _ = cfg["multiple"].(bool)
+	switch sel.Type {
+	case "number":
+		sel.Min = optFloat(cfg["min"])
+		sel.Max = optFloat(cfg["max"])
+		sel.Step = optFloat(cfg["step"])
+		sel.Unit, _ = cfg["unit_of_measurement"].(string)
+	case "color_temp":
+		sel.Unit, _ = cfg["unit"].(string)
+		if sel.Unit == "" {
+			sel.Unit = "mired"
+		}
+		sel.Min = optFloat(cfg["min"])
+		sel.Max = optFloat(cfg["max"])
+		if sel.Unit == "mired" {
+			if sel.Min == nil {
+				sel.Min = optFloat(cfg["min_mireds"])
+			}
+			if sel.Max == nil {
+				sel.Max = optFloat(cfg["max_mireds"])
+			}
+		}
+	case "select":
+		sel.CustomValue, _ = cfg["custom_value"].(bool)
+		opts, _ := cfg["options"].([]any)
+		for _, opt := range opts {
+			switch o := opt.(type) {
+			case string:
+				sel.Options = append(sel.Options, SelectOption{Value: o, Label: o})
+			case map[string]any:
+				value := fmt.Sprint(o["value"])
+				label, _ := o["label"].(string)
+				if label == "" {
+					label = value
+				}
+				sel.Options = append(sel.Options, SelectOption{Value: value, Label: label})
+			}
+		}
+	case "entity":
+		sel.Domains, sel.DeviceClasses = entityFilters(cfg)
+	case "text":
+		sel.Multiline, _ = cfg["multiline"].(bool)
+	}
+	return sel, nil
+}
+
+// entityFilters collects the domain and device_class filters of an entity
+// selector from both the filter list and the legacy top-level keys.
+func entityFilters(cfg map[string]any) (domains, deviceClasses []string) {
+	filters := []map[string]any{cfg}
+	switch f := cfg["filter"].(type) {
+	case map[string]any:
+		filters = append(filters, f)
+	case []any:
+		for _, item := range f {
+			if m, ok := item.(map[string]any); ok {
+				filters = append(filters, m)
+			}
+		}
+	}
+	for _, f := range filters {
+		domains = append(domains, stringList(f["domain"])...)
+		deviceClasses = append(deviceClasses, stringList(f["device_class"])...)
+	}
+	return domains, deviceClasses
+}
+
+// Validate checks a value against the selector. Selectors without
+// client-side rules, such as object or template, accept any value.
+func (s Selector) Validate(value any) error {
+	if s.Multiple {
+		if list, ok := value.([]any); ok {
+			for i, item := range list {
+				if err := s.validateOne(item); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+			return nil
+		}
+	}
+	return s.validateOne(value)
+}
+
+// validateOne checks a single value against the selector.
+func (s Selector) validateOne(value any) error {
+	switch s.Type {
+	case "number", "color_temp":
+		n, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("expected a number, got %v", describe(value))
+		}
+		if s.Min != nil && n < *s.Min {
+			return fmt.Errorf("%v is below the minimum of %v", formatFloat(n), formatFloat(*s.Min))
+		}
+		if s.Max != nil && n > *s.Max {
+			return fmt.Errorf("%v is above the maximum of %v", formatFloat(n), formatFloat(*s.Max))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected true or false, got %v", describe(value))
+		}
+	case "select":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", describe(value))
+		}
+		if s.CustomValue || len(s.Options) == 0 {
+			return nil
+		}
+		values := make([]string, len(s.Options))
+		for i, opt := range s.Options {
+			values[i] = opt.Value
+		}
+		if !slices.Contains(values, str) {
+			return fmt.Errorf("%q is not one of %s", str, strings.Join(values, ", "))
+		}
+	case "entity":
+		return validateEntityIDs(value, s.Domains, s.Multiple)
+	case "text", "icon", "theme", "area", "device", "floor", "label":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string, got %v", describe(value))
+		}
+	case "color_rgb":
+		list, ok := value.([]any)
+		if !ok || len(list) != 3 {
+			return fmt.Errorf("expected [r, g, b], got %v", describe(value))
+		}
+		for _, c := range list {
+			n, ok := toFloat(c)
+			if !ok || n < 0 || n > 255 || n != float64(int(n)) {
+				return fmt.Errorf("color components must be integers from 0 to 255, got %v", describe(value))
+			}
+		}
+	case "time":
+		if str, ok := value.(string); !ok || !timePattern.MatchString(str) {
+			return fmt.Errorf("expected a time as HH:MM[:SS], got %v", describe(value))
+		}
+	case "date":
+		if str, ok := value.(string); !ok || !datePattern.MatchString(str) {
+			return fmt.Errorf("expected a date as YYYY-MM-DD, got %v", describe(value))
+		}
+	case "datetime":
+		if str, ok := value.(string); !ok || !datetimePattern.MatchString(str) {
+			return fmt.Errorf("expected a date and time as YYYY-MM-DD HH:MM[:SS], got %v", describe(value))
+		}
+	case "duration":
+		switch v := value.(type) {
+		case map[string]any:
+			for key, part := range v {
+				if !slices.Contains(durationKeys, key) {
+					return fmt.Errorf("unknown duration key %q", key)
+				}
+				if _, ok := toFloat(part); !ok {
+					return fmt.Errorf("duration %s must be a number, got %v", key, describe(part))
+				}
+			}
+		case string:
+			if !durationPattern.MatchString(v) {
+				return fmt.Errorf("expected a duration as HH:MM[:SS], got %q", v)
+			}
+		default:
+			if _, ok := toFloat(value); !ok {
+				return fmt.Errorf("expected a duration, got %v", describe(value))
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	timePattern     = regexp.MustCompile(`^\d{1,2}:\d{2}(:\d{2})?$`)
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	datetimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[ T]\d{1,2}:\d{2}(:\d{2})?`)
+	durationPattern = regexp.MustCompile(`^-?\d+:\d{2}(:\d{2}(\.\d+)?)?$`)
+	entityPattern   = regexp.MustCompile(`^[a-z0-9_]+\.[a-z0-9_]+$`)
+	durationKeys    = []string{"days", "hours", "minutes", "seconds", "milliseconds"}
+)
+
+// validateEntityIDs checks one entity ID, a comma-separated list or a list
+// of IDs, and that each is in one of domains when domains is non-empty.
+func validateEntityIDs(value any, domains []string, multiple bool) error {
+	var ids []string
+	switch v := value.(type) {
+	case string:
+		if v == "all" || v == "none" {
+			return nil
+		}
+		ids = strings.Split(v, ",")
+	case []string:
+		ids = v
+	case []any:
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("expected entity IDs, got %v", describe(value))
+			}
+			ids = append(ids, str)
+		}
+	default:
+		return fmt.Errorf("expected an entity ID, got %v", describe(value))
+	}
+	if len(ids) > 1 && !multiple {
+		return fmt.Errorf("expected a single entity ID, got %d", len(ids))
+	}
+
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !entityPattern.MatchString(id) {
+			return fmt.Errorf("%q is not a valid entity ID", id)
+		}
+		domain, _, _ := strings.Cut(id, ".")
+		if len(domains) > 0 && !slices.Contains(domains, domain) {
+			return fmt.Errorf("%s is not in domain %s", id, strings.Join(domains, " or "))
+		}
+	}
+	return nil
+}
+
+// FieldError is a problem with one field of a service call.
+type FieldError struct {
+	// Field is the service data key, or "target" for target problems.
+	Field   string
+	Message string
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError reports every problem found when validating a service
+// call against its schema. It matches ErrBadRequest, the error Home
+// Assistant would have returned.
+type ValidationError struct {
+	Domain   string
+	Service  string
+	Problems []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Error()
+	}
+	return fmt.Sprintf("invalid call to %s.%s: %s", e.Domain, e.Service, strings.Join(msgs, "; "))
+}
+
+// Is reports whether target is ErrBadRequest.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrBadRequest
+}
+
+// FieldRule is the validation rule for one service field.
+type FieldRule struct {
+	Name        string
+	Description string
+	Required    bool
+	// Selector is nil if the field has no selector.
+	Selector *Selector
+}
+
+// ServiceSchema holds the validation rules of one service.
+type ServiceSchema struct {
+	Domain  string
+	Service string
+	Fields  map[string]FieldRule
+	// Target is the service's target specification, or nil if the service
+	// does not take a target.
+	Target *ServiceTarget
+}
+
+// targetKeys are the service data keys that make up a target.
+var targetKeys = []string{"entity_id", "device_id", "area_id", "floor_id", "label_id"}
+
+// NewServiceSchema builds the validation rules for a service from its
+// description. Fields grouped into sections are flattened.
+func NewServiceSchema(domain, service string, details ServiceDetails) (*ServiceSchema, error) {
+	schema := &ServiceSchema{
+		Domain:  domain,
+		Service: service,
+		Fields:  make(map[string]FieldRule),
+		Target:  details.Target,
+	}
+	if err := schema.addFields(details.Fields); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// addFields adds rules for fields, descending into sections.
+func (s *ServiceSchema) addFields(fields map[string]ServiceField) error {
+	for name, field := range fields {
+		if len(field.Fields) > 0 {
+			if err := s.addFields(field.Fields); err != nil {
+				return err
+			}
+			continue
+		}
+		rule := FieldRule{Name: name, Description: field.Description, Required: field.Required}
+		if field.Selector != nil {
+			sel, err := ParseSelector(field.Selector)
+			if err != nil {
+				return fmt.Errorf("%s.%s field %s: %w", s.Domain, s.Service, name, err)
+			}
+			rule.Selector = &sel
+		}
+		s.Fields[name] = rule
+	}
+	return nil
+}
+
+// Validate checks a service call request against the schema. It reports
+// unknown fields, missing required fields and values the field selectors
+// reject. Services that describe no fields accept any data.
+func (s *ServiceSchema) Validate(request *ServiceCallRequest) error {
+	if request == nil {
+		request = &ServiceCallRequest{}
+	}
+
+	// Round-trip the data through JSON so Go values such as []string and
+	// []int are checked the same way Home Assistant will see them.
+	var data map[string]any
+	if len(request.Data) > 0 {
+		raw, err := json.Marshal(request.Data)
+		if err != nil {
+			return fmt.Errorf("encode service data: %w", err)
+		}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("decode service data: %w", err)
+		}
+	}
+
+	var problems []FieldError
+
+	for _, name := range slices.Sorted(maps.Keys(data)) {
+		rule, known := s.Fields[name]
+		switch {
+		case known:
+			if rule.Selector != nil {
+				if err := rule.Selector.Validate(data[name]); err != nil {
+					problems = append(problems, FieldError{Field: name, Message: err.Error()})
+				}
+			}
+		case slices.Contains(targetKeys, name):
+			// Validated with the target below.
+		case len(s.Fields) > 0:
+			msg := "unknown field"
+			if suggestion := closest(name, slices.Sorted(maps.Keys(s.Fields))); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
+			problems = append(problems, FieldError{Field: name, Message: msg})
+		}
+	}
+
+	// Some services, such as homeassistant.update_entity, declare entity_id
+	// as a required field, which EntityID or Target satisfies as well.
+	target := request.targetFields()
+	for _, name := range slices.Sorted(maps.Keys(s.Fields)) {
+		_, inData := data[name]
+		_, inTarget := target[name]
+		if !inData && !inTarget && s.Fields[name].Required {
+			problems = append(problems, FieldError{Field: name, Message: "required field is missing"})
+		}
+	}
+
+	problems = append(problems, s.validateTarget(request, data)...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Domain: s.Domain, Service: s.Service, Problems: problems}
+	}
+	return nil
+}
+
+// validateTarget checks the target of a request: that the service takes one
+// and that targeted entities are in the domains it accepts.
+func (s *ServiceSchema) validateTarget(request *ServiceCallRequest, data map[string]any) []FieldError {
+	target := request.targetFields()
+	for _, key := range targetKeys {
+		if v, ok := data[key]; ok {
+			if _, field := s.Fields[key]; !field {
+				target[key] = v
+			}
+		}
+	}
+	if len(target) == 0 {
+		return nil
+	}
+	if s.Target == nil {
+		if _, ok := s.Fields["entity_id"]; ok && len(target) == 1 && target["entity_id"] != nil {
+			return nil
+		}
+		return []FieldError{{Field: "target", Message: fmt.Sprintf("%s.%s does not take a target", s.Domain, s.Service)}}
+	}
+
+	entities, ok := target["entity_id"]
+	if !ok || len(s.Target.Entity) == 0 {
+		return nil
+	}
+	var domains []string
+	for _, sel := range s.Target.Entity {
+		if len(sel.Domain) == 0 {
+			// An unfiltered entity selector accepts every domain.
+			return nil
+		}
+		domains = append(domains, sel.Domain...)
+	}
+	if err := validateEntityIDs(entities, domains, true); err != nil {
+		return []FieldError{{Field: "entity_id", Message: err.Error()}}
+	}
+	return nil
+}
+
+// ServiceCatalog indexes the validation rules of every service, as returned
+// by Services.
+type ServiceCatalog struct {
+	schemas map[string]*ServiceSchema
+}
+
+// NewServiceCatalog builds a catalog from a service list.
+func NewServiceCatalog(services []Service) (*ServiceCatalog, error) {
+	catalog := &ServiceCatalog{schemas: make(map[string]*ServiceSchema)}
+	for _, svc := range services {
+		for name, details := range svc.Services {
+			schema, err := NewServiceSchema(svc.Domain, name, details)
+			if err != nil {
+				return nil, err
+			}
+			catalog.schemas[svc.Domain+"."+name] = schema
+		}
+	}
+	return catalog, nil
+}
+
+// Schema returns the schema of a service.
+func (c *ServiceCatalog) Schema(domain, service string) (*ServiceSchema, bool) {
+	schema, ok := c.schemas[domain+"."+service]
+	return schema, ok
+}
+
+// Validate checks a service call against the catalog. Unknown services fail
+// with an error matching ErrNotFound.
+func (c *ServiceCatalog) Validate(domain, service string, request *ServiceCallRequest) error {
+	schema, ok := c.Schema(domain, service)
+	if !ok {
+		msg := fmt.Sprintf("unknown service %s.%s", domain, service)
+		if suggestion := closest(domain+"."+service, slices.Sorted(maps.Keys(c.schemas))); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+		return fmt.Errorf("%s: %w", msg, ErrNotFound)
+	}
+	return schema.Validate(request)
+}
+
+// ValidateServiceCall fetches the service catalog and checks a service call
+// against it without calling the service.
+func (c *Client) ValidateServiceCall(ctx context.Context, domain, service string, request *ServiceCallRequest) error {
+	services, err := c.Services(ctx)
+	if err != nil {
+		return err
+	}
+	catalog, err := NewServiceCatalog(services)
+	if err != nil {
+		return err
+	}
+	return catalog.Validate(domain, service, request)
+}
+
+// closest returns the candidate nearest to name by edit distance, or "" if
+// none is close enough to be a likely typo.
+func closest(name string, candidates []string) string {
+	best, bestDist := "", len(name)/3+1
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// toFloat converts a JSON-decoded or Go numeric value to float64. Numeric
+// strings are accepted, as Home Assistant coerces them.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// optFloat returns a pointer to v as a float64, or nil if v is not numeric.
+func optFloat(v any) *float64 {
+	if v == nil {
+		return nil
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+// stringList returns v as a list of strings; v may be a string or a list.
+func stringList(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []any:
+		var result []string
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	case []string:
+		return s
+	default:
+		return nil
+	}
+}
+
+// formatFloat formats a number without trailing zeros.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// describe formats a value for an error message.
+func describe(v any) string {
+	if v == nil {
+		return "null"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,242 @@
+package hago
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelector_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector any
+		value    any
+		wantErr  string
+	}{
+		{"number in range", map[string]any{"number": map[string]any{"min": 0.0, "max": 255.0}}, 128.0, ""},
+		{"number below min", map[string]any{"number": map[string]any{"min": 0.0, "max": 100.0}}, -1.0, "below the minimum of 0"},
+		{"number above max", map[string]any{"number": map[string]any{"min": 0.0, "max": 100.0}}, 150.0, "above the maximum of 100"},
+		{"number from string", map[string]any{"number": map[string]any{"max": 10.0}}, "5", ""},
+		{"number wrong type", map[string]any{"number": nil}, true, "expected a number"},
+		{"boolean", map[string]any{"boolean": nil}, false, ""},
+		{"boolean wrong type", map[string]any{"boolean": nil}, "yes", "expected true or false"},
+		{"select option", map[string]any{"select": map[string]any{"options": []any{"heat", "cool"}}}, "cool", ""},
+		{"select labelled option", map[string]any{"select": map[string]any{"options": []any{map[string]any{"value": "hs", "label": "HS"}}}}, "hs", ""},
+		{"select unknown option", map[string]any{"select": map[string]any{"options": []any{"heat", "cool"}}}, "dry", `"dry" is not one of heat, cool`},
+		{"select custom value", map[string]any{"select": map[string]any{"options": []any{"a"}, "custom_value": true}}, "b", ""},
+		{"select multiple", map[string]any{"select": map[string]any{"options": []any{"a", "b"}, "multiple": true}}, []any{"a", "c"}, "item 1"},
+		{"entity domain", map[string]any{"entity": map[string]any{"filter": map[string]any{"domain": "light"}}}, "light.kitchen", ""},
+		{"entity wrong domain", map[string]any{"entity": map[string]any{"filter": []any{map[string]any{"domain": []any{"light"}}}}}, "switch.fan", "not in domain light"},
+		{"entity invalid", map[string]any{"entity": nil}, "kitchen", "not a valid entity ID"},
+		{"entity single", map[string]any{"entity": nil}, []any{"light.a", "light.b"}, "single entity ID"},
+		{"entity multiple", map[string]any{"entity": map[string]any{"multiple": true}}, []any{"light.a", "light.b"}, ""},
+		{"text", map[string]any{"text": nil}, "hello", ""},
+		{"text wrong type", map[string]any{"text": nil}, 5.0, "expected a string"},
+		{"color_rgb", map[string]any{"color_rgb": map[string]any{}}, []any{255.0, 0.0, 128.0}, ""},
+		{"color_rgb out of range", map[string]any{"color_rgb": map[string]any{}}, []any{256.0, 0.0, 0.0}, "0 to 255"},
+		{"color_rgb short", map[string]any{"color_rgb": map[string]any{}}, []any{1.0, 2.0}, "expected [r, g, b]"},
+		{"color_temp kelvin", map[string]any{"color_temp": map[string]any{"unit": "kelvin", "min": 2000.0, "max": 6500.0}}, 1000.0, "below the minimum of 2000"},
+		{"color_temp mireds", map[string]any{"color_temp": map[string]any{"min_mireds": 153.0, "max_mireds": 500.0}}, 600.0, "above the maximum of 500"},
+		{"time", map[string]any{"time": nil}, "07:30", ""},
+		{"time invalid", map[string]any{"time": nil}, "7pm", "HH:MM"},
+		{"duration object", map[string]any{"duration": nil}, map[string]any{"minutes": 5.0}, ""},
+		{"duration bad key", map[string]any{"duration": nil}, map[string]any{"weeks": 1.0}, "unknown duration key"},
+		{"object accepts anything", map[string]any{"object": nil}, []any{1.0, "x"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("ParseSelector() error = %v", err)
+			}
+			err = sel.Validate(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, raw := range []any{nil, "number", map[string]any{}, map[string]any{"a": nil, "b": nil}} {
+		if _, err := ParseSelector(raw); err == nil {
+			t.Errorf("ParseSelector(%v) expected error", raw)
+		}
+	}
+}
+
+func testCatalog(t *testing.T) *ServiceCatalog {
+	t.Helper()
+	catalog, err := NewServiceCatalog([]Service{
+		{
+			Domain: "light",
+			Services: map[string]ServiceDetails{
+				"turn_on": {
+					Target: &ServiceTarget{Entity: []TargetSelector{{Domain: []string{"light"}}}},
+					Fields: map[string]ServiceField{
+						"transition": {Selector: map[string]any{"number": map[string]any{"min": 0.0, "max": 300.0}}},
+						"advanced_fields": {Fields: map[string]ServiceField{
+							"brightness_pct": {Selector: map[string]any{"number": map[string]any{"min": 0.0, "max": 100.0}}},
+							"rgb_color":      {Selector: map[string]any{"color_rgb": map[string]any{}}},
+						}},
+					},
+				},
+			},
+		},
+		{
+			Domain: "notify",
+			Services: map[string]ServiceDetails{
+				"persistent_notification": {
+					Fields: map[string]ServiceField{
+						"message": {Required: true, Selector: map[string]any{"text": nil}},
+						"title":   {Selector: map[string]any{"text": nil}},
+					},
+				},
+				"custom": {},
+			},
+		},
+		{
+			Domain: "homeassistant",
+			Services: map[string]ServiceDetails{
+				"update_entity": {
+					Fields: map[string]ServiceField{
+						"entity_id": {Required: true, Selector: map[string]any{"entity": map[string]any{}}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewServiceCatalog() error = %v", err)
+	}
+	return catalog
+}
+
+func TestServiceCatalog_Validate(t *testing.T) {
+	catalog := testCatalog(t)
+
+	tests := []struct {
+		name     string
+		domain   string
+		service  string
+		req      *ServiceCallRequest
+		wantErrs []string
+	}{
+		{
+			name:    "valid",
+			domain:  "light",
+			service: "turn_on",
+			req: &ServiceCallRequest{
+				Target: NewTarget().Entities("light.kitchen").Areas("hall"),
+				Data:   map[string]any{"brightness_pct": 50, "rgb_color": []int{255, 0, 0}},
+			},
+		},
+		{
+			name:     "unknown field with suggestion",
+			domain:   "light",
+			service:  "turn_on",
+			req:      &ServiceCallRequest{EntityID: "light.kitchen", Data: map[string]any{"brightnes_pct": 50}},
+			wantErrs: []string{"brightnes_pct: unknown field (did you mean brightness_pct?)"},
+		},
+		{
+			name:     "out of range",
+			domain:   "light",
+			service:  "turn_on",
+			req:      &ServiceCallRequest{EntityID: "light.kitchen", Data: map[string]any{"brightness_pct": 150}},
+			wantErrs: []string{"brightness_pct: 150 is above the maximum of 100"},
+		},
+		{
+			name:     "wrong target domain",
+			domain:   "light",
+			service:  "turn_on",
+			req:      &ServiceCallRequest{EntityID: "switch.fan"},
+			wantErrs: []string{"entity_id: switch.fan is not in domain light"},
+		},
+		{
+			name:     "missing required",
+			domain:   "notify",
+			service:  "persistent_notification",
+			req:      &ServiceCallRequest{Data: map[string]any{"title": "Hi"}},
+			wantErrs: []string{"message: required field is missing"},
+		},
+		{
+			name:    "required entity_id given as EntityID",
+			domain:  "homeassistant",
+			service: "update_entity",
+			req:     &ServiceCallRequest{EntityID: "light.kitchen"},
+		},
+		{
+			name:    "required entity_id given as Target",
+			domain:  "homeassistant",
+			service: "update_entity",
+			req:     &ServiceCallRequest{Target: NewTarget().Entities("light.kitchen")},
+		},
+		{
+			name:     "required entity_id missing",
+			domain:   "homeassistant",
+			service:  "update_entity",
+			req:      &ServiceCallRequest{},
+			wantErrs: []string{"entity_id: required field is missing"},
+		},
+		{
+			name:     "no target",
+			domain:   "notify",
+			service:  "persistent_notification",
+			req:      &ServiceCallRequest{EntityID: "light.kitchen", Data: map[string]any{"message": "Hi"}},
+			wantErrs: []string{"target: notify.persistent_notification does not take a target"},
+		},
+		{
+			name:    "undescribed service accepts any data",
+			domain:  "notify",
+			service: "custom",
+			req:     &ServiceCallRequest{Data: map[string]any{"anything": true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := catalog.Validate(tt.domain, tt.service, tt.req)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var vErr *ValidationError
+			if !errors.As(err, &vErr) {
+				t.Fatalf("Validate() error = %v, want ValidationError", err)
+			}
+			if !errors.Is(err, ErrBadRequest) {
+				t.Error("ValidationError should match ErrBadRequest")
+			}
+			if len(vErr.Problems) != len(tt.wantErrs) {
+				t.Fatalf("Problems = %v, want %v", vErr.Problems, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				if got := vErr.Problems[i].Error(); got != want {
+					t.Errorf("Problems[%d] = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceCatalog_ValidateUnknownService(t *testing.T) {
+	catalog := testCatalog(t)
+
+	err := catalog.Validate("light", "turn_onn", nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Validate() error = %v, want ErrNotFound", err)
+	}
+	if !strings.Contains(err.Error(), "did you mean light.turn_on?") {
+		t.Errorf("Validate() error = %v, want suggestion", err)
+	}
+}
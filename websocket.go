@@ -2,6 +2,7 @@ package hago
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,12 +15,65 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// WebSocketConn is a message-oriented WebSocket connection.
+// *websocket.Conn from gorilla/websocket satisfies it.
+type WebSocketConn interface {
+	ReadJSON(v any) error
+	WriteJSON(v any) error
+	Close() error
+}
+
+// WebSocketDialer opens WebSocket connections to Home Assistant.
+type WebSocketDialer interface {
+	DialWebSocket(ctx context.Context, url string, header http.Header) (WebSocketConn, error)
+}
+
+// WebSocketDialerFunc adapts a function to the WebSocketDialer interface.
+type WebSocketDialerFunc func(ctx context.Context, url string, header http.Header) (WebSocketConn, error)
+
+// DialWebSocket calls f(ctx, url, header).
+func (f WebSocketDialerFunc) DialWebSocket(ctx context.Context, url string, header http.Header) (WebSocketConn, error) {
+	return f(ctx, url, header)
+}
+
+// DefaultWebSocketDialer returns the dialer used when none is configured.
+// It dials with gorilla/websocket and a 10 second handshake timeout.
+func DefaultWebSocketDialer() WebSocketDialer {
+	return newWebSocketDialer(nil)
+}
+
+// newWebSocketDialer returns a gorilla/websocket dialer using the given TLS
+// configuration, or the default one if it is nil.
+func newWebSocketDialer(tlsConfig *tls.Config) WebSocketDialer {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  tlsConfig,
+	}
+	return WebSocketDialerFunc(func(ctx context.Context, url string, header http.Header) (WebSocketConn, error) {
+		conn, _, err := dialer.DialContext(ctx, url, header)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	})
+}
+
+// WithWebSocketDialer sets the dialer used for WebSocket connections, for
+// example to route them through a proxy or to record and replay traffic.
+func WithWebSocketDialer(dialer WebSocketDialer) Option {
+	return func(c *Client) error {
+		c.wsDialer = dialer
+		return nil
+	}
+}
+
 // wsConn manages the WebSocket connection to Home Assistant.
 type wsConn struct {
-	conn      *websocket.Conn
+	conn      WebSocketConn
 	mu        sync.Mutex
 	msgID     atomic.Int64
 	pending   map[int64]chan *wsResponse
+	subs      map[int64]*wsSubscription // guarded by pendingMu
 	pendingMu sync.Mutex
 	done      chan struct{}
 	closeOnce sync.Once
@@ -37,6 +91,7 @@ type wsResponse struct {
 	Type      string          `json:"type"`
 	Success   bool            `json:"success,omitempty"`
 	Result    json.RawMessage `json:"result,omitempty"`
+	Event     json.RawMessage `json:"event,omitempty"`
 	Error     *wsError        `json:"error,omitempty"`
 	HAVersion string          `json:"ha_version,omitempty"`
 	Message   string          `json:"message,omitempty"`
@@ -48,65 +103,77 @@ type wsError struct {
 	Message string `json:"message"`
 }
 
-// WebSocketError represents an error from the WebSocket API.
-type WebSocketError struct {
-	Code    string
-	Message string
-}
-
-// Error implements the error interface.
-func (e *WebSocketError) Error() string {
-	return fmt.Sprintf("websocket error [%s]: %s", e.Code, e.Message)
-}
-
 // connectWebSocket establishes and authenticates a WebSocket connection.
 func (c *Client) connectWebSocket(ctx context.Context) error {
 	c.wsMu.Lock()
-	defer c.wsMu.Unlock()
+	connected, err := c.connectLocked(ctx)
+	c.wsMu.Unlock()
 
+	if connected {
+		c.notifyConnectionState(ConnectionConnected)
+	}
+	return err
+}
+
+// connectLocked connects if there is no live connection. It reports whether a
+// new connection was established. Active subscriptions are replayed onto the
+// new connection. The caller must hold c.wsMu.
+func (c *Client) connectLocked(ctx context.Context) (bool, error) {
 	// Already connected
 	if c.ws != nil {
 		select {
 		case <-c.ws.done:
 			// Connection closed, need to reconnect
 		default:
-			return nil
+			return false, nil
 		}
 	}
 
 	// Build WebSocket URL
 	wsURL, err := c.buildWebSocketURL()
 	if err != nil {
-		return fmt.Errorf("build websocket URL: %w", err)
+		return false, fmt.Errorf("build websocket URL: %w", err)
 	}
 
 	// Connect
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
-	}
-
-	conn, _, err := dialer.DialContext(ctx, wsURL, http.Header{})
+	conn, err := c.wsDialer.DialWebSocket(ctx, wsURL, http.Header{})
 	if err != nil {
-		return fmt.Errorf("websocket dial: %w", err)
+		return false, fmt.Errorf("websocket dial: %w", err)
 	}
 
 	ws := &wsConn{
 		conn:    conn,
 		pending: make(map[int64]chan *wsResponse),
+		subs:    make(map[int64]*wsSubscription),
 		done:    make(chan struct{}),
 	}
 
 	// Authenticate
-	if err := ws.authenticate(ctx, c.token); err != nil {
+	token, err := c.tokenSource.Token(ctx)
+	if err != nil {
 		conn.Close()
-		return fmt.Errorf("websocket auth: %w", err)
+		return false, fmt.Errorf("websocket auth: get token: %w", err)
+	}
+	if err := ws.authenticate(ctx, token); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("websocket auth: %w", err)
 	}
 
 	// Start reader goroutine
 	go ws.reader()
 
 	c.ws = ws
-	return nil
+	if c.wsStop == nil {
+		c.wsStop = make(chan struct{})
+	}
+
+	c.replaySubscriptions(ctx, ws)
+	go c.monitor(ws, c.wsStop)
+	if c.heartbeatInterval > 0 {
+		go c.heartbeat(ws)
+	}
+
+	return true, nil
 }
 
 // buildWebSocketURL converts the REST API URL to a WebSocket URL.
@@ -165,7 +232,7 @@ func (ws *wsConn) authenticate(ctx context.Context, token string) error {
 		if msg == "" {
 			msg = "invalid authentication"
 		}
-		return fmt.Errorf("auth failed: %s", msg)
+		return fmt.Errorf("auth failed: %s: %w", msg, ErrUnauthorized)
 	default:
 		return fmt.Errorf("unexpected auth response: %s", authResp.Type)
 	}
@@ -182,23 +249,40 @@ func (ws *wsConn) reader() {
 			return
 		}
 
-		// Dispatch response to waiting caller
-		if resp.ID != 0 {
+		if resp.ID == 0 {
+			continue
+		}
+
+		// Subscription events go to the subscription's queue
+		if resp.Type == "event" {
 			ws.pendingMu.Lock()
-			if ch, ok := ws.pending[resp.ID]; ok {
-				ch <- &resp
-				delete(ws.pending, resp.ID)
-			}
+			sub := ws.subs[resp.ID]
 			ws.pendingMu.Unlock()
+			if sub != nil {
+				sub.events.push(resp.Event)
+			}
+			continue
 		}
+
+		// Dispatch response to waiting caller
+		ws.pendingMu.Lock()
+		if ch, ok := ws.pending[resp.ID]; ok {
+			ch <- &resp
+			delete(ws.pending, resp.ID)
+		}
+		ws.pendingMu.Unlock()
 	}
 }
 
 // sendCommand sends a command and waits for a response.
 func (ws *wsConn) sendCommand(ctx context.Context, cmd any) (*wsResponse, error) {
-	// Get next message ID
-	id := ws.msgID.Add(1)
+	return ws.sendCommandID(ctx, ws.msgID.Add(1), cmd)
+}
 
+// sendCommandID sends a command using a pre-allocated message ID and waits
+// for a response. Subscriptions use this so they can register for events
+// before the command is sent.
+func (ws *wsConn) sendCommandID(ctx context.Context, id int64, cmd any) (*wsResponse, error) {
 	// Create response channel
 	respCh := make(chan *wsResponse, 1)
 	ws.pendingMu.Lock()
@@ -234,23 +318,54 @@ func (ws *wsConn) sendCommand(ctx context.Context, cmd any) (*wsResponse, error)
 	// Wait for response
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, &RequestError{Op: "wait for response", Err: ctx.Err()}
 	case <-ws.done:
-		return nil, fmt.Errorf("websocket connection closed")
+		return nil, ErrWebSocketClosed
 	case resp := <-respCh:
-		if resp.Error != nil {
-			return nil, &WebSocketError{
-				Code:    resp.Error.Code,
-				Message: resp.Error.Message,
+		if resp.Error != nil || (!resp.Success && resp.Type == "result") {
+			wsErr := &WebSocketError{
+				Code:    ErrCodeUnknownError,
+				Message: "command failed",
 			}
-		}
-		if !resp.Success && resp.Type == "result" {
-			return nil, fmt.Errorf("command failed")
+			if resp.Error != nil {
+				wsErr.Code = resp.Error.Code
+				wsErr.Message = resp.Error.Message
+			}
+			wsErr.Command, _ = cmdMap["type"].(string)
+			wsErr.EntityID = commandEntityID(cmdMap)
+			return nil, wsErr
 		}
 		return resp, nil
 	}
 }
 
+// commandEntityID returns the entity a command acts on: its entity_id, or
+// for call_service the entity_id under target or service_data. The first
+// entity is used if several are given.
+func commandEntityID(cmd map[string]any) string {
+	candidates := []any{cmd["entity_id"]}
+	for _, key := range []string{"target", "service_data"} {
+		if m, ok := cmd[key].(map[string]any); ok {
+			candidates = append(candidates, m["entity_id"])
+		}
+	}
+	for _, v := range candidates {
+		switch v := v.(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case []any:
+			if len(v) > 0 {
+				if id, ok := v[0].(string); ok {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
 // close closes the WebSocket connection.
 func (ws *wsConn) close() {
 	ws.closeOnce.Do(func() {
@@ -260,25 +375,47 @@ func (ws *wsConn) close() {
 }
 
 // CloseWebSocket closes the WebSocket connection if open.
+// Automatic reconnection stops and all active subscriptions end.
+// A later WebSocket call opens a new connection.
 func (c *Client) CloseWebSocket() {
 	c.wsMu.Lock()
-	defer c.wsMu.Unlock()
-
+	if c.wsStop != nil {
+		close(c.wsStop)
+		c.wsStop = nil
+	}
 	if c.ws != nil {
 		c.ws.close()
 		c.ws = nil
 	}
+	c.wsMu.Unlock()
+
+	c.closeSubscriptions(nil)
+}
+
+// webSocket returns the current WebSocket connection, connecting if needed.
+func (c *Client) webSocket(ctx context.Context) (*wsConn, error) {
+	if err := c.connectWebSocket(ctx); err != nil {
+		return nil, err
+	}
+
+	c.wsMu.Lock()
+	defer c.wsMu.Unlock()
+	if c.ws == nil {
+		return nil, ErrWebSocketClosed
+	}
+	return c.ws, nil
 }
 
 // wsCommand sends a WebSocket command and returns the result.
 func (c *Client) wsCommand(ctx context.Context, cmd any, result any) error {
 	// Ensure connected
-	if err := c.connectWebSocket(ctx); err != nil {
+	ws, err := c.webSocket(ctx)
+	if err != nil {
 		return err
 	}
 
 	// Send command
-	resp, err := c.ws.sendCommand(ctx, cmd)
+	resp, err := ws.sendCommand(ctx, cmd)
 	if err != nil {
 		return err
 	}
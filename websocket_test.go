@@ -3,6 +3,7 @@ package hago
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -124,6 +125,9 @@ func TestClient_WebSocketAuthFailed(t *testing.T) {
 	if !strings.Contains(err.Error(), "auth failed") {
 		t.Errorf("expected auth failed error, got: %v", err)
 	}
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got: %v", err)
+	}
 }
 
 func TestClient_LovelaceListDashboards(t *testing.T) {
@@ -354,6 +358,99 @@ func TestWebSocketError_Error(t *testing.T) {
 	if err.Error() != expected {
 		t.Errorf("Error() = %v, want %v", err.Error(), expected)
 	}
+
+	err.Command = "lovelace/config"
+	expected = "lovelace/config: " + expected
+	if err.Error() != expected {
+		t.Errorf("Error() = %v, want %v", err.Error(), expected)
+	}
+}
+
+func TestWebSocketError_Is(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{ErrCodeNotFound, ErrNotFound},
+		{ErrCodeInvalidFormat, ErrBadRequest},
+		{ErrCodeUnauthorized, ErrUnauthorized},
+		{ErrCodeTimeout, ErrTimeout},
+		{ErrCodeUnknownError, ErrServerError},
+		{ErrCodeHomeAssistantError, ErrServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			var err error = &WebSocketError{Code: tt.code}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%s, %v) = false", tt.code, tt.want)
+			}
+			if errors.Is(err, ErrMethodNotAllowed) {
+				t.Errorf("errors.Is(%s, ErrMethodNotAllowed) = true", tt.code)
+			}
+		})
+	}
+}
+
+func TestClient_WebSocketCommandError(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(map[string]any{
+			"id":      cmd["id"],
+			"type":    "result",
+			"success": false,
+			"error":   map[string]any{"code": "not_found", "message": "Entity not found"},
+		})
+	})
+	defer server.Close()
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"))
+	defer client.CloseWebSocket()
+
+	cmd := map[string]any{"type": "config/entity_registry/get", "entity_id": "light.missing"}
+	err := client.wsCommand(context.Background(), cmd, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var wsErr *WebSocketError
+	if !errors.As(err, &wsErr) {
+		t.Fatalf("expected *WebSocketError, got %T", err)
+	}
+	if wsErr.Command != "config/entity_registry/get" {
+		t.Errorf("Command = %q", wsErr.Command)
+	}
+	if wsErr.EntityID != "light.missing" {
+		t.Errorf("EntityID = %q, want light.missing", wsErr.EntityID)
+	}
+}
+
+func TestCommandEntityID(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  map[string]any
+		want string
+	}{
+		{"top level", map[string]any{"entity_id": "light.missing"}, "light.missing"},
+		{"target", map[string]any{"target": map[string]any{"entity_id": "light.kitchen"}}, "light.kitchen"},
+		{"target list", map[string]any{"target": map[string]any{"entity_id": []any{"light.a", "light.b"}}}, "light.a"},
+		{"service data", map[string]any{"service_data": map[string]any{"entity_id": "switch.fan"}}, "switch.fan"},
+		{"area only", map[string]any{"target": map[string]any{"area_id": "kitchen"}}, ""},
+		{"none", map[string]any{"type": "ping"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandEntityID(tt.cmd); got != tt.want {
+				t.Errorf("commandEntityID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestClient_BuildWebSocketURL(t *testing.T) {
@@ -397,3 +494,33 @@ func TestClient_BuildWebSocketURL(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_WithWebSocketDialer(t *testing.T) {
+	server := mockWSServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "auth_required"})
+		var auth map[string]any
+		conn.ReadJSON(&auth)
+		conn.WriteJSON(map[string]any{"type": "auth_ok"})
+
+		var cmd map[string]any
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(map[string]any{"id": cmd["id"], "type": "result", "success": true, "result": []any{}})
+	})
+	defer server.Close()
+
+	var dialed string
+	dialer := WebSocketDialerFunc(func(ctx context.Context, url string, header http.Header) (WebSocketConn, error) {
+		dialed = url
+		return DefaultWebSocketDialer().DialWebSocket(ctx, url, header)
+	})
+
+	client, _ := New(WithBaseURL(server.URL), WithToken("test-token"), WithWebSocketDialer(dialer))
+	defer client.CloseWebSocket()
+
+	if _, err := client.AreaRegistry(context.Background()); err != nil {
+		t.Fatalf("AreaRegistry() error = %v", err)
+	}
+	if !strings.HasSuffix(dialed, "/api/websocket") {
+		t.Errorf("dialer called with %q", dialed)
+	}
+}